@@ -0,0 +1,46 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+	"bytes"
+	"strings"
+)
+
+// Array : implement Expression
+type Array struct {
+	Tok      *token.Token // [
+	Elements ExpressionSlice
+}
+
+func (this *Array) expressionNode() {}
+func (this *Array) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *Array) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *Array) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, e := range this.Elements {
+		elements = append(elements, e.String())
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+func (this *Array) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	elements, err := this.Elements.evalArgs(env, insideLoop)
+	if nil != err {
+		return nil, newError(this, err, "Array.Eval")
+	}
+	rc := &object.Array{Elements: elements}
+	if err := env.CheckSize(rc); nil != err {
+		return nil, newError(this, err, "Array.Eval")
+	}
+	return rc, nil
+}
@@ -2,13 +2,15 @@ package ast
 
 import (
 	"Q/object"
+	"Q/token"
 	"bytes"
-	"fmt"
 )
 
 // AssignStmt : implement Statement
+// it assigns to Name, or to the element of Index when `a[i] = v`
 type AssignStmt struct {
 	Name  *Identifier
+	Index *Index
 	Value Expression
 }
 
@@ -16,9 +18,19 @@ func (this *AssignStmt) statementNode() {}
 func (this *AssignStmt) TokenLiteral() string {
 	return ""
 }
+func (this *AssignStmt) Pos() token.Position {
+	if nil != this.Index {
+		return this.Index.Pos()
+	}
+	return this.Name.Pos()
+}
 func (this *AssignStmt) String() string {
 	var out bytes.Buffer
-	out.WriteString(this.Name.String())
+	if nil != this.Index {
+		out.WriteString(this.Index.String())
+	} else {
+		out.WriteString(this.Name.String())
+	}
 	out.WriteString(" = ")
 	if nil != this.Value {
 		out.WriteString(this.Value.String())
@@ -29,10 +41,16 @@ func (this *AssignStmt) String() string {
 func (this *AssignStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	val, err := this.Value.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("AssignStmt.Eval -> eval value | %v", err)
+		return nil, newError(this, err, "AssignStmt.Eval -> eval value")
+	}
+	if nil != this.Index {
+		if err := this.Index.assign(env, insideLoop, val); nil != err {
+			return nil, newError(this, err, "AssignStmt.Eval -> Index.assign")
+		}
+		return val, nil
 	}
 	if err := env.Assign(this.Name.Value, val); nil != err {
-		return nil, fmt.Errorf("AssignStmt.Eval -> env.Assign | %v", err)
+		return nil, newError(this, err, "AssignStmt.Eval -> env.Assign")
 	}
 	return val, nil
 }
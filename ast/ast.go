@@ -8,6 +8,7 @@ import (
 
 type Node interface {
 	TokenLiteral() string
+	Pos() token.Position // the position of the token the node is built around
 	String() string
 	Eval(env *object.Env, insideLoop bool) (object.Object, error)
 }
@@ -29,7 +30,7 @@ func (this *ExpressionSlice) evalArgs(env *object.Env, insideLoop bool) ([]objec
 	for _, expr := range *this {
 		evaluated, err := expr.Eval(env, insideLoop)
 		if nil != err {
-			return nil, fmt.Errorf("ExpressionSlice.eval | %v", err)
+			return nil, object.Wrap(err, "ExpressionSlice.eval")
 		}
 		result = append(result, evaluated)
 	}
@@ -41,8 +42,11 @@ type StatementSlice []Statement
 func (this *StatementSlice) eval(isBlockStmts bool, env *object.Env, insideLoop bool) (object.Object, error) {
 	var result object.Object
 	for _, stmt := range *this {
+		if err := env.Step(); nil != err {
+			return nil, newError(stmt, err, "evalStatements")
+		}
 		if v, err := stmt.Eval(env, insideLoop); nil != err {
-			return nil, fmt.Errorf("evalStatements | %v", err)
+			return nil, object.Wrap(err, "evalStatements")
 		} else {
 			if needReturn, returnValue := v.Return(); needReturn {
 				if isBlockStmts {
@@ -58,10 +62,18 @@ func (this *StatementSlice) eval(isBlockStmts bool, env *object.Env, insideLoop
 				if isBreak {
 					return v, nil
 				}
+				isContinue, _ := v.Continue()
+				if isContinue {
+					return v, nil
+				}
 			} else { // outside loop
 				isBreak, breakCount := v.Break()
 				if isBreak && 1 == breakCount { // orginal break
-					return nil, fmt.Errorf("evalStatements -> 'break' outside loop")
+					return nil, newError(stmt, nil, "evalStatements -> 'break' outside loop")
+				}
+				isContinue, continueCount := v.Continue()
+				if isContinue && 1 == continueCount { // orginal continue
+					return nil, newError(stmt, nil, "evalStatements -> 'continue' outside loop")
 				}
 			}
 			result = v
@@ -25,3 +25,53 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong, got: %v", program.String())
 	}
 }
+
+func TestDump(t *testing.T) {
+	tok := func(tt token.TokenType, lit string, col int) *token.Token {
+		return &token.Token{Type: tt, Literal: lit, Pos: token.Position{Line: 1, Col: col}}
+	}
+	// if (x) { {"a": 1}; }
+	program := &Program{
+		Stmts: StatementSlice{
+			&ExpressionStmt{
+				Tok: tok(token.IF, "if", 1),
+				Expr: &IfExpression{
+					Tok: tok(token.IF, "if", 1),
+					Clauses: IfClauseSlice{{
+						If: &Identifier{Tok: tok(token.IDENT, "x", 5), Value: "x"},
+						Then: &BlockStmt{
+							Tok: tok(token.LBRACE, "{", 8),
+							Stmts: StatementSlice{
+								&ExpressionStmt{
+									Tok: tok(token.LBRACE, "{", 10),
+									Expr: &Hash{
+										Tok: tok(token.LBRACE, "{", 10),
+										Pairs: HashPairSlice{{
+											Key:   &String{Tok: tok(token.STRING, "a", 11), Value: "a"},
+											Value: &Integer{Tok: tok(token.INT, "1", 16), Value: 1},
+										}},
+									},
+								},
+							},
+						},
+					}},
+				},
+			},
+		},
+	}
+	want := `Program 1:1 "if"
+  ExpressionStmt 1:1 "if"
+    Expr: IfExpression 1:1 "if"
+      Clauses[0]: IfClause
+        If: Identifier 1:5 "x"
+        Then: BlockStmt 1:8 "{"
+          Stmts[0]: ExpressionStmt 1:10 "{"
+            Expr: Hash 1:10 "{"
+              Pairs[0]: HashPair
+                Key: String 1:11 "a"
+                Value: Integer 1:16 "1"
+`
+	if got := Dump(program); got != want {
+		t.Errorf("Dump() =\n%v\nwant\n%v", got, want)
+	}
+}
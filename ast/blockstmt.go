@@ -16,6 +16,9 @@ func (this *BlockStmt) statementNode() {}
 func (this *BlockStmt) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *BlockStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *BlockStmt) String() string {
 	var out bytes.Buffer
 	for _, s := range this.Stmts {
@@ -24,6 +27,8 @@ func (this *BlockStmt) String() string {
 	return out.String()
 }
 func (this *BlockStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
-	// TODO scope
-	return this.Stmts.eval(true, env, insideLoop)
+	inner := object.NewEnclosedEnv(env)
+	rc, err := this.Stmts.eval(true, inner, insideLoop)
+	inner.Leave()
+	return rc, err
 }
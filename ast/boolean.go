@@ -15,6 +15,9 @@ func (this *Boolean) expressionNode() {}
 func (this *Boolean) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *Boolean) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *Boolean) String() string {
 	return this.Tok.Literal
 }
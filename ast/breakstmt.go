@@ -8,19 +8,30 @@ import (
 
 // BreakStmt : implement Statement
 type BreakStmt struct {
-	Tok *token.Token
+	Tok   *token.Token
+	Label *Identifier // nil for the nearest loop
 }
 
 func (this *BreakStmt) statementNode() {}
 func (this *BreakStmt) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *BreakStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *BreakStmt) String() string {
 	var out bytes.Buffer
 	out.WriteString(this.TokenLiteral())
+	if nil != this.Label {
+		out.WriteString(" ")
+		out.WriteString(this.Label.String())
+	}
 	out.WriteString(";")
 	return out.String()
 }
 func (this *BreakStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
-	return object.NewBreak(), nil
+	if nil != this.Label {
+		return object.NewBreak(this.Label.Value), nil
+	}
+	return object.NewBreak(""), nil
 }
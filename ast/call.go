@@ -4,7 +4,6 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
 	"strings"
 )
 
@@ -19,6 +18,9 @@ func (this *Call) expressionNode() {}
 func (this *Call) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *Call) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *Call) String() string {
 	var out bytes.Buffer
 
@@ -37,12 +39,25 @@ func (this *Call) String() string {
 func (this *Call) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	fn, err := this.Func.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("Call.Eval | %v", err)
+		return nil, newError(this, err, "Call.Eval")
 	}
 
 	args, err := this.Args.evalArgs(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("Call.Eval | %v", err)
+		return nil, newError(this, err, "Call.Eval")
+	}
+	if err := env.Step(); nil != err {
+		return nil, newError(this, err, "Call.Eval")
+	}
+	rc, err := fn.Call(args, insideLoop)
+	if nil != err {
+		e := newError(this, err, "Call.Eval -> %v", this.Func.String())
+		e.Call = true
+		return nil, e
+	}
+	// builtins like push grow their arguments
+	if err := env.CheckSize(rc); nil != err {
+		return nil, newError(this, err, "Call.Eval")
 	}
-	return fn.Call(args, insideLoop)
+	return rc, nil
 }
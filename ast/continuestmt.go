@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+	"bytes"
+)
+
+// ContinueStmt : implement Statement
+type ContinueStmt struct {
+	Tok   *token.Token
+	Label *Identifier // nil for the nearest loop
+}
+
+func (this *ContinueStmt) statementNode() {}
+func (this *ContinueStmt) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *ContinueStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *ContinueStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString(this.TokenLiteral())
+	if nil != this.Label {
+		out.WriteString(" ")
+		out.WriteString(this.Label.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+func (this *ContinueStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	if nil != this.Label {
+		return object.NewContinue(this.Label.Value), nil
+	}
+	return object.NewContinue(""), nil
+}
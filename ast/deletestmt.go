@@ -0,0 +1,44 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+	"bytes"
+)
+
+// DeleteStmt : implement Statement
+type DeleteStmt struct {
+	Tok   *token.Token
+	Index *Index
+}
+
+func (this *DeleteStmt) statementNode() {}
+func (this *DeleteStmt) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *DeleteStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *DeleteStmt) String() string {
+	var out bytes.Buffer
+	out.WriteString(this.TokenLiteral())
+	out.WriteString(" ")
+	out.WriteString(this.Index.String())
+	out.WriteString(";")
+	return out.String()
+}
+func (this *DeleteStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	container, key, err := this.Index.evalOperands(env, insideLoop)
+	if nil != err {
+		return nil, newError(this, err, "DeleteStmt.Eval")
+	}
+	hash, ok := container.(*object.Hash)
+	if !ok {
+		return nil, newError(this, nil, "DeleteStmt.Eval -> delete from %v is unsupported", object.ToString(container.(object.Object).Type()))
+	}
+	deleted, err := hash.Delete(key)
+	if nil != err {
+		return nil, newError(this, err, "DeleteStmt.Eval")
+	}
+	return object.ToBoolean(deleted), nil
+}
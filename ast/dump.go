@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var nodeType = reflect.TypeOf((*Node)(nil)).Elem()
+
+// Dump prints the tree below node, one node per line indented by depth:
+//
+//	Program 1:1 "var"
+//	  VarStmt 1:1 "var"
+//	    Name: Identifier 1:5 "x"
+//	    Value: Integer 1:9 "1"
+func Dump(node Node) string {
+	var out bytes.Buffer
+	dumpValue(&out, 0, "", reflect.ValueOf(node))
+	return out.String()
+}
+
+func dumpValue(out *bytes.Buffer, depth int, field string, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Interface:
+		if !v.IsNil() {
+			dumpValue(out, depth, field, v.Elem())
+		}
+	case reflect.Ptr:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct || v.Type().Elem().PkgPath() != nodeType.PkgPath() {
+			return
+		}
+		if node, ok := v.Interface().(Node); ok {
+			dumpLine(out, depth, field, fmt.Sprintf("%v %v %q", v.Type().Elem().Name(), node.Pos(), node.TokenLiteral()))
+		} else {
+			// the parts of a node which are no node themselves, like HashPair and IfClause
+			dumpLine(out, depth, field, v.Type().Elem().Name())
+		}
+		dumpFields(out, depth+1, v.Elem())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			name := ""
+			if "" != field {
+				name = fmt.Sprintf("%v[%v]", field, i)
+			}
+			dumpValue(out, depth, name, v.Index(i))
+		}
+	}
+}
+
+func dumpFields(out *bytes.Buffer, depth int, v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if v.Type().Field(i).PkgPath != "" || "Diags" == name {
+			continue
+		}
+		if v.Type() == reflect.TypeOf(Program{}) {
+			// a program lists its statements without a field name
+			name = ""
+		}
+		dumpValue(out, depth, name, v.Field(i))
+	}
+}
+
+func dumpLine(out *bytes.Buffer, depth int, field string, text string) {
+	out.WriteString(strings.Repeat("  ", depth))
+	if "" != field {
+		out.WriteString(field)
+		out.WriteString(": ")
+	}
+	out.WriteString(text)
+	out.WriteString("\n")
+}
@@ -0,0 +1,91 @@
+package ast
+
+import (
+	"Q/diag"
+	"Q/token"
+	"errors"
+	"fmt"
+)
+
+// Error is an evaluation error raised at Node, Err is the cause and is nil
+// when the node itself is the origin of the error
+type Error struct {
+	Node Node
+	Msg  string
+	Err  error
+	Call bool // Node is a *Call and Err was returned by the function it called
+}
+
+func newError(node Node, err error, format string, a ...interface{}) *Error {
+	return &Error{Node: node, Msg: fmt.Sprintf(format, a...), Err: err}
+}
+
+func (this *Error) Error() string {
+	if nil == this.Err {
+		return fmt.Sprintf("%v: %v", this.Node.Pos(), this.Msg)
+	}
+	return fmt.Sprintf("%v: %v | %v", this.Node.Pos(), this.Msg, this.Err)
+}
+
+func (this *Error) Unwrap() error {
+	return this.Err
+}
+
+// Diagnose turns an error returned by Eval into a Diagnostic located at the innermost node,
+// the calls the error was returned by become notes, not the calls whose callee or
+// arguments failed
+func Diagnose(err error) *diag.Diagnostic {
+	if nil == err {
+		return nil
+	}
+	var parseErr *diag.Diagnostic
+	if errors.As(err, &parseErr) {
+		return parseErr
+	}
+	var inner *Error
+	calls := []*Error{}
+	for cur := err; ; {
+		var e *Error
+		if !errors.As(cur, &e) {
+			break
+		}
+		if e.Call {
+			calls = append(calls, e)
+		}
+		inner = e
+		cur = e.Err
+	}
+	if nil == inner {
+		return diag.Errorf(diag.CodeRuntime, token.Position{}, token.Position{}, "%v", err)
+	}
+	msg := inner.Msg
+	if nil != inner.Err {
+		msg = inner.Err.Error()
+	}
+	start := inner.Node.Pos()
+	d := diag.Errorf(diag.CodeRuntime, start, start.Advance(inner.Node.TokenLiteral()), "%v", msg)
+	// a recursion repeats the same call, the repeats are counted instead of listed
+	last, repeats := "", 0
+	for i := len(calls) - 1; i >= 0; i-- {
+		if calls[i] == inner {
+			continue
+		}
+		call := calls[i].Node.(*Call)
+		note := fmt.Sprintf("called from %v at %v", call.Func.String(), call.Pos())
+		if note == last {
+			repeats++
+			continue
+		}
+		addRepeats(d, repeats)
+		d.AddNote("%v", note)
+		last, repeats = note, 0
+	}
+	addRepeats(d, repeats)
+	return d
+}
+
+func addRepeats(d *diag.Diagnostic, repeats int) {
+	if repeats > 0 {
+		d.AddNote("the call above repeated %v more times", repeats)
+	}
+}
@@ -15,6 +15,9 @@ func (this *ExpressionStmt) statementNode() {}
 func (this *ExpressionStmt) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *ExpressionStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *ExpressionStmt) String() string {
 	if this.Expr != nil {
 		return this.Expr.String()
@@ -0,0 +1,26 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+)
+
+// Float : implement Expression
+type Float struct {
+	Tok   *token.Token
+	Value float64
+}
+
+func (this *Float) expressionNode() {}
+func (this *Float) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *Float) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *Float) String() string {
+	return this.Tok.Literal
+}
+func (this *Float) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	return &object.Float{Value: this.Value}, nil
+}
@@ -4,22 +4,48 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
+	"strings"
 )
 
 // ForExpression : implement Expression
+// `for { }`, `for (cond) { }` and `for (init; cond; post) { }`, Init, Cond and Post may be nil
 type ForExpression struct {
-	Tok  *token.Token
-	Loop *BlockStmt
+	Tok    *token.Token
+	Label  *Identifier // nil when the loop has no label
+	Init   Statement
+	Cond   Expression
+	Post   Statement
+	CStyle bool
+	Loop   *BlockStmt
 }
 
 func (this *ForExpression) expressionNode() {}
 func (this *ForExpression) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *ForExpression) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *ForExpression) String() string {
 	var out bytes.Buffer
-	out.WriteString("for {")
+	out.WriteString(labelString(this.Label))
+	out.WriteString("for ")
+	if this.CStyle {
+		out.WriteString("(")
+		out.WriteString(clauseString(this.Init))
+		out.WriteString("; ")
+		if nil != this.Cond {
+			out.WriteString(this.Cond.String())
+		}
+		out.WriteString("; ")
+		out.WriteString(clauseString(this.Post))
+		out.WriteString(") ")
+	} else if nil != this.Cond {
+		out.WriteString("(")
+		out.WriteString(this.Cond.String())
+		out.WriteString(") ")
+	}
+	out.WriteString("{")
 	if nil != this.Loop {
 		out.WriteString(this.Loop.String())
 	}
@@ -27,19 +53,91 @@ func (this *ForExpression) String() string {
 	return out.String()
 }
 func (this *ForExpression) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
-	var rc object.Object
+	// the variables declared in Init live as long as the loop
+	loopEnv := object.NewEnclosedEnv(env)
+	defer loopEnv.Leave()
+
+	if nil != this.Init {
+		if _, err := this.Init.Eval(loopEnv, insideLoop); nil != err {
+			return nil, newError(this, err, "ForExpression.Eval -> init")
+		}
+	}
 	for {
-		v, err := this.Loop.Eval(env, true)
+		if err := env.Step(); nil != err {
+			return nil, newError(this, err, "ForExpression.Eval")
+		}
+		if nil != this.Cond {
+			cond, err := this.Cond.Eval(loopEnv, insideLoop)
+			if nil != err {
+				return nil, newError(this, err, "ForExpression.Eval -> cond")
+			}
+			if !cond.True() {
+				break
+			}
+		}
+		v, err := this.Loop.Eval(loopEnv, true)
 		if nil != err {
-			return nil, fmt.Errorf("ForExpression.Eval | %v", err)
+			return nil, newError(this, err, "ForExpression.Eval")
 		}
-		if isBreak, _ := v.Break(); isBreak {
-			rc = v
+		if stop, rc := loopControl(v, this.Label); stop {
+			if nil != rc {
+				return rc, nil
+			}
 			break
 		}
-		if needReturn, _ := v.Return(); needReturn {
-			return v, nil
+		if nil != this.Post {
+			if _, err := this.Post.Eval(loopEnv, insideLoop); nil != err {
+				return nil, newError(this, err, "ForExpression.Eval -> post")
+			}
+		}
+	}
+	// the break is consumed here, it must not stop an enclosing loop
+	return object.Nil, nil
+}
+
+// loopControl decides what a loop does after an iteration evaluated to v:
+// stop is false to go on, rc is not nil when v must bubble up to an enclosing loop or function
+func loopControl(v object.Object, label *Identifier) (bool, object.Object) {
+	if nil == v { // an empty body has no value
+		return false, nil
+	}
+	if isBreak, _ := v.Break(); isBreak {
+		if targets(v, label) {
+			return true, nil
 		}
+		return true, v
+	}
+	if isContinue, _ := v.Continue(); isContinue {
+		if targets(v, label) {
+			return false, nil
+		}
+		return true, v
+	}
+	if needReturn, _ := v.Return(); needReturn {
+		return true, v
+	}
+	return false, nil
+}
+
+// targets reports whether the signal v is meant for the loop with label
+func targets(v object.Object, label *Identifier) bool {
+	l, ok := v.(object.Labeled)
+	if !ok || "" == l.Label() {
+		return true
+	}
+	return nil != label && l.Label() == label.Value
+}
+
+func labelString(label *Identifier) string {
+	if nil == label {
+		return ""
+	}
+	return label.String() + ": "
+}
+
+func clauseString(stmt Statement) string {
+	if nil == stmt {
+		return ""
 	}
-	return rc, nil
+	return strings.TrimSuffix(stmt.String(), ";")
 }
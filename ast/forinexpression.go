@@ -0,0 +1,90 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+	"bytes"
+)
+
+// ForInExpression : implement Expression
+// `for (v in expr) { }` binds the element (the key for a hash), `for (k, v in expr) { }` binds both
+type ForInExpression struct {
+	Tok      *token.Token
+	Label    *Identifier // nil when the loop has no label
+	Key      *Identifier // nil for the single variable form
+	Value    *Identifier
+	Iterable Expression
+	Loop     *BlockStmt
+}
+
+func (this *ForInExpression) expressionNode() {}
+func (this *ForInExpression) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *ForInExpression) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *ForInExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString(labelString(this.Label))
+	out.WriteString("for (")
+	if nil != this.Key {
+		out.WriteString(this.Key.String())
+		out.WriteString(", ")
+	}
+	out.WriteString(this.Value.String())
+	out.WriteString(" in ")
+	out.WriteString(this.Iterable.String())
+	out.WriteString(") {")
+	if nil != this.Loop {
+		out.WriteString(this.Loop.String())
+	}
+	out.WriteString("}")
+	return out.String()
+}
+func (this *ForInExpression) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	v, err := this.Iterable.Eval(env, insideLoop)
+	if nil != err {
+		return nil, newError(this, err, "ForInExpression.Eval -> %v", this.Iterable.String())
+	}
+	iterable, ok := v.(object.Iterable)
+	if !ok {
+		return nil, newError(this, nil, "ForInExpression.Eval -> %v is not iterable", object.ToString(v.Type()))
+	}
+	_, isHash := v.(*object.Hash)
+	iter := iterable.Iter()
+	for {
+		if err := env.Step(); nil != err {
+			return nil, newError(this, err, "ForInExpression.Eval")
+		}
+		key, val, ok, err := iter.Next()
+		if nil != err {
+			return nil, newError(this, err, "ForInExpression.Eval")
+		}
+		if !ok {
+			break
+		}
+		// every iteration has its own bindings, so closures capture the current element
+		iterEnv := object.NewEnclosedEnv(env)
+		if nil != this.Key {
+			iterEnv.Set(this.Key.Value, key)
+			iterEnv.Set(this.Value.Value, val)
+		} else if isHash {
+			iterEnv.Set(this.Value.Value, key)
+		} else {
+			iterEnv.Set(this.Value.Value, val)
+		}
+		rc, err := this.Loop.Eval(iterEnv, true)
+		iterEnv.Leave()
+		if nil != err {
+			return nil, newError(this, err, "ForInExpression.Eval")
+		}
+		if stop, signal := loopControl(rc, this.Label); stop {
+			if nil != signal {
+				return signal, nil
+			}
+			break
+		}
+	}
+	return object.Nil, nil
+}
@@ -19,6 +19,9 @@ func (this *Function) expressionNode() {}
 func (this *Function) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *Function) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *Function) String() string {
 	var out bytes.Buffer
 
@@ -37,13 +40,14 @@ func (this *Function) String() string {
 func (this *Function) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	return &object.Function{
 		Fn: function.Function{
-			Inspect:    this.inspect,
+			Inspect:    this.Inspect,
 			ArgumentOf: this.argumentOf,
 			Body:       this.body,
 		},
 		Args:     this.Args.values(),
 		EvalBody: this.evalBody,
 		Env:      env,
+		Source:   env.Source(),
 	}, nil
 }
 
@@ -51,7 +55,8 @@ func (this *Function) evalBody(env *object.Env, insideLoop bool) (object.Object,
 	return this.Body.Eval(env, insideLoop)
 }
 
-func (this *Function) inspect() string {
+// Inspect returns the function as its object inspects
+func (this *Function) Inspect() string {
 	var out bytes.Buffer
 
 	args := []string{}
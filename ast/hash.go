@@ -0,0 +1,62 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+	"bytes"
+	"strings"
+)
+
+type HashPair struct {
+	Key   Expression
+	Value Expression
+}
+
+type HashPairSlice []*HashPair
+
+// Hash : implement Expression
+type Hash struct {
+	Tok   *token.Token // {
+	Pairs HashPairSlice
+}
+
+func (this *Hash) expressionNode() {}
+func (this *Hash) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *Hash) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *Hash) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range this.Pairs {
+		pairs = append(pairs, pair.Key.String()+": "+pair.Value.String())
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+func (this *Hash) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	hash := object.NewHash()
+	for _, pair := range this.Pairs {
+		key, err := pair.Key.Eval(env, insideLoop)
+		if nil != err {
+			return nil, newError(this, err, "Hash.Eval -> eval key")
+		}
+		val, err := pair.Value.Eval(env, insideLoop)
+		if nil != err {
+			return nil, newError(this, err, "Hash.Eval -> eval value")
+		}
+		if err := hash.Set(key, val); nil != err {
+			return nil, newError(this, err, "Hash.Eval")
+		}
+	}
+	if err := env.CheckSize(hash); nil != err {
+		return nil, newError(this, err, "Hash.Eval")
+	}
+	return hash, nil
+}
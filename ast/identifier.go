@@ -3,7 +3,6 @@ package ast
 import (
 	"Q/object"
 	"Q/token"
-	"fmt"
 )
 
 // Identifier : implement Expression
@@ -16,15 +15,23 @@ func (this *Identifier) expressionNode() {}
 func (this *Identifier) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *Identifier) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *Identifier) String() string {
 	return this.Value
 }
 func (this *Identifier) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
-	val, ok := env.Get(this.Value)
-	if !ok {
-		return nil, fmt.Errorf("Identifier.Eval -> `%v` not found", this.Value)
+	if val, ok := env.Get(this.Value); ok {
+		return val, nil
+	}
+	if builtin, ok := env.Builtin(this.Value); ok {
+		return builtin, nil
+	}
+	if env.Ended(this.Value) {
+		return nil, newError(this, nil, "Identifier.Eval -> `%v` not found, %v", this.Value, object.EndedHint)
 	}
-	return val, nil
+	return nil, newError(this, nil, "Identifier.Eval -> `%v` not found", this.Value)
 }
 
 type IdentifierSlice []*Identifier
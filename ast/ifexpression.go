@@ -4,7 +4,6 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
 )
 
 type IfClause struct {
@@ -25,6 +24,9 @@ func (this *IfExpression) expressionNode() {}
 func (this *IfExpression) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *IfExpression) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *IfExpression) String() string {
 	var out bytes.Buffer
 
@@ -51,7 +53,7 @@ func (this *IfExpression) Eval(env *object.Env, insideLoop bool) (object.Object,
 	for _, clause := range this.Clauses {
 		cond, err := clause.If.Eval(env, insideLoop)
 		if nil != err {
-			return nil, fmt.Errorf("IfExpression.Eval -> %v | %v", clause.If.String(), err)
+			return nil, newError(this, err, "IfExpression.Eval -> %v", clause.If.String())
 		}
 		if cond.True() {
 			return clause.Then.Eval(env, insideLoop)
@@ -0,0 +1,72 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+	"bytes"
+)
+
+// Index : implement Expression
+type Index struct {
+	Tok   *token.Token // [
+	Left  Expression
+	Index Expression
+}
+
+func (this *Index) expressionNode() {}
+func (this *Index) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *Index) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *Index) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(this.Left.String())
+	out.WriteString("[")
+	out.WriteString(this.Index.String())
+	out.WriteString("])")
+	return out.String()
+}
+func (this *Index) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	container, key, err := this.evalOperands(env, insideLoop)
+	if nil != err {
+		return nil, newError(this, err, "Index.Eval")
+	}
+	rc, err := container.Index(key)
+	if nil != err {
+		return nil, newError(this, err, "Index.Eval")
+	}
+	return rc, nil
+}
+
+func (this *Index) assign(env *object.Env, insideLoop bool, val object.Object) error {
+	container, key, err := this.evalOperands(env, insideLoop)
+	if nil != err {
+		return newError(this, err, "Index.assign")
+	}
+	if err := env.CheckSetIndex(container.(object.Object), key); nil != err {
+		return newError(this, err, "Index.assign")
+	}
+	if err := container.SetIndex(key, val); nil != err {
+		return newError(this, err, "Index.assign")
+	}
+	return nil
+}
+
+func (this *Index) evalOperands(env *object.Env, insideLoop bool) (object.Indexable, object.Object, error) {
+	left, err := this.Left.Eval(env, insideLoop)
+	if nil != err {
+		return nil, nil, err
+	}
+	container, ok := left.(object.Indexable)
+	if !ok {
+		return nil, nil, newError(this, nil, "Index.evalOperands -> %v is not indexable", object.ToString(left.Type()))
+	}
+	key, err := this.Index.Eval(env, insideLoop)
+	if nil != err {
+		return nil, nil, err
+	}
+	return container, key, nil
+}
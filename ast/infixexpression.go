@@ -4,7 +4,6 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
 )
 
 // InfixExpression : implement Expression
@@ -19,6 +18,9 @@ func (this *InfixExpression) expressionNode() {}
 func (this *InfixExpression) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *InfixExpression) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *InfixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -33,11 +35,87 @@ func (this *InfixExpression) String() string {
 func (this *InfixExpression) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	left, err := this.Left.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("InfixExpression.Eval -> this.Left.Eval() error | %v", err)
+		return nil, newError(this, err, "InfixExpression.Eval -> this.Left.Eval() error")
+	}
+	if this.decidedBy(left) {
+		if !this.ShortCircuits() {
+			return this.calcLiteral(left)
+		}
+		return left, nil
 	}
 	right, err := this.Right.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("InfixExpression.Eval -> this.Right.Eval() error | %v", err)
+		return nil, newError(this, err, "InfixExpression.Eval -> this.Right.Eval() error")
+	}
+	var rc object.Object
+	if this.Op.TypeIs(token.IN) {
+		rc, err = object.In(left, right)
+	} else {
+		rc, err = left.Calc(this.Op, right)
+	}
+	if nil != err {
+		return nil, newError(this, err, "InfixExpression.Eval")
+	}
+	if err := env.CheckSize(rc); nil != err {
+		return nil, newError(this, err, "InfixExpression.Eval")
+	}
+	return rc, nil
+}
+
+// decidedBy reports whether left alone decides `&&` or `||`, Right is then not evaluated
+func (this *InfixExpression) decidedBy(left object.Object) bool {
+	switch this.Op.Type {
+	case token.AND:
+		return !left.True()
+	case token.OR:
+		return left.True()
+	default:
+		return false
+	}
+}
+
+// ShortCircuits reports whether a decided `&&` or `||` is left itself, a literal Right
+// keeps the coercion of Calc instead (`false && 2` is 0)
+func (this *InfixExpression) ShortCircuits() bool {
+	if !this.Op.TypeIs(token.AND) && !this.Op.TypeIs(token.OR) {
+		return false
+	}
+	_, ok := literal(this.Right)
+	return !ok
+}
+
+// calcLiteral returns `left op Right` for a literal Right, which needs no evaluation
+func (this *InfixExpression) calcLiteral(left object.Object) (object.Object, error) {
+	right, _ := literal(this.Right)
+	rc, err := left.Calc(this.Op, right)
+	if nil != err {
+		return nil, newError(this, err, "InfixExpression.Eval")
+	}
+	return rc, nil
+}
+
+// literal returns the value of a literal scalar such as 2 or -2.5, a prefix which fails
+// on its operand is no literal
+func literal(expr Expression) (object.Object, bool) {
+	switch e := expr.(type) {
+	case *Integer:
+		return &object.Integer{Value: e.Value}, true
+	case *Float:
+		return &object.Float{Value: e.Value}, true
+	case *Boolean:
+		return object.ToBoolean(e.Value), true
+	case *Null:
+		return object.Nil, true
+	case *String:
+		return &object.String{Value: e.Value}, true
+	case *PrefixExpression:
+		right, ok := literal(e.Right)
+		if !ok {
+			return nil, false
+		}
+		rc, err := evalPrefixExpression(e.Op, right)
+		return rc, nil == err
+	default:
+		return nil, false
 	}
-	return left.Calc(this.Op, right)
 }
@@ -15,6 +15,9 @@ func (this *Integer) expressionNode() {}
 func (this *Integer) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *Integer) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *Integer) String() string {
 	return this.Tok.Literal
 }
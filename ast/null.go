@@ -14,6 +14,9 @@ func (this *Null) expressionNode() {}
 func (this *Null) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *Null) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *Null) String() string {
 	return this.Tok.Literal
 }
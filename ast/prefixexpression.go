@@ -4,7 +4,6 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
 )
 
 // PrefixExpression : implement Expression
@@ -18,6 +17,9 @@ func (this *PrefixExpression) expressionNode() {}
 func (this *PrefixExpression) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *PrefixExpression) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *PrefixExpression) String() string {
 	var out bytes.Buffer
 	out.WriteString("(")
@@ -29,7 +31,11 @@ func (this *PrefixExpression) String() string {
 func (this *PrefixExpression) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	right, err := this.Right.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("PrefixExpression.Eval -> this.Right.Eval() error | %v", err)
+		return nil, newError(this, err, "PrefixExpression.Eval -> this.Right.Eval() error")
+	}
+	rc, err := evalPrefixExpression(this.Op, right)
+	if nil != err {
+		return nil, newError(this, err, "PrefixExpression.Eval")
 	}
-	return evalPrefixExpression(this.Op, right)
+	return rc, nil
 }
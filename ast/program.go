@@ -1,13 +1,17 @@
 package ast
 
 import (
+	"Q/diag"
 	"Q/object"
+	"Q/token"
 	"bytes"
+	"fmt"
 )
 
 // Program : implement Node
 type Program struct {
 	Stmts StatementSlice
+	Diags []*diag.Diagnostic // the parse errors, a program with any is never evaluated
 }
 
 func (this *Program) TokenLiteral() string {
@@ -17,6 +21,13 @@ func (this *Program) TokenLiteral() string {
 	return ""
 }
 
+func (this *Program) Pos() token.Position {
+	if len(this.Stmts) > 0 {
+		return this.Stmts[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (this *Program) String() string {
 	var out bytes.Buffer
 	for _, s := range this.Stmts {
@@ -26,5 +37,16 @@ func (this *Program) String() string {
 }
 
 func (this *Program) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	if err := this.Check(); nil != err {
+		return nil, err
+	}
 	return this.Stmts.eval(false, env, false)
 }
+
+// Check returns the error of evaluating a program with parse errors, it unwraps to the first one
+func (this *Program) Check() error {
+	if len(this.Diags) > 0 {
+		return fmt.Errorf("Program.Eval -> %v parse errors | %w", len(this.Diags), this.Diags[0])
+	}
+	return nil
+}
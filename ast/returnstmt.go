@@ -4,7 +4,6 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
 )
 
 // ReturnStmt : implement Statement
@@ -17,6 +16,9 @@ func (this *ReturnStmt) statementNode() {}
 func (this *ReturnStmt) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *ReturnStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *ReturnStmt) String() string {
 	var out bytes.Buffer
 	out.WriteString(this.TokenLiteral())
@@ -31,7 +33,7 @@ func (this *ReturnStmt) String() string {
 func (this *ReturnStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	val, err := this.ReturnValue.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("ReturnStmt.Eval | %v", err)
+		return nil, newError(this, err, "ReturnStmt.Eval")
 	}
 	return &object.ReturnValue{Value: val}, nil
 }
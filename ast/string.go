@@ -0,0 +1,26 @@
+package ast
+
+import (
+	"Q/object"
+	"Q/token"
+)
+
+// String : implement Expression
+type String struct {
+	Tok   *token.Token
+	Value string
+}
+
+func (this *String) expressionNode() {}
+func (this *String) TokenLiteral() string {
+	return this.Tok.Literal
+}
+func (this *String) Pos() token.Position {
+	return this.Tok.Pos
+}
+func (this *String) String() string {
+	return object.Quote(this.Value)
+}
+func (this *String) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	return &object.String{Value: this.Value}, nil
+}
@@ -4,7 +4,6 @@ import (
 	"Q/object"
 	"Q/token"
 	"bytes"
-	"fmt"
 )
 
 // VarStmt : implement Statement
@@ -18,6 +17,9 @@ func (this *VarStmt) statementNode() {}
 func (this *VarStmt) TokenLiteral() string {
 	return this.Tok.Literal
 }
+func (this *VarStmt) Pos() token.Position {
+	return this.Tok.Pos
+}
 func (this *VarStmt) String() string {
 	var out bytes.Buffer
 	out.WriteString(this.TokenLiteral())
@@ -33,7 +35,7 @@ func (this *VarStmt) String() string {
 func (this *VarStmt) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
 	val, err := this.Value.Eval(env, insideLoop)
 	if nil != err {
-		return nil, fmt.Errorf("VarStmt.Eval | %v", err)
+		return nil, newError(this, err, "VarStmt.Eval")
 	}
 	env.Set(this.Name.Value, val)
 	return val, nil
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"Q/code"
+	"Q/q"
+)
+
+const (
+	exitOK    = 0
+	exitError = 1 // parse or runtime error
+	exitUsage = 2
+)
+
+const usage = `usage:
+  Q                       start the REPL, or run the program read from stdin when it is not a terminal
+  Q run file.q [args...]  run a script, or a program compiled by Q compile
+  Q compile file.q -o file.qc
+                          compile a script to bytecode which loads without parsing
+  Q -e 'code' [args...]   run code given on the command line
+  Q -h                    show this help
+
+scripts read their arguments from the array args and stop with exit(code)
+`
+
+// cli runs the command line args and returns the exit status
+func cli(args []string, stdin io.Reader, interactive bool, out io.Writer, errOut io.Writer) int {
+	if len(args) < 1 {
+		if interactive {
+			return repl(stdin, out)
+		}
+		src, err := ioutil.ReadAll(stdin)
+		if nil != err {
+			fmt.Fprintf(errOut, "Q: read stdin: %v\n", err)
+			return exitError
+		}
+		return runSource("<stdin>", string(src), nil, out, errOut)
+	}
+	switch args[0] {
+	case "run":
+		if len(args) < 2 {
+			fmt.Fprint(errOut, usage)
+			return exitUsage
+		}
+		src, err := ioutil.ReadFile(args[1])
+		if nil != err {
+			fmt.Fprintf(errOut, "Q: %v\n", err)
+			return exitError
+		}
+		if code.IsEncoded(src) {
+			prog, err := q.Load(bytes.NewReader(src))
+			if nil != err {
+				fmt.Fprintf(errOut, "Q: %v: %v\n", args[1], err)
+				return exitError
+			}
+			return runProgram(prog, args[2:], out, errOut)
+		}
+		return runSource(args[1], string(src), args[2:], out, errOut)
+	case "compile":
+		if 4 != len(args) || "-o" != args[2] {
+			fmt.Fprint(errOut, usage)
+			return exitUsage
+		}
+		return compileFile(args[1], args[3], errOut)
+	case "-e":
+		if len(args) < 2 {
+			fmt.Fprint(errOut, usage)
+			return exitUsage
+		}
+		return runSource("-e", args[1], args[2:], out, errOut)
+	case "-h", "--help", "help":
+		fmt.Fprint(out, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(errOut, "Q: unknown command %v\n", args[0])
+		fmt.Fprint(errOut, usage)
+		return exitUsage
+	}
+}
+
+// runSource compiles and runs a whole program, errors are rendered to errOut
+func runSource(name string, src string, scriptArgs []string, out io.Writer, errOut io.Writer) int {
+	prog, err := q.Compile(src, q.WithName(name))
+	if nil != err {
+		return exitStatus(err, errOut)
+	}
+	return runProgram(prog, scriptArgs, out, errOut)
+}
+
+// runProgram runs prog with the script arguments
+func runProgram(prog *q.Program, scriptArgs []string, out io.Writer, errOut io.Writer) int {
+	env := q.NewEnv(q.WithStdout(out), q.WithStderr(errOut))
+	if nil == scriptArgs {
+		scriptArgs = []string{}
+	}
+	if err := env.Set("args", scriptArgs); nil != err {
+		return exitStatus(err, errOut)
+	}
+	if _, err := prog.Run(context.Background(), env); nil != err {
+		return exitStatus(err, errOut)
+	}
+	return exitOK
+}
+
+// compileFile saves the bytecode of the script in to the file out
+func compileFile(in string, out string, errOut io.Writer) int {
+	src, err := ioutil.ReadFile(in)
+	if nil != err {
+		fmt.Fprintf(errOut, "Q: %v\n", err)
+		return exitError
+	}
+	prog, err := q.Compile(string(src), q.WithName(in), q.WithBackend(q.VM))
+	if nil != err {
+		return exitStatus(err, errOut)
+	}
+	var buf bytes.Buffer
+	if err := prog.Save(&buf); nil != err {
+		return exitStatus(err, errOut)
+	}
+	if err := ioutil.WriteFile(out, buf.Bytes(), 0644); nil != err {
+		fmt.Fprintf(errOut, "Q: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+// exitStatus maps an error of Compile or Run to the status of the process,
+// exit(code) is not an error
+func exitStatus(err error, errOut io.Writer) int {
+	var exit *q.ExitError
+	var syntaxErr *q.SyntaxError
+	var runtimeErr *q.RuntimeError
+	switch {
+	case errors.As(err, &exit):
+		return exit.Code
+	case errors.As(err, &syntaxErr):
+		io.WriteString(errOut, syntaxErr.Render())
+	case errors.As(err, &runtimeErr):
+		io.WriteString(errOut, runtimeErr.Render())
+	default:
+		fmt.Fprintf(errOut, "Q: %v\n", err)
+	}
+	return exitError
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if nil != err {
+		return false
+	}
+	return 0 != fi.Mode()&os.ModeCharDevice
+}
@@ -0,0 +1,221 @@
+// Package code defines the bytecode the compiler emits and the vm runs
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is a sequence of encoded instructions, an opcode followed by its
+// operands in big endian
+type Instructions []byte
+
+// Opcode is the first byte of an instruction
+type Opcode byte
+
+const (
+	OpConstant      Opcode = iota // push constant [index]
+	OpNull                        // push null
+	OpTrue                        // push true
+	OpFalse                       // push false
+	OpPop                         // drop the top
+	OpGetLocal                    // push local [slot]
+	OpSetLocal                    // store the top in local [slot], the top stays
+	OpMakeCell                    // give the frame a new empty cell [index]
+	OpGetCell                     // push the value of cell [index], [site] fails when it is empty
+	OpSetCell                     // store the top in cell [index], the top stays
+	OpGetFree                     // push the value of captured cell [index], [site] fails when it is empty
+	OpSetFree                     // store the top in captured cell [index], the top stays
+	OpGetGlobal                   // push global or builtin [name], [hint] adds the ended block hint when [site] fails
+	OpDefineGlobal                // define global [name] as the top, the top stays
+	OpSetGlobal                   // assign the top to the existing global [name], see OpGetGlobal
+	OpArray                       // replace the top [count] by an array of them
+	OpHash                        // replace the top [count] keys and values by a hash of them
+	OpIndex                       // pop a key and replace the container below by its element
+	OpSetIndex                    // pop a key and a container and set the element to the top, the top stays
+	OpDelete                      // pop a key and replace the hash below by whether it held it, [site] [index site]
+	OpBinary                      // replace the top two by the result of [operator]
+	OpNot                         // replace the top by !top
+	OpNeg                         // replace the top by -top
+	OpJump                        // go to [offset]
+	OpJumpIfFalse                 // pop the top, go to [offset] when it is false
+	OpJumpIfDecided               // go to [offset] when the top alone decides [operator], && or ||
+	OpIter                        // pop an iterable and start iterating it
+	OpIterNext                    // push the next element ([form] 1) or index and element ([form] 2), go to [offset] at the end
+	OpIterEnd                     // stop the innermost iteration
+	OpStep                        // count a step of [kind] for the limits
+	OpCall                        // call the function below the top [count] arguments, replace them by the result
+	OpReturn                      // return the top from the function
+	OpClosure                     // push a closure of function [index]
+	OpFail                        // fail with message [name] at [site]
+)
+
+// the kinds of OpStep, the tree-walker counts a step before each of them
+const (
+	StepStatement = iota
+	StepFor
+	StepForIn
+)
+
+// Definition describes the operands of an Opcode
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+// the operands naming a site are 4 bytes wide, like the jump offsets
+var definitions = map[Opcode]*Definition{
+	OpConstant:      {"OpConstant", []int{2}},
+	OpNull:          {"OpNull", []int{}},
+	OpTrue:          {"OpTrue", []int{}},
+	OpFalse:         {"OpFalse", []int{}},
+	OpPop:           {"OpPop", []int{}},
+	OpGetLocal:      {"OpGetLocal", []int{2}},
+	OpSetLocal:      {"OpSetLocal", []int{2}},
+	OpMakeCell:      {"OpMakeCell", []int{2}},
+	OpGetCell:       {"OpGetCell", []int{2, 4}},
+	OpSetCell:       {"OpSetCell", []int{2}},
+	OpGetFree:       {"OpGetFree", []int{2, 4}},
+	OpSetFree:       {"OpSetFree", []int{2}},
+	OpGetGlobal:     {"OpGetGlobal", []int{2, 1, 4}},
+	OpDefineGlobal:  {"OpDefineGlobal", []int{2}},
+	OpSetGlobal:     {"OpSetGlobal", []int{2, 1, 4}},
+	OpArray:         {"OpArray", []int{2, 4}},
+	OpHash:          {"OpHash", []int{2, 4}},
+	OpIndex:         {"OpIndex", []int{4}},
+	OpSetIndex:      {"OpSetIndex", []int{4}},
+	OpDelete:        {"OpDelete", []int{4, 4}},
+	OpBinary:        {"OpBinary", []int{1, 4}},
+	OpNot:           {"OpNot", []int{4}},
+	OpNeg:           {"OpNeg", []int{4}},
+	OpJump:          {"OpJump", []int{4}},
+	OpJumpIfFalse:   {"OpJumpIfFalse", []int{4}},
+	OpJumpIfDecided: {"OpJumpIfDecided", []int{1, 4}},
+	OpIter:          {"OpIter", []int{4}},
+	OpIterNext:      {"OpIterNext", []int{1, 4, 4}},
+	OpIterEnd:       {"OpIterEnd", []int{}},
+	OpStep:          {"OpStep", []int{1, 4}},
+	OpCall:          {"OpCall", []int{2, 4}},
+	OpReturn:        {"OpReturn", []int{}},
+	OpClosure:       {"OpClosure", []int{2}},
+	OpFail:          {"OpFail", []int{2, 4}},
+}
+
+// Lookup returns the Definition of op
+func Lookup(op byte) (*Definition, error) {
+	def, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("code.Lookup -> opcode %v undefined", op)
+	}
+	return def, nil
+}
+
+// StackEffect returns how many values an instruction pops and pushes, OpIterNext pushes
+// them only when it does not jump to the end
+func StackEffect(op Opcode, operands []int) (pops, pushes int) {
+	switch op {
+	case OpConstant, OpNull, OpTrue, OpFalse, OpGetLocal, OpGetCell, OpGetFree, OpGetGlobal, OpClosure:
+		return 0, 1
+	case OpSetLocal, OpSetCell, OpSetFree, OpDefineGlobal, OpSetGlobal, OpNot, OpNeg, OpJumpIfDecided:
+		return 1, 1
+	case OpPop, OpJumpIfFalse, OpIter, OpReturn:
+		return 1, 0
+	case OpIndex, OpDelete, OpBinary:
+		return 2, 1
+	case OpSetIndex:
+		return 3, 1
+	case OpArray:
+		return operands[0], 1
+	case OpHash:
+		return 2 * operands[0], 1
+	case OpIterNext:
+		return 0, operands[0]
+	case OpCall:
+		return operands[0] + 1, 1
+	default:
+		return 0, 0
+	}
+}
+
+// Make encodes an instruction, the operands beyond the width of their field are truncated
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+	size := 1
+	for _, w := range def.OperandWidths {
+		size += w
+	}
+	ins := make([]byte, size)
+	ins[0] = byte(op)
+	offset := 1
+	for i, o := range operands {
+		switch def.OperandWidths[i] {
+		case 1:
+			ins[offset] = byte(o)
+		case 2:
+			binary.BigEndian.PutUint16(ins[offset:], uint16(o))
+		case 4:
+			binary.BigEndian.PutUint32(ins[offset:], uint32(o))
+		}
+		offset += def.OperandWidths[i]
+	}
+	return ins
+}
+
+// ReadOperands decodes the operands of an instruction of def from ins, it returns them
+// and the number of bytes read
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for i, w := range def.OperandWidths {
+		switch w {
+		case 1:
+			operands[i] = int(ins[offset])
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 4:
+			operands[i] = int(ReadUint32(ins[offset:]))
+		}
+		offset += w
+	}
+	return operands, offset
+}
+
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+func ReadUint32(ins Instructions) uint32 {
+	return binary.BigEndian.Uint32(ins)
+}
+
+// String disassembles the instructions, one per line prefixed with its offset
+func (this Instructions) String() string {
+	var out bytes.Buffer
+	for i := 0; i < len(this); {
+		def, err := Lookup(this[i])
+		if nil != err {
+			fmt.Fprintf(&out, "ERROR: %v\n", err)
+			return out.String()
+		}
+		operands, read := ReadOperands(def, this[i+1:])
+		fmt.Fprintf(&out, "%04d %v\n", i, formatInstruction(def, operands))
+		i += 1 + read
+	}
+	return out.String()
+}
+
+func formatInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %v does not match defined %v", len(operands), len(def.OperandWidths))
+	}
+	var out bytes.Buffer
+	out.WriteString(def.Name)
+	for _, o := range operands {
+		fmt.Fprintf(&out, " %v", o)
+	}
+	return out.String()
+}
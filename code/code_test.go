@@ -0,0 +1,153 @@
+package code
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"Q/object"
+	"Q/token"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		operands []int
+		expected []byte
+	}{
+		{OpConstant, []int{65534}, []byte{byte(OpConstant), 255, 254}},
+		{OpPop, []int{}, []byte{byte(OpPop)}},
+		{OpGetGlobal, []int{2, 1, 70000}, []byte{byte(OpGetGlobal), 0, 2, 1, 0, 1, 17, 112}},
+	}
+	for _, tt := range tests {
+		ins := Make(tt.op, tt.operands...)
+		if !reflect.DeepEqual(ins, tt.expected) {
+			t.Errorf("Make(%v, %v) = %v, want %v", tt.op, tt.operands, ins, tt.expected)
+		}
+		def, err := Lookup(byte(tt.op))
+		if nil != err {
+			t.Fatal(err)
+		}
+		operands, read := ReadOperands(def, Instructions(ins[1:]))
+		if read != len(ins)-1 || !reflect.DeepEqual(operands, tt.operands) {
+			t.Errorf("ReadOperands(%v) = %v, %v, want %v", def.Name, operands, read, tt.operands)
+		}
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	ins := Instructions{}
+	for _, i := range [][]byte{
+		Make(OpConstant, 1),
+		Make(OpStep, StepStatement, 3),
+		Make(OpJumpIfDecided, 14, 20),
+		Make(OpReturn),
+	} {
+		ins = append(ins, i...)
+	}
+	expected := "0000 OpConstant 1\n0003 OpStep 0 3\n0009 OpJumpIfDecided 14 20\n0015 OpReturn\n"
+	if ins.String() != expected {
+		t.Errorf("instructions wrong\nwant %q\ngot  %q", expected, ins.String())
+	}
+	if _, err := Lookup(255); nil == err {
+		t.Errorf("opcode 255 defined")
+	}
+}
+
+// testProgram makes a Program which passes verify
+func testProgram() *Program {
+	return &Program{
+		Main: &Function{
+			Params: []string{}, NumLocals: 1, NumCells: 1, MaxStack: 2, Free: []Capture{},
+			Instructions: concat(Make(OpConstant, 0), Make(OpClosure, 0), Make(OpPop), Make(OpReturn)),
+			Sites:        []Site{{Pos: token.Position{File: "a.q", Line: 1, Col: 2, Offset: 1}, Literal: "f", Callee: "f", Calls: []int{0}}},
+		},
+		Functions: []*Function{{Params: []string{"x"}, NumLocals: 1, MaxStack: 1, Free: []Capture{{Local: true, Index: 0}},
+			Instructions: concat(Make(OpNull), Make(OpReturn)), Sites: []Site{}, Inspect: "func(x) {}", Body: "{}"}},
+		Constants: []object.Object{&object.Integer{Value: -7}, &object.Float{Value: 0.5}, &object.String{Value: "é"}},
+		Names:     []string{"f", ""},
+		Name:      "a.q",
+		Source:    "f(1)",
+	}
+}
+
+func concat(ins ...[]byte) Instructions {
+	out := Instructions{}
+	for _, i := range ins {
+		out = append(out, i...)
+	}
+	return out
+}
+
+func TestEncodeDecode(t *testing.T) {
+	prog := testProgram()
+	var buf bytes.Buffer
+	if err := prog.Encode(&buf); nil != err {
+		t.Fatal(err)
+	}
+	decoded, err := Decode(&buf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(prog, decoded) {
+		t.Errorf("decoded %#v, want %#v", decoded, prog)
+	}
+
+	prog.Constants = []object.Object{object.Nil}
+	if err := prog.Encode(&buf); nil == err {
+		t.Errorf("encoded a null constant")
+	}
+}
+
+// a valid checksum over a program the vm cannot run fails with ErrFormat
+func TestDecodeVerify(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(p *Program)
+	}{
+		{"undefined opcode", func(p *Program) { p.Main.Instructions = Instructions{255} }},
+		{"cut short", func(p *Program) { p.Main.Instructions = Instructions(Make(OpConstant, 0))[:2] }},
+		{"constant", func(p *Program) { p.Main.Instructions = concat(Make(OpConstant, 0xffff), Make(OpReturn)) }},
+		{"local", func(p *Program) { p.Main.Instructions = concat(Make(OpGetLocal, 1), Make(OpReturn)) }},
+		{"cell", func(p *Program) { p.Main.Instructions = concat(Make(OpMakeCell, 1), Make(OpNull), Make(OpReturn)) }},
+		{"free", func(p *Program) { p.Functions[0].Instructions = concat(Make(OpGetFree, 1, 0), Make(OpReturn)) }},
+		{"name", func(p *Program) { p.Main.Instructions = concat(Make(OpGetGlobal, 2, 0, 0), Make(OpReturn)) }},
+		{"function", func(p *Program) { p.Main.Instructions = concat(Make(OpClosure, 1), Make(OpReturn)) }},
+		{"capture", func(p *Program) { p.Main.NumCells = 0 }},
+		{"site", func(p *Program) { p.Main.Instructions = concat(Make(OpNull), Make(OpNot, 1), Make(OpReturn)) }},
+		{"site call", func(p *Program) { p.Main.Sites[0].Calls = []int{1} }},
+		{"operator", func(p *Program) {
+			p.Main.Instructions = concat(Make(OpNull), Make(OpNull), Make(OpBinary, int(token.LPAREN), 0), Make(OpReturn))
+		}},
+		{"step", func(p *Program) { p.Main.Instructions = concat(Make(OpStep, 3, 0), Make(OpNull), Make(OpReturn)) }},
+		{"jump inside an instruction", func(p *Program) { p.Main.Instructions = concat(Make(OpJump, 1), Make(OpNull), Make(OpReturn)) }},
+		{"jump past the end", func(p *Program) { p.Main.Instructions = concat(Make(OpJump, 99), Make(OpNull), Make(OpReturn)) }},
+		{"runs past the end", func(p *Program) { p.Main.Instructions = concat(Make(OpNull)) }},
+		{"no instructions", func(p *Program) { p.Functions[0].Instructions, p.Functions[0].MaxStack = Instructions{}, 0 }},
+		{"stack underflow", func(p *Program) { p.Main.Instructions = concat(Make(OpPop), Make(OpNull), Make(OpReturn)) }},
+		{"beyond MaxStack", func(p *Program) { p.Functions[0].MaxStack = 0 }},
+		{"MaxStack", func(p *Program) { p.Main.MaxStack = 1 << 30 }},
+		{"call", func(p *Program) { p.Main.Instructions = concat(Make(OpNull), Make(OpCall, 1, 0), Make(OpReturn)) }},
+		{"depths differ", func(p *Program) {
+			p.Main.Instructions = concat(Make(OpTrue), Make(OpJumpIfFalse, 7), Make(OpNull), Make(OpNull), Make(OpReturn))
+		}},
+		{"no iteration", func(p *Program) { p.Main.Instructions = concat(Make(OpIterEnd), Make(OpNull), Make(OpReturn)) }},
+		{"iteration form", func(p *Program) {
+			p.Main.Instructions = concat(Make(OpNull), Make(OpIter, 0), Make(OpIterNext, 3, 16, 0), Make(OpNull), Make(OpReturn))
+		}},
+		{"params", func(p *Program) { p.Functions[0].NumLocals = 0 }},
+		{"main params", func(p *Program) { p.Main.Params = []string{"x"} }},
+	}
+	for _, tt := range tests {
+		prog := testProgram()
+		tt.mutate(prog)
+		var buf bytes.Buffer
+		if err := prog.Encode(&buf); nil != err {
+			t.Fatal(err)
+		}
+		if _, err := Decode(&buf); !errors.Is(err, ErrFormat) {
+			t.Errorf("[%v] Decode error %v, want %v", tt.name, err, ErrFormat)
+		}
+	}
+}
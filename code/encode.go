@@ -0,0 +1,289 @@
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"math"
+
+	"Q/object"
+	"Q/token"
+)
+
+// An encoded Program is the magic, the format version, the payload and the crc32 of
+// everything before it. The integers of the payload are varints, the strings and
+// lists are prefixed by their length.
+const (
+	Magic         = "Q\x00bc"
+	FormatVersion = 1
+)
+
+const headerSize = len(Magic) + 2
+
+var (
+	ErrFormat   = errors.New("not a compiled Q program")
+	ErrVersion  = errors.New("compiled by a different format version")
+	ErrChecksum = errors.New("checksum mismatch")
+)
+
+// constant tags
+const (
+	tagInteger byte = iota
+	tagFloat
+	tagString
+)
+
+// IsEncoded reports whether data starts as an encoded Program
+func IsEncoded(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// Encode writes the Program in the binary format Decode reads
+func (this *Program) Encode(w io.Writer) error {
+	e := &encoder{}
+	e.buf.WriteString(Magic)
+	binary.Write(&e.buf, binary.BigEndian, uint16(FormatVersion))
+	e.string(this.Name)
+	e.string(this.Source)
+	e.uint(len(this.Constants))
+	for _, c := range this.Constants {
+		switch c := c.(type) {
+		case *object.Integer:
+			e.buf.WriteByte(tagInteger)
+			e.int(c.Value)
+		case *object.Float:
+			e.buf.WriteByte(tagFloat)
+			binary.Write(&e.buf, binary.BigEndian, math.Float64bits(c.Value))
+		case *object.String:
+			e.buf.WriteByte(tagString)
+			e.string(c.Value)
+		default:
+			return fmt.Errorf("Program.Encode -> constant %v is not encodable", c.Inspect())
+		}
+	}
+	e.strings(this.Names)
+	e.function(this.Main)
+	e.uint(len(this.Functions))
+	for _, f := range this.Functions {
+		e.function(f)
+	}
+	binary.Write(&e.buf, binary.BigEndian, crc32.ChecksumIEEE(e.buf.Bytes()))
+	if _, err := w.Write(e.buf.Bytes()); nil != err {
+		return fmt.Errorf("Program.Encode -> %w", err)
+	}
+	return nil
+}
+
+// Decode reads a Program written by Encode, a Program of another format version fails
+// with ErrVersion, a corrupted one with ErrChecksum and one the vm cannot run safely with
+// ErrFormat
+func Decode(r io.Reader) (*Program, error) {
+	data, err := ioutil.ReadAll(r)
+	if nil != err {
+		return nil, fmt.Errorf("code.Decode -> %w", err)
+	}
+	if !IsEncoded(data) || len(data) < headerSize+4 {
+		return nil, fmt.Errorf("code.Decode -> %w", ErrFormat)
+	}
+	if version := binary.BigEndian.Uint16(data[len(Magic):]); FormatVersion != version {
+		return nil, fmt.Errorf("code.Decode -> %w: version %v, want %v", ErrVersion, version, FormatVersion)
+	}
+	payload, sum := data[:len(data)-4], binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, fmt.Errorf("code.Decode -> %w", ErrChecksum)
+	}
+	d := &decoder{data: payload[headerSize:]}
+	this := &Program{Name: d.string(), Source: d.string()}
+	this.Constants = make([]object.Object, d.len())
+	for i := range this.Constants {
+		switch tag := d.byte(); tag {
+		case tagInteger:
+			this.Constants[i] = &object.Integer{Value: d.int()}
+		case tagFloat:
+			this.Constants[i] = &object.Float{Value: math.Float64frombits(binary.BigEndian.Uint64(d.bytes(8)))}
+		case tagString:
+			this.Constants[i] = &object.String{Value: d.string()}
+		default:
+			d.fail("constant tag %v undefined", tag)
+		}
+	}
+	this.Names = d.strings()
+	this.Main = d.function()
+	this.Functions = make([]*Function, d.len())
+	for i := range this.Functions {
+		this.Functions[i] = d.function()
+	}
+	if nil == d.err && len(d.data) > 0 {
+		d.fail("%v trailing bytes", len(d.data))
+	}
+	if nil == d.err {
+		if err := this.verify(); nil != err {
+			d.fail("%v", err)
+		}
+	}
+	if nil != d.err {
+		return nil, fmt.Errorf("code.Decode -> %w: %v", ErrFormat, d.err)
+	}
+	return this, nil
+}
+
+type encoder struct {
+	buf bytes.Buffer
+}
+
+func (this *encoder) uint(n int) {
+	var b [binary.MaxVarintLen64]byte
+	this.buf.Write(b[:binary.PutUvarint(b[:], uint64(n))])
+}
+
+func (this *encoder) int(n int64) {
+	var b [binary.MaxVarintLen64]byte
+	this.buf.Write(b[:binary.PutVarint(b[:], n)])
+}
+
+func (this *encoder) string(s string) {
+	this.uint(len(s))
+	this.buf.WriteString(s)
+}
+
+func (this *encoder) strings(ss []string) {
+	this.uint(len(ss))
+	for _, s := range ss {
+		this.string(s)
+	}
+}
+
+func (this *encoder) function(f *Function) {
+	this.strings(f.Params)
+	this.uint(f.NumLocals)
+	this.uint(f.NumCells)
+	this.uint(f.MaxStack)
+	this.uint(len(f.Free))
+	for _, c := range f.Free {
+		if c.Local {
+			this.buf.WriteByte(1)
+		} else {
+			this.buf.WriteByte(0)
+		}
+		this.uint(c.Index)
+	}
+	this.uint(len(f.Instructions))
+	this.buf.Write(f.Instructions)
+	this.uint(len(f.Sites))
+	for _, s := range f.Sites {
+		this.string(s.Pos.File)
+		this.uint(s.Pos.Line)
+		this.uint(s.Pos.Col)
+		this.uint(s.Pos.Offset)
+		this.string(s.Literal)
+		this.string(s.Callee)
+		this.uint(len(s.Calls))
+		for _, c := range s.Calls {
+			this.uint(c)
+		}
+	}
+	this.string(f.Inspect)
+	this.string(f.Body)
+}
+
+// decoder reads the payload, the first error sticks and the reads after it return zeros
+type decoder struct {
+	data []byte
+	err  error
+}
+
+func (this *decoder) fail(format string, args ...interface{}) {
+	if nil == this.err {
+		this.err = fmt.Errorf(format, args...)
+	}
+	this.data = nil
+}
+
+func (this *decoder) bytes(n int) []byte {
+	if nil != this.err || n > len(this.data) {
+		this.fail("unexpected end of data")
+		return make([]byte, n)
+	}
+	b := this.data[:n]
+	this.data = this.data[n:]
+	return b
+}
+
+func (this *decoder) byte() byte {
+	return this.bytes(1)[0]
+}
+
+func (this *decoder) uint() int {
+	if nil != this.err {
+		return 0
+	}
+	n, size := binary.Uvarint(this.data)
+	if size <= 0 || n > math.MaxInt32 {
+		this.fail("malformed integer")
+		return 0
+	}
+	this.data = this.data[size:]
+	return int(n)
+}
+
+func (this *decoder) int() int64 {
+	if nil != this.err {
+		return 0
+	}
+	n, size := binary.Varint(this.data)
+	if size <= 0 {
+		this.fail("malformed integer")
+		return 0
+	}
+	this.data = this.data[size:]
+	return n
+}
+
+// len reads the length of a list, each element takes a byte at least
+func (this *decoder) len() int {
+	n := this.uint()
+	if n > len(this.data) {
+		this.fail("unexpected end of data")
+		return 0
+	}
+	return n
+}
+
+func (this *decoder) string() string {
+	return string(this.bytes(this.len()))
+}
+
+func (this *decoder) strings() []string {
+	ss := make([]string, this.len())
+	for i := range ss {
+		ss[i] = this.string()
+	}
+	return ss
+}
+
+func (this *decoder) function() *Function {
+	f := &Function{Params: this.strings(), NumLocals: this.uint(), NumCells: this.uint(), MaxStack: this.uint()}
+	f.Free = make([]Capture, this.len())
+	for i := range f.Free {
+		f.Free[i] = Capture{Local: 1 == this.byte(), Index: this.uint()}
+	}
+	f.Instructions = Instructions(append([]byte{}, this.bytes(this.len())...))
+	f.Sites = make([]Site, this.len())
+	for i := range f.Sites {
+		s := &f.Sites[i]
+		s.Pos = token.Position{File: this.string(), Line: this.uint(), Col: this.uint(), Offset: this.uint()}
+		s.Literal = this.string()
+		s.Callee = this.string()
+		s.Calls = make([]int, this.len())
+		for j := range s.Calls {
+			s.Calls[j] = this.uint()
+		}
+	}
+	f.Inspect = this.string()
+	f.Body = this.string()
+	return f
+}
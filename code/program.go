@@ -0,0 +1,44 @@
+package code
+
+import (
+	"Q/object"
+	"Q/token"
+)
+
+// Program is a compiled Q program
+type Program struct {
+	Main      *Function       // the top level statements
+	Functions []*Function     // the function literals, indexed by OpClosure
+	Constants []object.Object // the integer, float and string literals, indexed by OpConstant
+	Names     []string        // the global names and the messages of OpFail
+	Name      string          // the file name the sites refer to
+	Source    string          // the text compiled, the errors render its lines
+}
+
+// Function is a compiled function literal, or the top level of a Program
+type Function struct {
+	Params       []string
+	NumLocals    int // the slots of the frame, the params come first
+	NumCells     int // the cells of the frame, for the variables captured by closures
+	MaxStack     int // the stack the instructions use beyond the locals
+	Free         []Capture
+	Instructions Instructions
+	Sites        []Site
+	Inspect      string // the function as the tree-walker inspects it
+	Body         string
+}
+
+// Capture locates a cell a closure captures in the frame creating it
+type Capture struct {
+	Local bool // a cell of the frame, otherwise a cell captured by the frame's closure
+	Index int
+}
+
+// Site is the node of the source an instruction which may fail was compiled from,
+// the errors are located there as the tree-walker locates them
+type Site struct {
+	Pos     token.Position
+	Literal string // the token at Pos
+	Callee  string // the called expression of a call, empty for the other nodes
+	Calls   []int  // the sites of the calls whose callee or arguments the node is part of
+}
@@ -0,0 +1,210 @@
+package code
+
+import (
+	"fmt"
+
+	"Q/token"
+)
+
+// the operators of OpBinary, the vm has a token for each of them
+var binaryOperators = map[token.TokenType]bool{
+	token.LT: true, token.GT: true, token.ADD: true, token.SUB: true, token.MUL: true, token.DIV: true,
+	token.MOD: true, token.EQ: true, token.NEQ: true, token.LEQ: true, token.GEQ: true,
+	token.AND: true, token.OR: true, token.IN: true,
+}
+
+// verify checks what the vm takes for granted of a Program: the operands are within the
+// tables they index, the jumps land on instructions, and every path keeps the stack within
+// MaxStack and the iterations balanced until it returns or fails
+func (this *Program) verify() error {
+	if len(this.Main.Params) > 0 || len(this.Main.Free) > 0 {
+		return fmt.Errorf("main has parameters or captures")
+	}
+	if err := this.verifyFunction(this.Main); nil != err {
+		return fmt.Errorf("main: %v", err)
+	}
+	for i, fn := range this.Functions {
+		if err := this.verifyFunction(fn); nil != err {
+			return fmt.Errorf("function %v: %v", i, err)
+		}
+	}
+	return nil
+}
+
+// paths meet at an instruction with the same stack depth and iterations
+type state struct {
+	depth int
+	iters int
+}
+
+func (this *Program) verifyFunction(fn *Function) error {
+	ins := fn.Instructions
+	if fn.NumLocals < len(fn.Params) {
+		return fmt.Errorf("%v locals for %v parameters", fn.NumLocals, len(fn.Params))
+	}
+	// the operands are 2 bytes wide, each instruction pushes 2 values at most
+	if fn.NumLocals > 1<<16 || fn.NumCells > 1<<16 || fn.MaxStack > 2*len(ins) {
+		return fmt.Errorf("%v locals, %v cells and a stack of %v do not fit the instructions", fn.NumLocals, fn.NumCells, fn.MaxStack)
+	}
+	for i, s := range fn.Sites {
+		for _, c := range s.Calls {
+			if c >= len(fn.Sites) {
+				return fmt.Errorf("site %v refers to site %v of %v", i, c, len(fn.Sites))
+			}
+		}
+	}
+	starts := map[int]bool{}
+	for ip := 0; ip < len(ins); {
+		def, err := Lookup(ins[ip])
+		if nil != err {
+			return fmt.Errorf("%04d: %v", ip, err)
+		}
+		size := 1
+		for _, w := range def.OperandWidths {
+			size += w
+		}
+		if ip+size > len(ins) {
+			return fmt.Errorf("%04d: %v cut short", ip, def.Name)
+		}
+		starts[ip] = true
+		ip += size
+	}
+
+	states := map[int]state{}
+	todo := []int{}
+	visit := func(ip int, s state) error {
+		if !starts[ip] {
+			return fmt.Errorf("goes to %v, which starts no instruction", ip)
+		}
+		if prev, ok := states[ip]; ok {
+			if prev != s {
+				return fmt.Errorf("%04d is reached with %v and %v values, %v and %v iterations", ip, prev.depth, s.depth, prev.iters, s.iters)
+			}
+			return nil
+		}
+		states[ip] = s
+		todo = append(todo, ip)
+		return nil
+	}
+	if err := visit(0, state{}); nil != err {
+		return err
+	}
+	for len(todo) > 0 {
+		ip := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		s := states[ip]
+		op := Opcode(ins[ip])
+		def := definitions[op]
+		operands, read := ReadOperands(def, ins[ip+1:])
+		if err := this.verifyOperands(fn, op, operands, s); nil != err {
+			return fmt.Errorf("%04d %v: %v", ip, formatInstruction(def, operands), err)
+		}
+		pops, pushes := StackEffect(op, operands)
+		if s.depth < pops {
+			return fmt.Errorf("%04d %v: pops %v of %v values", ip, formatInstruction(def, operands), pops, s.depth)
+		}
+		if s.depth-pops+pushes > fn.MaxStack {
+			return fmt.Errorf("%04d %v: stack beyond MaxStack %v", ip, formatInstruction(def, operands), fn.MaxStack)
+		}
+		next := state{depth: s.depth - pops + pushes, iters: s.iters}
+		switch op {
+		case OpIter:
+			next.iters++
+		case OpIterEnd:
+			next.iters--
+		}
+		var err error
+		switch op {
+		case OpJump:
+			err = visit(operands[0], next)
+		case OpJumpIfFalse:
+			if err = visit(ip+1+read, next); nil == err {
+				err = visit(operands[0], next)
+			}
+		case OpJumpIfDecided:
+			if err = visit(ip+1+read, next); nil == err {
+				err = visit(operands[1], next)
+			}
+		case OpIterNext:
+			if err = visit(ip+1+read, next); nil == err {
+				err = visit(operands[1], s) // nothing is pushed once the iteration ends
+			}
+		case OpReturn, OpFail:
+		default:
+			err = visit(ip+1+read, next)
+		}
+		if nil != err {
+			return fmt.Errorf("%04d %v %v", ip, formatInstruction(def, operands), err)
+		}
+	}
+	return nil
+}
+
+// verifyOperands checks the operands of an instruction of fn reached in state s
+func (this *Program) verifyOperands(fn *Function, op Opcode, operands []int, s state) error {
+	var index, size int
+	var table string
+	site := -1
+	switch op {
+	case OpConstant:
+		index, size, table = operands[0], len(this.Constants), "constants"
+	case OpGetLocal, OpSetLocal:
+		index, size, table = operands[0], fn.NumLocals, "locals"
+	case OpMakeCell, OpGetCell, OpSetCell:
+		index, size, table = operands[0], fn.NumCells, "cells"
+	case OpGetFree, OpSetFree:
+		index, size, table = operands[0], len(fn.Free), "captures"
+	case OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpFail:
+		index, size, table = operands[0], len(this.Names), "names"
+	case OpClosure:
+		index, size, table = operands[0], len(this.Functions), "functions"
+	}
+	switch op {
+	case OpGetCell, OpGetFree, OpArray, OpHash, OpBinary, OpStep, OpCall, OpFail:
+		site = operands[1]
+	case OpGetGlobal, OpSetGlobal, OpIterNext:
+		site = operands[2]
+	case OpIndex, OpSetIndex, OpNot, OpNeg, OpIter:
+		site = operands[0]
+	case OpDelete:
+		if operands[1] >= len(fn.Sites) {
+			return fmt.Errorf("site %v of %v", operands[1], len(fn.Sites))
+		}
+		site = operands[0]
+	}
+	if "" != table && index >= size {
+		return fmt.Errorf("index %v of %v %v", index, size, table)
+	}
+	if site >= len(fn.Sites) {
+		return fmt.Errorf("site %v of %v", site, len(fn.Sites))
+	}
+	switch op {
+	case OpBinary:
+		if !binaryOperators[token.TokenType(operands[0])] {
+			return fmt.Errorf("operator %v undefined", operands[0])
+		}
+	case OpJumpIfDecided:
+		if token.AND != token.TokenType(operands[0]) && token.OR != token.TokenType(operands[0]) {
+			return fmt.Errorf("operator %v is not && or ||", operands[0])
+		}
+	case OpIterNext, OpIterEnd:
+		if 0 == s.iters {
+			return fmt.Errorf("no iteration in progress")
+		}
+		if OpIterNext == op && 1 != operands[0] && 2 != operands[0] {
+			return fmt.Errorf("form %v undefined", operands[0])
+		}
+	case OpStep:
+		if operands[0] > StepForIn {
+			return fmt.Errorf("step kind %v undefined", operands[0])
+		}
+	case OpClosure:
+		// the captures are cells of the function creating the closure or captured by it
+		for _, c := range this.Functions[index].Free {
+			if c.Local && c.Index >= fn.NumCells || !c.Local && c.Index >= len(fn.Free) {
+				return fmt.Errorf("capture %v is out of range", c.Index)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,643 @@
+// Package compiler compiles a parsed program to the bytecode of package code, which the
+// vm runs with the results and errors of the tree-walker
+package compiler
+
+import (
+	"Q/ast"
+	"Q/code"
+	"Q/object"
+	"Q/token"
+	"fmt"
+)
+
+// the limit of the 2 byte operands
+const maxOperand = 1<<16 - 1
+
+type compiler struct {
+	prog      *code.Program
+	constants map[interface{}]int
+	names     map[string]int
+	captured  map[capture]bool // the symbols a closure captures, found by the first pass
+	analysis  bool             // the first pass, which keeps every symbol in a slot
+	fn        *funcState
+	scope     *scope
+}
+
+// Compile compiles program, it fails like Eval when program has parse errors
+func Compile(program *ast.Program) (*code.Program, error) {
+	if err := program.Check(); nil != err {
+		return nil, err
+	}
+	// the first pass finds the captured symbols, the second one keeps them in cells
+	analysis := newCompiler(map[capture]bool{})
+	analysis.analysis = true
+	if err := analysis.compileProgram(program); nil != err {
+		return nil, err
+	}
+	this := newCompiler(analysis.captured)
+	if err := this.compileProgram(program); nil != err {
+		return nil, err
+	}
+	return this.prog, nil
+}
+
+func newCompiler(captured map[capture]bool) *compiler {
+	return &compiler{
+		prog:      &code.Program{},
+		constants: map[interface{}]int{},
+		names:     map[string]int{},
+		captured:  captured,
+	}
+}
+
+func (this *compiler) compileProgram(program *ast.Program) error {
+	main := &code.Function{}
+	this.fn = &funcState{proto: main, free: map[*symbol]int{}}
+	this.enterScope(program).global = true
+	if err := this.compileStatements(program.Stmts); nil != err {
+		return err
+	}
+	this.emit(code.OpReturn)
+	this.prog.Main = main
+	return nil
+}
+
+// compileStatements leaves the value of the last statement on the stack, null when there is none
+func (this *compiler) compileStatements(stmts ast.StatementSlice) error {
+	if 0 == len(stmts) {
+		this.emit(code.OpNull)
+		return nil
+	}
+	for i, stmt := range stmts {
+		if i > 0 {
+			this.emit(code.OpPop)
+		}
+		this.emit(code.OpStep, code.StepStatement, this.site(stmt))
+		if err := this.compileStatement(stmt); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (this *compiler) compileBlock(block *ast.BlockStmt) error {
+	if nil == block {
+		this.emit(code.OpNull)
+		return nil
+	}
+	this.enterScope(block)
+	for _, stmt := range block.Stmts {
+		if v, ok := stmt.(*ast.VarStmt); ok {
+			if _, err := this.declare(v.Name.Value); nil != err {
+				return err
+			}
+		}
+	}
+	if err := this.compileStatements(block.Stmts); nil != err {
+		return err
+	}
+	this.leaveScope()
+	return nil
+}
+
+func (this *compiler) compileStatement(stmt ast.Statement) error {
+	switch s := stmt.(type) {
+	case *ast.ExpressionStmt:
+		if nil == s.Expr {
+			this.emit(code.OpNull)
+			return nil
+		}
+		return this.compileExpression(s.Expr)
+	case *ast.VarStmt:
+		if err := this.compileExpression(s.Value); nil != err {
+			return err
+		}
+		sym, err := this.declare(s.Name.Value)
+		if nil != err {
+			return err
+		}
+		if this.scope.global {
+			name, err := this.name(sym.name)
+			if nil != err {
+				return err
+			}
+			this.emit(code.OpDefineGlobal, name)
+		} else if err := this.store(sym); nil != err {
+			return err
+		}
+		sym.declared = true
+		return nil
+	case *ast.AssignStmt:
+		if err := this.compileExpression(s.Value); nil != err {
+			return err
+		}
+		if nil != s.Index {
+			if err := this.compileExpression(s.Index.Left); nil != err {
+				return err
+			}
+			if err := this.compileExpression(s.Index.Index); nil != err {
+				return err
+			}
+			this.emit(code.OpSetIndex, this.site(s.Index))
+			return nil
+		}
+		if sym := this.resolve(s.Name.Value); nil != sym {
+			return this.store(sym)
+		}
+		name, err := this.name(s.Name.Value)
+		if nil != err {
+			return err
+		}
+		this.emit(code.OpSetGlobal, name, this.hint(s.Name.Value), this.site(s))
+		return nil
+	case *ast.ReturnStmt:
+		depth := this.fn.depth
+		if err := this.compileExpression(s.ReturnValue); nil != err {
+			return err
+		}
+		this.emit(code.OpReturn)
+		this.fn.depth = depth + 1
+		return nil
+	case *ast.BreakStmt:
+		return this.compileJump(s, s.Label, true)
+	case *ast.ContinueStmt:
+		return this.compileJump(s, s.Label, false)
+	case *ast.DeleteStmt:
+		if err := this.compileExpression(s.Index.Left); nil != err {
+			return err
+		}
+		if err := this.compileExpression(s.Index.Index); nil != err {
+			return err
+		}
+		this.emit(code.OpDelete, this.site(s), this.site(s.Index))
+		return nil
+	case *ast.BlockStmt:
+		return this.compileBlock(s)
+	default:
+		return fmt.Errorf("Compiler.compileStatement -> unsupported statement %T", stmt)
+	}
+}
+
+func (this *compiler) compileExpression(expr ast.Expression) error {
+	switch e := expr.(type) {
+	case *ast.Integer:
+		return this.constant(&object.Integer{Value: e.Value}, e.Value)
+	case *ast.Float:
+		return this.constant(&object.Float{Value: e.Value}, e.Value)
+	case *ast.String:
+		return this.constant(&object.String{Value: e.Value}, e.Value)
+	case *ast.Boolean:
+		if e.Value {
+			this.emit(code.OpTrue)
+		} else {
+			this.emit(code.OpFalse)
+		}
+	case *ast.Null:
+		this.emit(code.OpNull)
+	case *ast.Identifier:
+		if sym := this.resolve(e.Value); nil != sym {
+			return this.load(sym, this.site(e))
+		}
+		name, err := this.name(e.Value)
+		if nil != err {
+			return err
+		}
+		this.emit(code.OpGetGlobal, name, this.hint(e.Value), this.site(e))
+	case *ast.PrefixExpression:
+		if err := this.compileExpression(e.Right); nil != err {
+			return err
+		}
+		switch e.Op.Type {
+		case token.NOT:
+			this.emit(code.OpNot, this.site(e))
+		case token.SUB:
+			this.emit(code.OpNeg, this.site(e))
+		default:
+			return fmt.Errorf("Compiler.compileExpression -> unsupport op %v(%v)", e.Op.Literal, e.Op.Type)
+		}
+	case *ast.InfixExpression:
+		if err := this.compileExpression(e.Left); nil != err {
+			return err
+		}
+		decided := -1
+		if e.ShortCircuits() {
+			decided = this.emit(code.OpJumpIfDecided, int(e.Op.Type), 0)
+		}
+		if err := this.compileExpression(e.Right); nil != err {
+			return err
+		}
+		this.emit(code.OpBinary, int(e.Op.Type), this.site(e))
+		if decided >= 0 {
+			this.patch(decided)
+		}
+	case *ast.Index:
+		if err := this.compileExpression(e.Left); nil != err {
+			return err
+		}
+		if err := this.compileExpression(e.Index); nil != err {
+			return err
+		}
+		this.emit(code.OpIndex, this.site(e))
+	case *ast.Array:
+		if len(e.Elements) > maxOperand {
+			return fmt.Errorf("Compiler.compileExpression -> too many elements")
+		}
+		for _, element := range e.Elements {
+			if err := this.compileExpression(element); nil != err {
+				return err
+			}
+		}
+		this.emit(code.OpArray, len(e.Elements), this.site(e))
+	case *ast.Hash:
+		if len(e.Pairs) > maxOperand {
+			return fmt.Errorf("Compiler.compileExpression -> too many pairs")
+		}
+		for _, pair := range e.Pairs {
+			if err := this.compileExpression(pair.Key); nil != err {
+				return err
+			}
+			if err := this.compileExpression(pair.Value); nil != err {
+				return err
+			}
+		}
+		this.emit(code.OpHash, len(e.Pairs), this.site(e))
+	case *ast.Call:
+		return this.compileCall(e)
+	case *ast.Function:
+		return this.compileFunction(e)
+	case *ast.IfExpression:
+		return this.compileIf(e)
+	case *ast.ForExpression:
+		return this.compileFor(e)
+	case *ast.ForInExpression:
+		return this.compileForIn(e)
+	default:
+		return fmt.Errorf("Compiler.compileExpression -> unsupported expression %T", expr)
+	}
+	return nil
+}
+
+func (this *compiler) compileCall(call *ast.Call) error {
+	if len(call.Args) > maxOperand {
+		return fmt.Errorf("Compiler.compileCall -> too many arguments")
+	}
+	site := this.site(call)
+	// the errors of the callee and the arguments are located in the call too
+	this.fn.calls = append(this.fn.calls, site)
+	if err := this.compileExpression(call.Func); nil != err {
+		return err
+	}
+	for _, arg := range call.Args {
+		if err := this.compileExpression(arg); nil != err {
+			return err
+		}
+	}
+	this.fn.calls = this.fn.calls[:len(this.fn.calls)-1]
+	this.emit(code.OpCall, len(call.Args), site)
+	return nil
+}
+
+func (this *compiler) compileFunction(fn *ast.Function) error {
+	idx := len(this.prog.Functions)
+	if idx > maxOperand {
+		return fmt.Errorf("Compiler.compileFunction -> too many functions")
+	}
+	proto := &code.Function{Inspect: fn.Inspect(), Body: fn.Body.String()}
+	this.prog.Functions = append(this.prog.Functions, proto)
+
+	outer, outerScope := this.fn, this.scope
+	this.fn = &funcState{outer: outer, proto: proto, free: map[*symbol]int{}}
+	params := this.enterScope(fn)
+	params.params = true
+	for _, arg := range fn.Args {
+		proto.Params = append(proto.Params, arg.Value)
+		// every argument has a slot, a repeated name refers to the last one
+		slot, err := this.newSlot()
+		if nil != err {
+			return err
+		}
+		params.symbols[arg.Value] = &symbol{name: arg.Value, slot: slot, declared: true, scope: params}
+	}
+	for _, arg := range fn.Args {
+		sym := params.symbols[arg.Value]
+		if sym.captured || !this.captured[capture{fn, arg.Value}] {
+			continue
+		}
+		cell, err := this.newCell()
+		if nil != err {
+			return err
+		}
+		sym.captured, sym.cell = true, cell
+		this.emit(code.OpMakeCell, sym.cell)
+		this.emit(code.OpGetLocal, sym.slot)
+		this.emit(code.OpSetCell, sym.cell)
+		this.emit(code.OpPop)
+	}
+	if err := this.compileBlock(fn.Body); nil != err {
+		return err
+	}
+	this.emit(code.OpReturn)
+	this.leaveScope()
+	this.fn, this.scope = outer, outerScope
+
+	this.emit(code.OpClosure, idx)
+	return nil
+}
+
+func (this *compiler) compileIf(expr *ast.IfExpression) error {
+	depth := this.fn.depth
+	ends := []int{}
+	for _, clause := range expr.Clauses {
+		if err := this.compileExpression(clause.If); nil != err {
+			return err
+		}
+		next := this.emit(code.OpJumpIfFalse, 0)
+		if err := this.compileBlock(clause.Then); nil != err {
+			return err
+		}
+		ends = append(ends, this.emit(code.OpJump, 0))
+		this.fn.depth = depth
+		this.patch(next)
+	}
+	if err := this.compileBlock(expr.Else); nil != err {
+		return err
+	}
+	for _, end := range ends {
+		this.patch(end)
+	}
+	return nil
+}
+
+func (this *compiler) compileFor(expr *ast.ForExpression) error {
+	// the variables declared in Init live as long as the loop
+	this.enterScope(expr)
+	if v, ok := expr.Init.(*ast.VarStmt); ok {
+		if _, err := this.declare(v.Name.Value); nil != err {
+			return err
+		}
+	}
+	if nil != expr.Init {
+		if err := this.compileStatement(expr.Init); nil != err {
+			return err
+		}
+		this.emit(code.OpPop)
+	}
+	l := this.enterLoop(expr.Label, false)
+	top := len(this.fn.proto.Instructions)
+	this.emit(code.OpStep, code.StepFor, this.site(expr))
+	end := -1
+	if nil != expr.Cond {
+		if err := this.compileExpression(expr.Cond); nil != err {
+			return err
+		}
+		end = this.emit(code.OpJumpIfFalse, 0)
+	}
+	if err := this.compileBlock(expr.Loop); nil != err {
+		return err
+	}
+	this.emit(code.OpPop)
+	for _, pos := range l.continues {
+		this.patch(pos)
+	}
+	if nil != expr.Post {
+		if err := this.compileStatement(expr.Post); nil != err {
+			return err
+		}
+		this.emit(code.OpPop)
+	}
+	this.patch(this.emit(code.OpJump, 0), top)
+	if end >= 0 {
+		this.patch(end)
+	}
+	this.leaveLoop()
+	this.emit(code.OpNull)
+	this.leaveScope()
+	return nil
+}
+
+func (this *compiler) compileForIn(expr *ast.ForInExpression) error {
+	if err := this.compileExpression(expr.Iterable); nil != err {
+		return err
+	}
+	site := this.site(expr)
+	this.emit(code.OpIter, site)
+	l := this.enterLoop(expr.Label, true)
+	top := len(this.fn.proto.Instructions)
+	this.emit(code.OpStep, code.StepForIn, site)
+	form := 1
+	if nil != expr.Key {
+		form = 2
+	}
+	next := this.emit(code.OpIterNext, form, 0, site)
+	// every iteration has its own bindings, so closures capture the current element
+	this.enterScope(expr)
+	value, err := this.declare(expr.Value.Value)
+	if nil == err {
+		err = this.store(value)
+	}
+	if nil != err {
+		return err
+	}
+	this.emit(code.OpPop)
+	value.declared = true
+	if nil != expr.Key {
+		key, err := this.declare(expr.Key.Value)
+		if nil == err {
+			err = this.store(key)
+		}
+		if nil != err {
+			return err
+		}
+		this.emit(code.OpPop)
+		key.declared = true
+	}
+	if err := this.compileBlock(expr.Loop); nil != err {
+		return err
+	}
+	this.emit(code.OpPop)
+	this.leaveScope()
+	this.patch(this.emit(code.OpJump, 0), top)
+	for _, pos := range l.continues {
+		this.patch(pos, top)
+	}
+	this.patch(next)
+	this.leaveLoop()
+	this.emit(code.OpIterEnd)
+	this.emit(code.OpNull)
+	return nil
+}
+
+func (this *compiler) enterLoop(label *ast.Identifier, forIn bool) *loop {
+	l := &loop{depth: this.fn.depth, forIn: forIn}
+	if nil != label {
+		l.label = label.Value
+	}
+	this.fn.loops = append(this.fn.loops, l)
+	return l
+}
+
+// leaveLoop makes the breaks jump to the next instruction
+func (this *compiler) leaveLoop() {
+	l := this.fn.loops[len(this.fn.loops)-1]
+	this.fn.loops = this.fn.loops[:len(this.fn.loops)-1]
+	for _, pos := range l.breaks {
+		this.patch(pos)
+	}
+}
+
+// compileJump compiles break and continue, the loops they leave drop what they pushed
+func (this *compiler) compileJump(stmt ast.Statement, label *ast.Identifier, isBreak bool) error {
+	depth := this.fn.depth
+	defer func() {
+		this.fn.depth = depth + 1
+	}()
+	target := -1
+	for i := len(this.fn.loops) - 1; i >= 0; i-- {
+		if nil == label || label.Value == this.fn.loops[i].label {
+			target = i
+			break
+		}
+	}
+	if target < 0 {
+		msg, err := this.name(fmt.Sprintf("evalStatements -> '%v' outside loop", stmt.TokenLiteral()))
+		if nil != err {
+			return err
+		}
+		this.emit(code.OpFail, msg, this.site(stmt))
+		return nil
+	}
+	l := this.fn.loops[target]
+	for this.fn.depth > l.depth {
+		this.emit(code.OpPop)
+	}
+	for _, inner := range this.fn.loops[target+1:] {
+		if inner.forIn {
+			this.emit(code.OpIterEnd)
+		}
+	}
+	pos := this.emit(code.OpJump, 0)
+	if isBreak {
+		l.breaks = append(l.breaks, pos)
+	} else {
+		l.continues = append(l.continues, pos)
+	}
+	return nil
+}
+
+func (this *compiler) load(sym *symbol, site int) error {
+	switch {
+	case sym.scope.fn != this.fn:
+		this.captured[capture{sym.scope.key, sym.name}] = true
+		idx, err := this.free(this.fn, sym)
+		if nil != err {
+			return err
+		}
+		this.emit(code.OpGetFree, idx, site)
+	case sym.captured:
+		this.emit(code.OpGetCell, sym.cell, site)
+	default:
+		this.emit(code.OpGetLocal, sym.slot)
+	}
+	return nil
+}
+
+// store sets sym to the top of the stack, which stays
+func (this *compiler) store(sym *symbol) error {
+	switch {
+	case sym.scope.fn != this.fn:
+		this.captured[capture{sym.scope.key, sym.name}] = true
+		idx, err := this.free(this.fn, sym)
+		if nil != err {
+			return err
+		}
+		this.emit(code.OpSetFree, idx)
+	case sym.captured:
+		this.emit(code.OpSetCell, sym.cell)
+	default:
+		this.emit(code.OpSetLocal, sym.slot)
+	}
+	return nil
+}
+
+// hint is the operand adding object.EndedHint to the errors about the global name
+func (this *compiler) hint(name string) int {
+	if this.ended(name) {
+		return 1
+	}
+	return 0
+}
+
+// constant pushes obj, the literals of the same type and value share it
+func (this *compiler) constant(obj object.Object, value interface{}) error {
+	key := struct {
+		t object.ObjectType
+		v interface{}
+	}{obj.Type(), value}
+	idx, ok := this.constants[key]
+	if !ok {
+		idx = len(this.prog.Constants)
+		if idx > maxOperand {
+			return fmt.Errorf("Compiler.constant -> too many constants")
+		}
+		this.prog.Constants = append(this.prog.Constants, obj)
+		this.constants[key] = idx
+	}
+	this.emit(code.OpConstant, idx)
+	return nil
+}
+
+func (this *compiler) name(name string) (int, error) {
+	idx, ok := this.names[name]
+	if !ok {
+		idx = len(this.prog.Names)
+		if idx > maxOperand {
+			return 0, fmt.Errorf("Compiler.name -> too many names")
+		}
+		this.prog.Names = append(this.prog.Names, name)
+		this.names[name] = idx
+	}
+	return idx, nil
+}
+
+// site records node as the location of the errors of an instruction
+func (this *compiler) site(node ast.Node) int {
+	s := code.Site{Pos: node.Pos(), Literal: node.TokenLiteral()}
+	if call, ok := node.(*ast.Call); ok {
+		s.Callee = call.Func.String()
+	}
+	for i := len(this.fn.calls) - 1; i >= 0; i-- {
+		s.Calls = append(s.Calls, this.fn.calls[i])
+	}
+	this.fn.proto.Sites = append(this.fn.proto.Sites, s)
+	return len(this.fn.proto.Sites) - 1
+}
+
+// emit appends an instruction and returns its position
+func (this *compiler) emit(op code.Opcode, operands ...int) int {
+	pos := len(this.fn.proto.Instructions)
+	this.fn.proto.Instructions = append(this.fn.proto.Instructions, code.Make(op, operands...)...)
+	pops, pushes := code.StackEffect(op, operands)
+	this.fn.depth += pushes - pops
+	if this.fn.depth > this.fn.proto.MaxStack {
+		this.fn.proto.MaxStack = this.fn.depth
+	}
+	return pos
+}
+
+// patch sets the target of the jump at pos, to the next instruction when none is given
+func (this *compiler) patch(pos int, target ...int) {
+	ins := this.fn.proto.Instructions
+	to := len(ins)
+	if len(target) > 0 {
+		to = target[0]
+	}
+	def, _ := code.Lookup(ins[pos])
+	operands, _ := code.ReadOperands(def, ins[pos+1:])
+	switch code.Opcode(ins[pos]) {
+	case code.OpJumpIfDecided, code.OpIterNext:
+		operands[1] = to
+	default:
+		operands[0] = to
+	}
+	copy(ins[pos:], code.Make(code.Opcode(ins[pos]), operands...))
+}
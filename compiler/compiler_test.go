@@ -0,0 +1,159 @@
+package compiler
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"Q/code"
+	"Q/lexer"
+	"Q/parser"
+)
+
+func TestMaxStack(t *testing.T) {
+	tests := []struct {
+		input     string
+		main      int
+		functions []int
+	}{
+		{"1 + 2 * 3", 3, nil},
+		{"var f = func(a, b) { a }; f(f(1, 2), f(3, [4, 5, f(6, 7)]))", 9, []int{1}},
+		{"for (var i = 0; i < 3; i = i + 1) { if (i == 1) { continue; } if (i == 2) { break; } i; }", 2, nil},
+		{"outer: for (x in [1, 2]) { [x, for (y in [3]) { break outer; }] }", 2, nil},
+		{"outer: for (x in [1, 2]) { [x, [x, x, for (y in [3]) { continue outer; }]] }", 4, nil},
+		{`var g = func(h) { for (k, v in h) { [k, v, [k]]; continue; } }; g({"a": 1})`, 3, []int{3}},
+		{"[1, for (y in [3]) { continue; }, 2]", 3, nil},
+		{"var f = func() { for (x in [1]) { return [x, x]; } }; f() && f() || f()", 2, []int{2}},
+	}
+	for _, tt := range tests {
+		p, err := parser.New(lexer.New(tt.input))
+		if nil != err {
+			t.Fatal(err)
+		}
+		prog, err := Compile(p.ParseProgram())
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		if prog.Main.MaxStack != tt.main {
+			t.Errorf("[%v] main MaxStack %v, want %v", tt.input, prog.Main.MaxStack, tt.main)
+		}
+		if len(prog.Functions) != len(tt.functions) {
+			t.Fatalf("[%v] %v functions, want %v", tt.input, len(prog.Functions), len(tt.functions))
+		}
+		for i, fn := range prog.Functions {
+			if fn.MaxStack != tt.functions[i] {
+				t.Errorf("[%v] function %v MaxStack %v, want %v", tt.input, i, fn.MaxStack, tt.functions[i])
+			}
+		}
+		for _, fn := range append([]*code.Function{prog.Main}, prog.Functions...) {
+			checkStack(t, tt.input, fn)
+		}
+	}
+}
+
+// checkStack follows every path of the instructions of fn and fails when the stack depth
+// differs where paths join, goes below zero or beyond MaxStack, or never reaches MaxStack
+func checkStack(t *testing.T, input string, fn *code.Function) {
+	ins := fn.Instructions
+	depths := map[int]int{}
+	todo := []int{0}
+	visit := func(ip int, depth int) {
+		if d, ok := depths[ip]; ok {
+			if d != depth {
+				t.Errorf("[%v] stack depth %v and %v at %04d\n%v", input, d, depth, ip, ins)
+			}
+			return
+		}
+		depths[ip] = depth
+		todo = append(todo, ip)
+	}
+	depths[0] = 0
+	max := 0
+	for len(todo) > 0 {
+		ip := todo[len(todo)-1]
+		todo = todo[:len(todo)-1]
+		def, err := code.Lookup(ins[ip])
+		if nil != err {
+			t.Fatal(err)
+		}
+		operands, read := code.ReadOperands(def, ins[ip+1:])
+		next := ip + 1 + read
+		pops, pushes := code.StackEffect(code.Opcode(ins[ip]), operands)
+		depth := depths[ip] - pops + pushes
+		if depth < 0 || depth > fn.MaxStack {
+			t.Errorf("[%v] stack depth %v after %04d, MaxStack is %v\n%v", input, depth, ip, fn.MaxStack, ins)
+		}
+		if depth > max {
+			max = depth
+		}
+		switch code.Opcode(ins[ip]) {
+		case code.OpJump:
+			visit(operands[0], depth)
+		case code.OpJumpIfFalse:
+			visit(next, depth)
+			visit(operands[0], depth)
+		case code.OpJumpIfDecided:
+			visit(next, depth)
+			visit(operands[1], depth)
+		case code.OpIterNext:
+			visit(next, depth)
+			visit(operands[1], depths[ip]) // nothing is pushed once the iteration ends
+		case code.OpReturn, code.OpFail:
+		default:
+			visit(next, depth)
+		}
+	}
+	if max != fn.MaxStack {
+		t.Errorf("[%v] the stack depth reaches %v, MaxStack is %v", input, max, fn.MaxStack)
+	}
+}
+
+func TestOperandLimits(t *testing.T) {
+	// name is the i-th variable of prefix, identifiers have no digits
+	name := func(prefix string, i int) string {
+		out := []byte(prefix)
+		for ; i > 0; i /= 26 {
+			out = append(out, byte('a'+i%26))
+		}
+		return string(out)
+	}
+	// vars declares n variables of prefix, uses refers to all of them
+	vars := func(prefix string, n int) string {
+		var out strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&out, "var %v = 0;\n", name(prefix, i))
+		}
+		return out.String()
+	}
+	uses := func(prefix string, n int) string {
+		var out strings.Builder
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&out, "%v;\n", name(prefix, i))
+		}
+		return out.String()
+	}
+	tests := []struct {
+		input string
+		want  string // empty when it compiles
+	}{
+		{"func() { " + vars("_v", 1<<16) + " }", ""},
+		{"func() { " + vars("_v", 1<<16+1) + " }", "Compiler.newSlot -> too many local variables"},
+		{"func() { " + vars("_x", 40000) + vars("_y", 40000) + " func() { " + uses("_x", 40000) + " } }", ""},
+		{"func() { " + vars("_v", 1<<16+1) + " func() { " + uses("_v", 1<<16+1) + " } }", "Compiler.newCell -> too many captured variables"},
+		{"func() { " + vars("_x", 1<<15+1) + " func() { " + vars("_y", 1<<15) + " func() { " + uses("_x", 1<<15+1) + uses("_y", 1<<15) + " } } }",
+			"Compiler.free -> too many captured variables"},
+	}
+	for i, tt := range tests {
+		p, err := parser.New(lexer.New(tt.input))
+		if nil != err {
+			t.Fatal(err)
+		}
+		_, err = Compile(p.ParseProgram())
+		if "" == tt.want && nil != err {
+			t.Errorf("%v: %v", i, err)
+		}
+		if "" != tt.want && (nil == err || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("%v: error %v, want %q", i, err, tt.want)
+		}
+	}
+}
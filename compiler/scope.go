@@ -0,0 +1,200 @@
+package compiler
+
+import (
+	"Q/code"
+	"fmt"
+)
+
+// symbol is a variable of a function frame, kept in a local slot or, when a closure
+// captures it, in a cell
+type symbol struct {
+	name     string
+	slot     int
+	cell     int
+	captured bool
+	declared bool // its declaration was compiled, the function declaring it sees it from then on
+	scope    *scope
+}
+
+// scope is the block, loop, iteration or parameter list declaring symbols, it mirrors
+// the Env the tree-walker encloses for it
+type scope struct {
+	key      interface{} // the node owning the scope
+	outer    *scope
+	fn       *funcState
+	global   bool // the program level, its variables are the globals of the Env
+	params   bool // the parameters of a function, nothing ends in its caller
+	symbols  map[string]*symbol
+	ended    map[string]bool // names declared by inner scopes which have ended
+	locals   int             // the slots of fn in use when the scope was entered
+	cells    int
+	declares []string // the names in declaration order, for the ended names
+}
+
+// funcState is the function being compiled
+type funcState struct {
+	outer  *funcState
+	proto  *code.Function
+	locals int
+	cells  int
+	depth  int // the stack depth after the last instruction
+	free   map[*symbol]int
+	loops  []*loop
+	calls  []int // the sites of the calls whose callee or arguments are being compiled
+}
+
+// loop is a loop being compiled, break and continue jump out of it
+type loop struct {
+	label     string
+	depth     int  // the stack depth at the top of the loop
+	forIn     bool // an iteration is in progress inside the loop
+	breaks    []int
+	continues []int
+}
+
+// capture identifies a symbol across the passes of the compiler
+type capture struct {
+	key  interface{}
+	name string
+}
+
+func (this *compiler) enterScope(key interface{}) *scope {
+	s := &scope{
+		key:     key,
+		outer:   this.scope,
+		fn:      this.fn,
+		symbols: map[string]*symbol{},
+		locals:  this.fn.locals,
+		cells:   this.fn.cells,
+	}
+	this.scope = s
+	return s
+}
+
+// leaveScope frees the slots of the scope and marks its names ended in the outer one,
+// as Env.Leave does
+func (this *compiler) leaveScope() {
+	s := this.scope
+	this.scope = s.outer
+	s.fn.locals, s.fn.cells = s.locals, s.cells
+	if s.params || nil == s.outer {
+		return
+	}
+	for _, name := range s.declares {
+		if s.symbols[name].declared {
+			s.outer.markEnded(name)
+		}
+	}
+	for name := range s.ended {
+		s.outer.markEnded(name)
+	}
+}
+
+func (this *scope) markEnded(name string) {
+	if sym, ok := this.symbols[name]; ok && sym.declared {
+		return
+	}
+	if nil == this.ended {
+		this.ended = map[string]bool{}
+	}
+	this.ended[name] = true
+}
+
+// declare allocates the symbol of name in the current scope, a captured one gets a new cell
+// each time the scope is entered
+func (this *compiler) declare(name string) (*symbol, error) {
+	s := this.scope
+	if sym, ok := s.symbols[name]; ok {
+		return sym, nil
+	}
+	sym := &symbol{name: name, scope: s}
+	s.symbols[name] = sym
+	s.declares = append(s.declares, name)
+	if s.global {
+		return sym, nil
+	}
+	var err error
+	sym.captured = this.captured[capture{s.key, name}]
+	if sym.captured {
+		if sym.cell, err = this.newCell(); nil != err {
+			return nil, err
+		}
+		this.emit(code.OpMakeCell, sym.cell)
+	} else if sym.slot, err = this.newSlot(); nil != err {
+		return nil, err
+	}
+	return sym, nil
+}
+
+// newSlot allocates a local slot, the first pass does not check the operand limit since the
+// captured symbols take cells instead in the second one
+func (this *compiler) newSlot() (int, error) {
+	slot := this.fn.locals
+	if !this.analysis && slot > maxOperand {
+		return 0, fmt.Errorf("Compiler.newSlot -> too many local variables")
+	}
+	this.fn.locals++
+	if this.fn.locals > this.fn.proto.NumLocals {
+		this.fn.proto.NumLocals = this.fn.locals
+	}
+	return slot, nil
+}
+
+func (this *compiler) newCell() (int, error) {
+	cell := this.fn.cells
+	if !this.analysis && cell > maxOperand {
+		return 0, fmt.Errorf("Compiler.newCell -> too many captured variables")
+	}
+	this.fn.cells++
+	if this.fn.cells > this.fn.proto.NumCells {
+		this.fn.proto.NumCells = this.fn.cells
+	}
+	return cell, nil
+}
+
+// resolve returns the symbol name refers to, nil for a global. The function declaring a
+// symbol sees it once its declaration is compiled, the functions inside see it at once
+// since they may be called later
+func (this *compiler) resolve(name string) *symbol {
+	for s := this.scope; nil != s; s = s.outer {
+		if s.global {
+			return nil
+		}
+		if sym, ok := s.symbols[name]; ok && (sym.declared || s.fn != this.fn) {
+			return sym
+		}
+	}
+	return nil
+}
+
+// ended reports whether name was only declared by scopes which have ended
+func (this *compiler) ended(name string) bool {
+	for s := this.scope; nil != s; s = s.outer {
+		if s.ended[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// free returns the index of sym among the captured cells of fn, capturing it through the
+// functions in between
+func (this *compiler) free(fn *funcState, sym *symbol) (int, error) {
+	if idx, ok := fn.free[sym]; ok {
+		return idx, nil
+	}
+	if !this.analysis && len(fn.proto.Free) > maxOperand {
+		return 0, fmt.Errorf("Compiler.free -> too many captured variables")
+	}
+	c := code.Capture{Local: true, Index: sym.cell}
+	if fn.outer != sym.scope.fn {
+		idx, err := this.free(fn.outer, sym)
+		if nil != err {
+			return 0, err
+		}
+		c = code.Capture{Index: idx}
+	}
+	fn.proto.Free = append(fn.proto.Free, c)
+	fn.free[sym] = len(fn.proto.Free) - 1
+	return fn.free[sym], nil
+}
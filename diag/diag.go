@@ -0,0 +1,103 @@
+package diag
+
+import (
+	"Q/token"
+	"fmt"
+)
+
+type Severity uint8
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+	SeverityNote
+)
+
+var severityStrings = map[Severity]string{
+	SeverityError:   "error",
+	SeverityWarning: "warning",
+	SeverityNote:    "note",
+}
+
+func (this Severity) String() string {
+	s, ok := severityStrings[this]
+	if ok {
+		return s
+	}
+	return "unknown"
+}
+
+// Codes identify the kind of a Diagnostic, tools filter on them instead of on the message
+const (
+	CodeUnexpectedToken = "P0001"
+	CodeNoDecoder       = "P0002"
+	CodeIllegalToken    = "P0003"
+	CodeBadLiteral      = "P0004"
+	CodeBadLoop         = "P0005"
+	CodeBadLabel        = "P0006"
+	CodeBadDelete       = "P0007"
+	CodeIncomplete      = "P0008" // the input ended inside a statement, more input may complete it
+	CodeRuntime         = "R0001"
+)
+
+// Fix is a suggested edit: Replacement replaces the source from Start to End,
+// Start == End is an insertion
+type Fix struct {
+	Message     string
+	Start       token.Position
+	End         token.Position
+	Replacement string
+}
+
+// Diagnostic is a problem found in the source, End is the position after the offending span
+type Diagnostic struct {
+	Severity Severity
+	Code     string
+	Message  string
+	Start    token.Position
+	End      token.Position
+	Notes    []string
+	Fix      *Fix
+}
+
+// Errorf returns an error Diagnostic for the span from start to end
+func Errorf(code string, start token.Position, end token.Position, format string, a ...interface{}) *Diagnostic {
+	return &Diagnostic{
+		Severity: SeverityError,
+		Code:     code,
+		Message:  fmt.Sprintf(format, a...),
+		Start:    start,
+		End:      end,
+	}
+}
+
+// String is the one line view: file:line:col: message
+func (this *Diagnostic) String() string {
+	if !this.Start.IsValid() {
+		return this.Message
+	}
+	return fmt.Sprintf("%v: %v", this.Start, this.Message)
+}
+
+func (this *Diagnostic) Error() string {
+	return this.String()
+}
+
+func (this *Diagnostic) AddNote(format string, a ...interface{}) *Diagnostic {
+	this.Notes = append(this.Notes, fmt.Sprintf(format, a...))
+	return this
+}
+
+func (this *Diagnostic) SetFix(fix *Fix) *Diagnostic {
+	this.Fix = fix
+	return this
+}
+
+// Strings returns the one line views of diags
+func Strings(diags []*Diagnostic) []string {
+	r := make([]string, 0, len(diags))
+	for _, d := range diags {
+		r = append(r, d.String())
+	}
+	return r
+}
@@ -0,0 +1,72 @@
+package diag
+
+import (
+	"Q/token"
+	"testing"
+)
+
+func pos(line int, col int) token.Position {
+	return token.Position{File: "t.q", Line: line, Col: col}
+}
+
+func TestString(t *testing.T) {
+	d := Errorf(CodeUnexpectedToken, pos(2, 5), pos(2, 6), "expected %v", "IDENT")
+	if d.String() != "t.q:2:5: expected IDENT" {
+		t.Errorf("String() = %q", d.String())
+	}
+	if d.Error() != d.String() {
+		t.Errorf("Error() = %q, want %q", d.Error(), d.String())
+	}
+	noPos := Errorf(CodeRuntime, token.Position{}, token.Position{}, "boom")
+	if noPos.String() != "boom" {
+		t.Errorf("String() = %q, want boom", noPos.String())
+	}
+	strs := Strings([]*Diagnostic{d, noPos})
+	if len(strs) != 2 || strs[0] != d.String() || strs[1] != "boom" {
+		t.Errorf("Strings() = %v", strs)
+	}
+}
+
+func TestRender(t *testing.T) {
+	source := "var x = 1;\n\tvar = 2;\nfoo(bar baz);\n"
+	tests := []struct {
+		d    *Diagnostic
+		want string
+	}{
+		{
+			Errorf(CodeUnexpectedToken, pos(2, 6), pos(2, 7), "expected next token to be IDENT, got ASSIGN instead"),
+			"t.q:2:6: error[P0001]: expected next token to be IDENT, got ASSIGN instead\n" +
+				" 2 | \tvar = 2;\n" +
+				"   | \t    ^\n",
+		},
+		{
+			Errorf(CodeRuntime, pos(3, 5), pos(3, 8), "`bar` not found").AddNote("called from f at t.q:9:1"),
+			"t.q:3:5: error[R0001]: `bar` not found\n" +
+				" 3 | foo(bar baz);\n" +
+				"   |     ^^^\n" +
+				"   = note: called from f at t.q:9:1\n",
+		},
+		{
+			Errorf(CodeUnexpectedToken, pos(3, 9), pos(3, 12), "expected ,").SetFix(&Fix{Message: "insert `,`", Start: pos(3, 8), End: pos(3, 8), Replacement: ","}),
+			"t.q:3:9: error[P0001]: expected ,\n" +
+				" 3 | foo(bar baz);\n" +
+				"   |         ^^^\n" +
+				"   = help: insert `,`\n",
+		},
+		{
+			Errorf(CodeUnexpectedToken, pos(1, 11), pos(1, 11), "expected EOF"),
+			"t.q:1:11: error[P0001]: expected EOF\n" +
+				" 1 | var x = 1;\n" +
+				"   |           ^\n",
+		},
+		{
+			&Diagnostic{Severity: SeverityWarning, Code: CodeRuntime, Message: "far away", Start: pos(12, 1)},
+			"t.q:12:1: warning[R0001]: far away\n",
+		},
+	}
+	for i, tt := range tests {
+		if got := tt.d.Render(source); got != tt.want {
+			t.Errorf("case %v: Render() =\n%v\nwant\n%v", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package diag
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Render prints the Diagnostic with the offending line of source and a caret underline:
+//
+//	t.q:2:5: error[P0001]: expected next token to be IDENT, got ASSIGN instead
+//	  2 | var = 2;
+//	    |     ^
+//	    = help: ...
+func (this *Diagnostic) Render(source string) string {
+	var out bytes.Buffer
+	if this.Start.IsValid() {
+		out.WriteString(fmt.Sprintf("%v: ", this.Start))
+	}
+	out.WriteString(fmt.Sprintf("%v[%v]: %v\n", this.Severity, this.Code, this.Message))
+
+	gutter := strings.Repeat(" ", len(strconv.Itoa(this.Start.Line)))
+	if line, ok := sourceLine(source, this.Start.Line); ok {
+		out.WriteString(fmt.Sprintf(" %v | %v\n", this.Start.Line, line))
+		out.WriteString(fmt.Sprintf(" %v | %v\n", gutter, this.underline(line)))
+	}
+	for _, note := range this.Notes {
+		out.WriteString(fmt.Sprintf(" %v = note: %v\n", gutter, note))
+	}
+	if nil != this.Fix {
+		out.WriteString(fmt.Sprintf(" %v = help: %v\n", gutter, this.Fix.Message))
+	}
+	return out.String()
+}
+
+// RenderAll renders diags one after another
+func RenderAll(diags []*Diagnostic, source string) string {
+	var out bytes.Buffer
+	for _, d := range diags {
+		out.WriteString(d.Render(source))
+	}
+	return out.String()
+}
+
+func sourceLine(source string, line int) (string, bool) {
+	if line < 1 || "" == source {
+		return "", false
+	}
+	lines := strings.Split(source, "\n")
+	if line > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[line-1], "\r"), true
+}
+
+// underline puts carets under the span, tabs are kept so the carets line up with the source
+func (this *Diagnostic) underline(line string) string {
+	chars := []rune(line)
+	var out bytes.Buffer
+	for i := 0; i < this.Start.Col-1 && i < len(chars); i++ {
+		if '\t' == chars[i] {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+	}
+	width := 1
+	if this.End.Line == this.Start.Line && this.End.Col > this.Start.Col {
+		width = this.End.Col - this.Start.Col
+	}
+	if rest := len(chars) - (this.Start.Col - 1); width > rest && rest > 0 {
+		width = rest
+	}
+	out.WriteString(strings.Repeat("^", width))
+	return out.String()
+}
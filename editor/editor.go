@@ -0,0 +1,405 @@
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ErrInterrupt is returned by ReadLine when Ctrl-C is pressed
+var ErrInterrupt = errors.New("interrupt")
+
+// Completer returns the words word can be completed to
+type Completer func(word string) []string
+
+// Editor reads lines from a terminal with cursor movement, history (Up/Down, Ctrl-R)
+// and tab completion
+type Editor struct {
+	fd       int
+	raw      bool // fd is switched to raw mode while a line is read
+	in       *bufio.Reader
+	out      io.Writer
+	history  *History
+	complete Completer
+}
+
+// New returns an Editor for the terminal in, an error when in is no terminal
+func New(in *os.File, out io.Writer, history *History, complete Completer) (*Editor, error) {
+	if !IsTerminal(int(in.Fd())) {
+		return nil, fmt.Errorf("editor.New -> %v is not a terminal", in.Name())
+	}
+	this := newEditor(in, out, history, complete)
+	this.fd = int(in.Fd())
+	this.raw = true
+	return this, nil
+}
+
+func newEditor(in io.Reader, out io.Writer, history *History, complete Completer) *Editor {
+	if nil == history {
+		history = NewHistory(0)
+	}
+	return &Editor{in: bufio.NewReader(in), out: out, history: history, complete: complete}
+}
+
+// ReadLine prints prompt and returns the line entered without the line break, io.EOF for
+// Ctrl-D on an empty line and ErrInterrupt for Ctrl-C
+func (this *Editor) ReadLine(prompt string) (string, error) {
+	if this.raw {
+		old, err := makeRaw(this.fd)
+		if nil != err {
+			return "", fmt.Errorf("Editor.ReadLine -> %w", err)
+		}
+		defer setState(this.fd, old)
+	}
+	line, err := this.edit(prompt)
+	if nil != err {
+		return "", err
+	}
+	// a line the history file failed to take is still kept for this session
+	this.history.Add(line)
+	return line, nil
+}
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyCtrlH     = 8
+	keyTab       = 9
+	keyLF        = 10
+	keyCtrlK     = 11
+	keyCR        = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEsc       = 27
+	keyBackspace = 127
+)
+
+// the keys sent as escape sequences
+const (
+	keyUp rune = -(iota + 1)
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDelete
+	keyUnknown
+)
+
+// line is the line being edited, pos is the index of the rune under the cursor
+type line struct {
+	prompt string
+	buf    []rune
+	pos    int
+}
+
+func (this *line) set(s string) {
+	this.buf = []rune(s)
+	this.pos = len(this.buf)
+}
+
+func (this *line) insert(r ...rune) {
+	buf := make([]rune, 0, len(this.buf)+len(r))
+	buf = append(buf, this.buf[:this.pos]...)
+	buf = append(buf, r...)
+	this.buf = append(buf, this.buf[this.pos:]...)
+	this.pos += len(r)
+}
+
+// cut removes the runes from start to end and leaves the cursor at start
+func (this *line) cut(start int, end int) {
+	this.buf = append(this.buf[:start], this.buf[end:]...)
+	this.pos = start
+}
+
+func (this *line) wordStart() int {
+	start := this.pos
+	for start > 0 && isWordRune(this.buf[start-1]) {
+		start--
+	}
+	return start
+}
+
+func isWordRune(r rune) bool {
+	return '_' == r || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func (this *Editor) edit(prompt string) (string, error) {
+	l := &line{prompt: prompt}
+	entries := this.history.Entries()
+	current := len(entries) // the history entry shown, len(entries) is the new line
+	var draft string        // the new line while history entries are shown
+	this.refresh(l)
+	for {
+		r, err := this.readKey()
+		if nil != err {
+			if io.EOF == err && len(l.buf) > 0 {
+				return string(l.buf), nil
+			}
+			return "", err
+		}
+		if keyCtrlR == r {
+			if r, err = this.search(l, entries); nil != err {
+				return "", err
+			}
+		}
+		switch r {
+		case keyCR, keyLF:
+			io.WriteString(this.out, "\r\n")
+			return string(l.buf), nil
+		case keyCtrlC:
+			io.WriteString(this.out, "^C\r\n")
+			return "", ErrInterrupt
+		case keyCtrlD:
+			if 0 == len(l.buf) {
+				io.WriteString(this.out, "\r\n")
+				return "", io.EOF
+			}
+			if l.pos < len(l.buf) {
+				l.cut(l.pos, l.pos+1)
+			}
+		case keyDelete:
+			if l.pos < len(l.buf) {
+				l.cut(l.pos, l.pos+1)
+			}
+		case keyBackspace, keyCtrlH:
+			if l.pos > 0 {
+				l.cut(l.pos-1, l.pos)
+			}
+		case keyCtrlA, keyHome:
+			l.pos = 0
+		case keyCtrlE, keyEnd:
+			l.pos = len(l.buf)
+		case keyCtrlB, keyLeft:
+			if l.pos > 0 {
+				l.pos--
+			}
+		case keyCtrlF, keyRight:
+			if l.pos < len(l.buf) {
+				l.pos++
+			}
+		case keyCtrlK:
+			l.cut(l.pos, len(l.buf))
+		case keyCtrlU:
+			l.cut(0, l.pos)
+		case keyCtrlW:
+			start := l.pos
+			for start > 0 && ' ' == l.buf[start-1] {
+				start--
+			}
+			for start > 0 && ' ' != l.buf[start-1] {
+				start--
+			}
+			l.cut(start, l.pos)
+		case keyCtrlP, keyUp:
+			if current > 0 {
+				if len(entries) == current {
+					draft = string(l.buf)
+				}
+				current--
+				l.set(entries[current])
+			}
+		case keyCtrlN, keyDown:
+			if current < len(entries) {
+				current++
+				if len(entries) == current {
+					l.set(draft)
+				} else {
+					l.set(entries[current])
+				}
+			}
+		case keyTab:
+			this.completeWord(l)
+		default:
+			if r >= ' ' {
+				l.insert(r)
+			}
+		}
+		this.refresh(l)
+	}
+}
+
+// search runs a Ctrl-R reverse incremental search through entries, the match is left in l.
+// It returns the key which ended the search for edit to handle, Ctrl-G restores l and
+// returns 0.
+func (this *Editor) search(l *line, entries []string) (rune, error) {
+	orig := string(l.buf)
+	query := []rune{}
+	match := len(entries)
+	failed := false
+	find := func(from int) {
+		for i := from; i >= 0 && i < len(entries); i-- {
+			if idx := strings.Index(entries[i], string(query)); idx >= 0 {
+				match, failed = i, false
+				l.set(entries[i])
+				l.pos = len([]rune(entries[i][:idx]))
+				return
+			}
+		}
+		failed = true
+	}
+	for {
+		status := "reverse-i-search"
+		if failed {
+			status = "failed " + status
+		}
+		this.refresh(&line{prompt: fmt.Sprintf("(%v)`%v': ", status, string(query)), buf: l.buf, pos: l.pos})
+		r, err := this.readKey()
+		if nil != err {
+			return 0, err
+		}
+		switch {
+		case keyCtrlR == r:
+			if len(query) > 0 {
+				find(match - 1)
+			}
+		case keyBackspace == r || keyCtrlH == r:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				if len(query) > 0 {
+					find(len(entries) - 1)
+				}
+			}
+		case keyCtrlG == r:
+			l.set(orig)
+			return 0, nil
+		case r >= ' ':
+			query = append(query, r)
+			if match == len(entries) {
+				find(len(entries) - 1)
+			} else {
+				find(match)
+			}
+		default:
+			return r, nil
+		}
+	}
+}
+
+// completeWord completes the word before the cursor to the longest prefix its candidates
+// share, the candidates are listed when that adds nothing
+func (this *Editor) completeWord(l *line) {
+	if nil == this.complete {
+		return
+	}
+	start := l.wordStart()
+	word := string(l.buf[start:l.pos])
+	seen := map[string]bool{}
+	candidates := []string{}
+	for _, c := range this.complete(word) {
+		if strings.HasPrefix(c, word) && !seen[c] {
+			seen[c] = true
+			candidates = append(candidates, c)
+		}
+	}
+	if 0 == len(candidates) {
+		io.WriteString(this.out, "\a")
+		return
+	}
+	sort.Strings(candidates)
+	prefix := commonPrefix(candidates)
+	if len(prefix) > len(word) {
+		l.insert([]rune(prefix[len(word):])...)
+		return
+	}
+	if len(candidates) > 1 {
+		io.WriteString(this.out, "\r\n"+strings.Join(candidates, "  ")+"\r\n")
+	}
+}
+
+func commonPrefix(words []string) string {
+	prefix := words[0]
+	for _, w := range words[1:] {
+		for !strings.HasPrefix(w, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// refresh redraws the line and puts the cursor at pos
+func (this *Editor) refresh(l *line) {
+	var out bytes.Buffer
+	out.WriteString("\r")
+	out.WriteString(l.prompt)
+	out.WriteString(string(l.buf))
+	out.WriteString("\x1b[K\r")
+	if col := len([]rune(l.prompt)) + l.pos; col > 0 {
+		out.WriteString(fmt.Sprintf("\x1b[%vC", col))
+	}
+	this.out.Write(out.Bytes())
+}
+
+// readKey returns the next key, escape sequences are returned as one of the negative keys
+func (this *Editor) readKey() (rune, error) {
+	r, _, err := this.in.ReadRune()
+	if nil != err || keyEsc != r {
+		return r, err
+	}
+	r, _, err = this.in.ReadRune()
+	if nil != err {
+		return 0, err
+	}
+	if '[' != r && 'O' != r {
+		return keyUnknown, nil
+	}
+	r, _, err = this.in.ReadRune()
+	if nil != err {
+		return 0, err
+	}
+	switch r {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if r < '0' || r > '9' {
+		return keyUnknown, nil
+	}
+	// ESC [ n ~, n may have more digits and parameters
+	seq := []rune{r}
+	for {
+		r, _, err = this.in.ReadRune()
+		if nil != err {
+			return 0, err
+		}
+		if '~' == r {
+			break
+		}
+		if (r < '0' || r > '9') && ';' != r {
+			return keyUnknown, nil
+		}
+		seq = append(seq, r)
+	}
+	switch string(seq) {
+	case "1", "7":
+		return keyHome, nil
+	case "4", "8":
+		return keyEnd, nil
+	case "3":
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
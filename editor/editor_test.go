@@ -0,0 +1,180 @@
+package editor
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	up    = "\x1b[A"
+	down  = "\x1b[B"
+	right = "\x1b[C"
+	left  = "\x1b[D"
+	home  = "\x1b[H"
+	end   = "\x1b[F"
+	del   = "\x1b[3~"
+)
+
+func TestReadLine(t *testing.T) {
+	complete := func(word string) []string {
+		return []string{"var", "value", "values", "for", "func", "false"}
+	}
+	tests := []struct {
+		input   string
+		history []string
+		want    []string
+	}{
+		{"var x = 1;\r", nil, []string{"var x = 1;"}},
+		{"var x = 1;\n", nil, []string{"var x = 1;"}},
+		{"ac" + left + "b\r", nil, []string{"abc"}},
+		{"bc" + home + "a" + end + "d\r", nil, []string{"abcd"}},
+		{"bc\x01a\x05d\r", nil, []string{"abcd"}},
+		{"abx\x7fc\r", nil, []string{"abc"}},
+		{"abxc" + left + left + del + "\r", nil, []string{"abc"}},
+		{"abxc\x02\x02\x04\r", nil, []string{"abc"}},
+		{"abc\x02\x02\x0b\r", nil, []string{"a"}},
+		{"abc\x02\x15\r", nil, []string{"c"}},
+		{"var x = 1\x17\x17\r", nil, []string{"var x "}},
+		{"ab" + right + right + "c\r", nil, []string{"abc"}},
+		{"日本" + left + "x\r", nil, []string{"日x本"}},
+		{up + "\r", []string{"one", "two"}, []string{"two"}},
+		{up + up + up + "\r", []string{"one", "two"}, []string{"one"}},
+		{"new" + up + down + "\r", []string{"one"}, []string{"new"}},
+		{up + up + down + "!\r", []string{"one", "two"}, []string{"two!"}},
+		{"\x10\x10\x0e\r", []string{"one", "two"}, []string{"two"}},
+		{"\x12on\r", []string{"one", "two", "bone"}, []string{"bone"}},
+		{"\x12on\x12\r", []string{"one", "two", "bone"}, []string{"one"}},
+		{"\x12on\x12\x12\r", []string{"one", "two", "bone"}, []string{"one"}},
+		{"\x12tw" + end + "!\r", []string{"one", "two"}, []string{"two!"}},
+		{"x\x12tw\x07\r", []string{"one", "two"}, []string{"x"}},
+		{"\x12twx\x7f\r", []string{"one", "two"}, []string{"two"}},
+		{"fo\t (\r", nil, []string{"for ("}},
+		{"x = val\t\r", nil, []string{"x = value"}},
+		{"x = val\tsx\r", nil, []string{"x = valuesx"}},
+		{"fa\tl\r", nil, []string{"falsel"}},
+		{"zz\t\r", nil, []string{"zz"}},
+		{"a\rb\r", nil, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		history := NewHistory(0)
+		for _, h := range tt.history {
+			history.Add(h)
+		}
+		var out strings.Builder
+		e := newEditor(strings.NewReader(tt.input), &out, history, complete)
+		got := []string{}
+		for range tt.want {
+			line, err := e.ReadLine("> ")
+			if nil != err {
+				t.Fatalf("[%q] error %v", tt.input, err)
+			}
+			got = append(got, line)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("[%q] lines %q, want %q", tt.input, got, tt.want)
+		}
+		if _, err := e.ReadLine("> "); io.EOF != err {
+			t.Errorf("[%q] error %v at the end of the input, want EOF", tt.input, err)
+		}
+	}
+}
+
+func TestReadLineErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  error
+	}{
+		{"abc\x03", ErrInterrupt},
+		{"\x12ab\x03", ErrInterrupt},
+		{"\x04", io.EOF},
+		{"", io.EOF},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		e := newEditor(strings.NewReader(tt.input), &out, nil, nil)
+		if _, err := e.ReadLine("> "); err != tt.want {
+			t.Errorf("[%q] error %v, want %v", tt.input, err, tt.want)
+		}
+	}
+}
+
+func TestCompletionList(t *testing.T) {
+	var out strings.Builder
+	complete := func(word string) []string {
+		return []string{"for", "func", "false"}
+	}
+	e := newEditor(strings.NewReader("f\t\r"), &out, nil, complete)
+	if _, err := e.ReadLine("> "); nil != err {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "\r\nfalse  for  func\r\n") {
+		t.Errorf("candidates not listed: %q", out.String())
+	}
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "editor")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history")
+
+	h, err := LoadHistory(file, 3)
+	if nil != err {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a", "b", "b", "", "  ", "c", "d"} {
+		if err := h.Add(line); nil != err {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"b", "c", "d"}; !reflect.DeepEqual(h.Entries(), want) {
+		t.Errorf("entries %q, want %q", h.Entries(), want)
+	}
+
+	e := newEditor(strings.NewReader("e\r"), ioutil.Discard, h, nil)
+	if _, err := e.ReadLine("> "); nil != err {
+		t.Fatal(err)
+	}
+	h, err = LoadHistory(file, 3)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want := []string{"c", "d", "e"}; !reflect.DeepEqual(h.Entries(), want) {
+		t.Errorf("loaded entries %q, want %q", h.Entries(), want)
+	}
+	data, err := ioutil.ReadFile(file)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want := "c\nd\ne\n"; string(data) != want {
+		t.Errorf("file %q, want %q", data, want)
+	}
+
+	// a file longer than max is trimmed by the next Add
+	if err := ioutil.WriteFile(file, []byte("1\n2\n3\n4\n5\n"), 0600); nil != err {
+		t.Fatal(err)
+	}
+	h, err = LoadHistory(file, 3)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := h.Add("6"); nil != err {
+		t.Fatal(err)
+	}
+	if data, err = ioutil.ReadFile(file); nil != err {
+		t.Fatal(err)
+	}
+	if want := "4\n5\n6\n"; string(data) != want {
+		t.Errorf("file %q, want %q", data, want)
+	}
+	if files, _ := ioutil.ReadDir(dir); 1 != len(files) {
+		t.Errorf("%v files left in the directory, want 1", len(files))
+	}
+}
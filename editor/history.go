@@ -0,0 +1,115 @@
+package editor
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// History is the list of the lines entered, the oldest first. A History with a file
+// loads it and appends each new line to it, so it is kept between sessions. The file is
+// rewritten with the last max lines once it holds more.
+type History struct {
+	file    string
+	max     int
+	entries []string
+	lines   int // in the file
+}
+
+// NewHistory returns an empty History kept in memory only
+func NewHistory(max int) *History {
+	return &History{max: max}
+}
+
+// LoadHistory reads the last max lines of file, a missing file is an empty History
+func LoadHistory(file string, max int) (*History, error) {
+	this := &History{file: file, max: max}
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return this, nil
+	}
+	if nil != err {
+		return nil, fmt.Errorf("LoadHistory -> %w", err)
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		this.append(scanner.Text())
+		this.lines++
+	}
+	if err := scanner.Err(); nil != err {
+		return nil, fmt.Errorf("LoadHistory -> %w", err)
+	}
+	return this, nil
+}
+
+// Entries returns the lines, the oldest first
+func (this *History) Entries() []string {
+	return this.entries
+}
+
+// Add remembers line, blank lines and repeats of the last line are dropped
+func (this *History) Add(line string) error {
+	if "" == strings.TrimSpace(line) || strings.ContainsAny(line, "\r\n") {
+		return nil
+	}
+	if n := len(this.entries); n > 0 && this.entries[n-1] == line {
+		return nil
+	}
+	this.append(line)
+	if "" == this.file {
+		return nil
+	}
+	if this.max > 0 && this.lines >= this.max {
+		return this.rewrite()
+	}
+	f, err := os.OpenFile(this.file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if nil != err {
+		return fmt.Errorf("History.Add -> %w", err)
+	}
+	defer f.Close()
+	if _, err := fmt.Fprintln(f, line); nil != err {
+		return fmt.Errorf("History.Add -> %w", err)
+	}
+	this.lines++
+	return nil
+}
+
+// rewrite replaces the file by the entries, through a temporary file renamed over it so
+// that a failure leaves the old file whole
+func (this *History) rewrite() error {
+	f, err := ioutil.TempFile(filepath.Dir(this.file), filepath.Base(this.file)+".*")
+	if nil != err {
+		return fmt.Errorf("History.rewrite -> %w", err)
+	}
+	defer os.Remove(f.Name())
+	w := bufio.NewWriter(f)
+	for _, line := range this.entries {
+		fmt.Fprintln(w, line)
+	}
+	if err := w.Flush(); nil != err {
+		f.Close()
+		return fmt.Errorf("History.rewrite -> %w", err)
+	}
+	if err := f.Close(); nil != err {
+		return fmt.Errorf("History.rewrite -> %w", err)
+	}
+	if err := os.Rename(f.Name(), this.file); nil != err {
+		return fmt.Errorf("History.rewrite -> %w", err)
+	}
+	this.lines = len(this.entries)
+	return nil
+}
+
+func (this *History) append(line string) {
+	if "" == line {
+		return
+	}
+	this.entries = append(this.entries, line)
+	if this.max > 0 && len(this.entries) > this.max {
+		this.entries = this.entries[len(this.entries)-this.max:]
+	}
+}
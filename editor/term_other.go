@@ -0,0 +1,22 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package editor
+
+import "errors"
+
+type termState struct{}
+
+var errNoTerminal = errors.New("line editing is not supported on this platform")
+
+func IsTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errNoTerminal
+}
+
+func setState(fd int, state *termState) error {
+	return errNoTerminal
+}
@@ -0,0 +1,55 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package editor
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+type termState struct {
+	termios syscall.Termios
+}
+
+func getState(fd int) (*termState, error) {
+	var state termState
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlGetTermios, uintptr(unsafe.Pointer(&state.termios))); 0 != errno {
+		return nil, errno
+	}
+	return &state, nil
+}
+
+func setState(fd int, state *termState) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), ioctlSetTermios, uintptr(unsafe.Pointer(&state.termios))); 0 != errno {
+		return errno
+	}
+	return nil
+}
+
+// IsTerminal reports whether fd is a terminal the Editor can drive
+func IsTerminal(fd int) bool {
+	_, err := getState(fd)
+	return nil == err
+}
+
+// makeRaw puts the terminal in raw mode: no echo, no line buffering and no signals,
+// Ctrl-C is read as a key. The returned state restores it.
+func makeRaw(fd int) (*termState, error) {
+	old, err := getState(fd)
+	if nil != err {
+		return nil, err
+	}
+	raw := *old
+	raw.termios.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.termios.Oflag &^= syscall.OPOST
+	raw.termios.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.termios.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.termios.Cflag |= syscall.CS8
+	raw.termios.Cc[syscall.VMIN] = 1
+	raw.termios.Cc[syscall.VTIME] = 0
+	if err := setState(fd, &raw); nil != err {
+		return nil, err
+	}
+	return old, nil
+}
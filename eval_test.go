@@ -1,13 +1,66 @@
 package main
 
 import (
+	"Q/ast"
+	"Q/code"
+	"Q/compiler"
+	"Q/diag"
 	"Q/lexer"
 	"Q/object"
 	"Q/parser"
+	"Q/vm"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
+// evaluators are the backends the tests run with, every test runs once with each
+var evaluators = []struct {
+	name string
+	eval func(program *ast.Program, env *object.Env) (object.Object, error)
+}{
+	{"tree-walker", func(program *ast.Program, env *object.Env) (object.Object, error) {
+		return program.Eval(env, false)
+	}},
+	{"vm", func(program *ast.Program, env *object.Env) (object.Object, error) {
+		bytecode, err := compiler.Compile(program)
+		if nil != err {
+			return nil, err
+		}
+		// run the program as Decode gives it back, which verifies it
+		var buf bytes.Buffer
+		if err := bytecode.Encode(&buf); nil != err {
+			return nil, err
+		}
+		if bytecode, err = code.Decode(&buf); nil != err {
+			return nil, err
+		}
+		return vm.Run(bytecode, env)
+	}},
+}
+
+// evaluate evaluates program with the backend of the current run
+var evaluate = evaluators[0].eval
+
+func TestMain(m *testing.M) {
+	for _, e := range evaluators {
+		evaluate = e.eval
+		if status := m.Run(); 0 != status {
+			fmt.Fprintf(os.Stderr, "FAIL with the %v\n", e.name)
+			os.Exit(status)
+		}
+	}
+	os.Exit(0)
+}
+
 func testEval(input string) (object.Object, error) {
 	l := lexer.New(input)
 	p, err := parser.New(l)
@@ -16,7 +69,7 @@ func testEval(input string) (object.Object, error) {
 	}
 	program := p.ParseProgram()
 	env := object.NewEnv()
-	return program.Eval(env, false)
+	return evaluate(program, env)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -54,6 +107,32 @@ func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	return true
 }
 
+func testStringObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.String)
+	if !ok {
+		t.Errorf("object is not string, got=%v", obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value, got=%v, want: %v", result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not float, got=%v", obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value, got=%v, want: %v", result.Value, expected)
+		return false
+	}
+	return true
+}
+
 func testEvalObject(t *testing.T, evaluated object.Object, expected interface{}) {
 	switch et := expected.(type) {
 	case bool:
@@ -62,6 +141,10 @@ func testEvalObject(t *testing.T, evaluated object.Object, expected interface{})
 		testNullObject(t, evaluated)
 	case int:
 		testIntegerObject(t, evaluated, int64(et))
+	case string:
+		testStringObject(t, evaluated, et)
+	case float64:
+		testFloatObject(t, evaluated, et)
 	}
 }
 
@@ -396,3 +479,1420 @@ func TestForCases(t *testing.T) {
 		testEvalObject(t, evaluated, tt.expected)
 	}
 }
+
+func TestStringCases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"hello"`, "hello"},
+		{`"hello" + " " + "world"`, "hello world"},
+		{`var s = "a"; s = s + "\u{62}"; s`, "ab"},
+		{`"a\tb"`, "a\tb"},
+		{`"" + ""`, ""},
+		{`"abc" == "abc"`, true},
+		{`"abc" != "abc"`, false},
+		{`"abc" < "abd"`, true},
+		{`"b" > "abc"`, true},
+		{`"abc" <= "abc"`, true},
+		{`"abc" >= "abd"`, false},
+		{`!""`, true},
+		{`!"a"`, false},
+		{`"a" && "b"`, "b"},
+		{`"" && "b"`, ""},
+		{`"" || "b"`, "b"},
+		{`"a" || "b"`, "a"},
+		{`"a" == 1`, false},
+		{`"a" != 1`, true},
+		{`1 == "1"`, false},
+		{`"a" == true`, false},
+		{`"" || 2`, 2},
+		{`1 && "x"`, "x"},
+		{`"a" != null`, true},
+		{`"a" == null`, false},
+		{`null < "a"`, true},
+		{`"a" > null`, true},
+		{`"a" && null`, object.Nil},
+		{`"" && null`, ""},
+		{`null || "a"`, "a"},
+		{`if ("x") { "yes" } else { "no" }`, "yes"},
+		{`var greet = func(name) { "hi " + name }; greet("q")`, "hi q"},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"a" + 1`, "unsupported op: string + integer"},
+		{`1 + "a"`, "unsupported op: integer + string"},
+		{`"a" - "b"`, "unsupported op -"},
+		{`"a" * true`, "unsupported op: string * boolean"},
+		{`-"a"`, "String.Opposite -> unsupported"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestStringInspect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`"abc"`, `"abc"`},
+		{`"a\"b"`, `"a\"b"`},
+		{`"a\nb\\"`, `"a\nb\\"`},
+		{`"\u{7}"`, `"\u{7}"`},
+		{`"中"`, `"中"`},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if evaluated.Inspect() != tt.want {
+			t.Errorf("[%v] Inspect() = %v, want %v", tt.input, evaluated.Inspect(), tt.want)
+		}
+	}
+}
+
+func TestFloatCases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1.5", 1.5},
+		{".5", 0.5},
+		{"1e-3", 0.001},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3.0},
+		{"1.5 + 1", 2.5},
+		{"1 + 1.5", 2.5},
+		{"7 / 2.0", 3.5},
+		{"7.0 / 2", 3.5},
+		{"7 / 2", 3},
+		{"2.5 * 2", 5.0},
+		{"5.5 % 2", 1.5},
+		{"1 - .25", 0.75},
+		{"true + 0.5", 1.5},
+		{"0.5 + false", 0.5},
+		{"1.0 == 1", true},
+		{"1 == 1.0", true},
+		{"1.5 > 1", true},
+		{"1 < 1.5", true},
+		{"1.5 <= 1.5", true},
+		{"1.5 >= 2", false},
+		{"1.5 != 1.5", false},
+		{"true == 1.0", true},
+		{"!0.0", true},
+		{"!0.1", false},
+		{"0.0 || 2.5", 2.5},
+		{"1.5 && 2.5", 2.5},
+		{"0.0 && 2", 0.0},
+		{"2.5 || 1", 2.5},
+		{"1.5 > null", true},
+		{"null < 1.5", true},
+		{"1.5 == null", false},
+		{"1.5 && null", object.Nil},
+		{"0.0 && null", 0.0},
+		{"null || 1.5", 1.5},
+		{"1.5 == \"1.5\"", false},
+		{"var avg = func(a, b) { (a + b) / 2.0 }; avg(1, 2)", 1.5},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"1.5", "1.5"},
+		{"2.0", "2.0"},
+		{"4 / 2.0", "2.0"},
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1e21", "1e+21"},
+		{"1e-7", "1e-07"},
+		{"-0.5", "-0.5"},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		inspected := evaluated.Inspect()
+		if inspected != tt.want {
+			t.Errorf("[%v] Inspect() = %v, want %v", tt.input, inspected, tt.want)
+		}
+		// parsing the output of Inspect gives back the same value
+		roundTrip, err := testEval(inspected)
+		if nil != err {
+			t.Fatal(err)
+		}
+		testFloatObject(t, roundTrip, evaluated.(*object.Float).Value)
+	}
+}
+
+func TestDivisionByZero(t *testing.T) {
+	tests := []string{
+		"1 / 0",
+		"1 % 0",
+		"1.5 / 0",
+		"1 / 0.0",
+		"1.5 % 0.0",
+		"true / false",
+	}
+	for _, input := range tests {
+		_, err := testEval(input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", input)
+		}
+		if !strings.Contains(err.Error(), "division by zero") {
+			t.Errorf("[%v] unexpected error %v", input, err)
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+	evaluated, err := testEval(input)
+	if nil != err {
+		t.Fatal(err)
+	}
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not array, got %v", reflect.TypeOf(evaluated).String())
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements, got %v", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+	if result.Inspect() != "[1, 4, 6]" {
+		t.Errorf("Inspect() wrong, got %v", result.Inspect())
+	}
+}
+
+func TestArrayCases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1]", 2},
+		{"[1, 2, 3][2]", 3},
+		{"var i = 0; [1][i];", 1},
+		{"[1, 2, 3][1 + 1];", 3},
+		{"var a = [1, 2, 3]; a[2];", 3},
+		{"var a = [1, 2, 3]; a[0] + a[1] + a[2];", 6},
+		{"var a = [1, 2, 3]; var i = a[0]; a[i]", 2},
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[[1, 2], [3, 4]][1][0]", 3},
+		{"var f = func() { [10, 20] }; f()[1]", 20},
+		{"var a = [1, 2, 3]; a[0] = 10; a[0] + a[1]", 12},
+		{"var a = [1, 2, 3]; a[-1] = 30; a[2]", 30},
+		{"var a = [[1], [2]]; a[1][0] = 5; a[1][0]", 5},
+		{"var a = [1, 2]; var b = a; b[0] = 9; a[0]", 9},
+		{"var a = [1, 2]; a[1] = a[0] + a[1];", 3},
+		{`["a", "b"][1]`, "b"},
+		{`"héllo"[1]`, "é"},
+		{`"abc"[-1]`, "c"},
+		{"([1, 2] + [3])[2]", 3},
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] == [1, 3]", false},
+		{"[1, 2] != [1]", true},
+		{"[] == []", true},
+		{"[1] == 1", false},
+		{"[1] != null", true},
+		{"null == []", false},
+		{"!![]", false},
+		{"!![0]", true},
+		{"[] || 1", 1},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestArrayErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"[1, 2, 3][3]", "index 3 out of range for array of length 3"},
+		{"[1, 2, 3][-4]", "index -4 out of range for array of length 3"},
+		{"[][0]", "index 0 out of range for array of length 0"},
+		{"var a = [1]; a[1] = 2;", "index 1 out of range for array of length 1"},
+		{`[1]["0"]`, "index must be integer, got string"},
+		{"[1][1.0]", "index must be integer, got float"},
+		{"1[0]", "integer is not indexable"},
+		{`"abc"[3]`, "index 3 out of range for string of length 3"},
+		{`var s = "abc"; s[0] = "x";`, "strings are immutable"},
+		{"[1] + 1", "unsupported op: array + integer"},
+		{"[1] - [1]", "unsupported op: array - array"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestHashCases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`{"a": 5}["a"]`, 5},
+		{`{"a": 5}["b"]`, object.Nil},
+		{`var key = "a"; {"a": 5}[key]`, 5},
+		{`{}["a"]`, object.Nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+		{`{false: 5}[false]`, 5},
+		{`{null: 5}[null]`, 5},
+		{`{1: "int", true: "bool"}[1]`, "int"},
+		{`{1: "int", true: "bool"}[true]`, "bool"},
+		{`{"one": 10 - 9, "two": 1 + 1, "thr" + "ee": 6 / 2}["three"]`, 3},
+		{`var h = {"a": 1}; h["a"] = 2; h["a"]`, 2},
+		{`var h = {}; h["x"] = [1, 2]; h["x"][1]`, 2},
+		{`var h = {"a": {"b": 1}}; h["a"]["b"] = 7; h["a"]["b"]`, 7},
+		{`"a" in {"a": 1}`, true},
+		{`"b" in {"a": 1}`, false},
+		{`1 in {1: null}`, true},
+		{`2 in [1, 2, 3]`, true},
+		{`4 in [1, 2, 3]`, false},
+		{`"ell" in "hello"`, true},
+		{`"x" in "hello"`, false},
+		{`var h = {"a": 1, "b": 2}; delete h["a"]; "a" in h`, false},
+		{`var h = {"a": 1, "b": 2}; delete h["a"];`, true},
+		{`var h = {"a": 1}; delete h["z"];`, false},
+		{`var h = {"a": 1}; delete h["a"]; h["a"]`, object.Nil},
+		{`{"a": 1} == {"a": 1}`, true},
+		{`{"a": 1, "b": 2} == {"b": 2, "a": 1}`, true},
+		{`{"a": 1} == {"a": 2}`, false},
+		{`{"a": 1} != {"b": 1}`, true},
+		{`{} != null`, true},
+		{`!{}`, true},
+		{`var h = {"k": 1}; h["k"] != null && h["k"] > 0`, true},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestHashInspect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{}`, `{}`},
+		{`{"b": 1, "a": 2, 3: true, null: [1]}`, `{"b": 1, "a": 2, 3: true, null: [1]}`},
+		{`var h = {"z": 1, "y": 2}; h["a"] = 3; h["z"] = 4; h`, `{"z": 4, "y": 2, "a": 3}`},
+		{`var h = {"z": 1, "y": 2, "x": 3}; delete h["y"]; h["y"] = 5; h`, `{"z": 1, "x": 3, "y": 5}`},
+		{`{"a": 1, "a": 2}`, `{"a": 2}`},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		if evaluated.Inspect() != tt.want {
+			t.Errorf("[%v] Inspect() = %v, want %v", tt.input, evaluated.Inspect(), tt.want)
+		}
+	}
+}
+
+func TestCyclicContainers(t *testing.T) {
+	tests := []struct {
+		input    string
+		inspect  string
+		expected interface{}
+	}{
+		{"var a = [1, 2]; a[0] = a; a", "[[...], 2]", nil},
+		{`var h = {"k": 1}; h["self"] = h; h`, `{"k": 1, "self": {...}}`, nil},
+		{`var a = [1]; var h = {"a": a}; a[0] = h; a`, `[{"a": [...]}]`, nil},
+		{"var a = [1, 2]; a[1] = [a, a]; a", "[1, [[...], [...]]]", nil},
+		{"var a = [1]; a[0] = a; var b = [1]; b[0] = b; a == b", "", true},
+		{"var a = [1]; a[0] = a; var b = [[1]]; b[0][0] = b; a == b", "", true},
+		{"var a = [1, 1]; a[0] = a; var b = [1, 2]; b[0] = b; a != b", "", true},
+		{`var h = {}; h["h"] = h; var g = {}; g["h"] = g; h == g`, "", true},
+		{"var a = [1]; a[0] = a; a in [a]", "", true},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		if nil != tt.expected {
+			testEvalObject(t, evaluated, tt.expected)
+		} else if evaluated.Inspect() != tt.inspect {
+			t.Errorf("[%v] Inspect() = %v, want %v", tt.input, evaluated.Inspect(), tt.inspect)
+		}
+	}
+}
+
+func TestHashErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{[1]: 2}`, "array is not hashable"},
+		{`{"a": 1}[{}]`, "hash is not hashable"},
+		{`{"a": 1}[1.5]`, "float is not hashable"},
+		{`var h = {}; h[[1]] = 1;`, "array is not hashable"},
+		{`var a = [1]; delete a[0];`, "delete from array is unsupported"},
+		{`1 in 2`, "integer is not a container"},
+		{`1 in "abc"`, "expected string, got integer"},
+		{`[1] in {}`, "array is not hashable"},
+		{`{} + {}`, "unsupported op: hash + hash"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestBuiltinCases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("héllo")`, 5},
+		{`len([1, 2, 3])`, 3},
+		{`len([])`, 0},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`type(1)`, "integer"},
+		{`type(1.5)`, "float"},
+		{`type("s")`, "string"},
+		{`type(true)`, "boolean"},
+		{`type(null)`, "null"},
+		{`type([])`, "array"},
+		{`type({})`, "hash"},
+		{`type(len)`, "builtin"},
+		{`type(func() {})`, "function"},
+		{`int("42")`, 42},
+		{`int(" -7 ")`, -7},
+		{`int(3.9)`, 3},
+		{`int(-3.9)`, -3},
+		{`int(true)`, 1},
+		{`int(5)`, 5},
+		{`int(-9223372036854775808.0)`, -9223372036854775808},
+		{`int(9.2e18)`, 9200000000000000000},
+		{`float("1.5")`, 1.5},
+		{`float(2)`, 2.0},
+		{`bool(0)`, false},
+		{`bool("x")`, true},
+		{`bool([])`, false},
+		{`bool(null)`, false},
+		{`str(42)`, "42"},
+		{`str(1.0)`, "1.0"},
+		{`str("s")`, "s"},
+		{`str([1, "a"])`, `[1, "a"]`},
+		{`str(null)`, "null"},
+		{`var a = [1]; push(a, 2); len(a)`, 2},
+		{`var a = [1, 2]; pop(a) + len(a)`, 3},
+		{`keys({"b": 1, "a": 2})[0]`, "b"},
+		{`values({"b": 1, "a": 2})[1]`, 2},
+		{`var len = func(x) { 42 }; len([])`, 42},
+		{`var f = len; f("ab")`, 2},
+		{`len == len`, true},
+		{`len != str`, true},
+		{`len != null`, true},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`len(1)`, "Builtin.Call -> len: argument 1 must be string, array, hash or range, got integer"},
+		{`len("one", "two")`, "Builtin.Call -> len: 2 args provided, but 1 args required"},
+		{`len()`, "Builtin.Call -> len: 0 args provided, but 1 args required"},
+		{`int("x")`, `cannot convert "x" to integer`},
+		{`int(1e19)`, "cannot convert 1e+19 to integer, out of range"},
+		{`int(-1e19)`, "cannot convert -1e+19 to integer, out of range"},
+		{`int(9223372036854775808.0)`, "out of range"},
+		{`int(1e308 * 10.0)`, "cannot convert +Inf to integer, out of range"},
+		{`int(-1e308 * 10.0)`, "cannot convert -Inf to integer, out of range"},
+		{`int(0.0 * (1e308 * 10.0))`, "cannot convert NaN to integer"},
+		{`int([])`, "argument 1 must be integer, float, boolean or string, got array"},
+		{`float("x")`, `cannot convert "x" to float`},
+		{`push(1, 2)`, "argument 1 must be array, got integer"},
+		{`pop([])`, "pop from empty array"},
+		{`keys([])`, "argument 1 must be hash, got array"},
+		{`undefinedFn(1)`, "`undefinedFn` not found"},
+		{`len + 1`, "unsupported op: builtin + integer"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestPrintBuiltin(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`print("a", 1, true)`, "a 1 true"},
+		{`println("a", 1.5, null)`, "a 1.5 null\n"},
+		{`println()`, "\n"},
+		{`print([1, "x"]); print({"k": "v"})`, `[1, "x"]{"k": "v"}`},
+		{`var greet = func(name) { println("hi " + name) }; greet("q"); greet("z")`, "hi q\nhi z\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		l := lexer.New(tt.input)
+		p, err := parser.New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		env := object.NewEnv()
+		env.SetBuiltins(object.NewBuiltins(&out))
+		evaluated, err := evaluate(program, env)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testNullObject(t, evaluated)
+		if out.String() != tt.want {
+			t.Errorf("[%v] printed %q, want %q", tt.input, out.String(), tt.want)
+		}
+	}
+}
+
+func TestRegisterBuiltin(t *testing.T) {
+	eval := func(input string, env *object.Env) (object.Object, error) {
+		p, err := parser.New(lexer.New(input))
+		if nil != err {
+			return nil, err
+		}
+		return evaluate(p.ParseProgram(), env)
+	}
+	env := object.NewEnv()
+	env.RegisterBuiltin("double", func(args []object.Object) (object.Object, error) {
+		if err := object.CheckArgs(args, 1); nil != err {
+			return nil, err
+		}
+		i, ok := args[0].(*object.Integer)
+		if !ok {
+			return nil, object.ArgError(1, "integer", args[0])
+		}
+		return &object.Integer{Value: i.Value * 2}, nil
+	})
+	evaluated, err := eval("double(len([1]) + 20)", env)
+	if nil != err {
+		t.Fatal(err)
+	}
+	testIntegerObject(t, evaluated, 42)
+
+	_, err = eval(`double("x")`, env)
+	if nil == err || !strings.Contains(err.Error(), "double: argument 1 must be integer, got string") {
+		t.Errorf("unexpected error %v", err)
+	}
+	if _, err := testEval("double(1)"); nil == err || !strings.Contains(err.Error(), "`double` not found") {
+		t.Errorf("builtin registered in another Env: %v", err)
+	}
+}
+
+func TestClosureAssign(t *testing.T) {
+	counter := `
+	var newCounter = func() {
+		var count = 0;
+		func() {
+			count = count + 1;
+			count;
+		};
+	};
+	var first = newCounter();
+	var second = newCounter();
+	first();
+	first();
+	second();
+	first();
+	`
+	shared := `
+	var newPair = func() {
+		var n = 0;
+		var inc = func() { n = n + 1; };
+		var get = func() { n; };
+		[inc, get];
+	};
+	var p = newPair();
+	p[0]();
+	p[0]();
+	p[1]();
+	`
+	nested := `
+	var total = 0;
+	var outer = func() {
+		var add = func(x) {
+			var inner = func() { total = total + x; };
+			inner();
+		};
+		add(1);
+		add(2);
+	};
+	outer();
+	outer();
+	total;
+	`
+	shadowed := `
+	var x = 1;
+	var f = func(x) { x = x + 10; x; };
+	f(5) + x;
+	`
+	memo := `
+	var cache = {};
+	var calls = 0;
+	var fib = func(n) {
+		if (n in cache) { return cache[n]; }
+		calls = calls + 1;
+		var v = n;
+		if (n > 1) { v = fib(n - 1) + fib(n - 2); }
+		cache[n] = v;
+		v;
+	};
+	fib(30) + calls;
+	`
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{counter, 3},
+		{shared, 2},
+		{nested, 6},
+		{shadowed, 16},
+		{memo, 832040 + 31},
+		{"var a = 1; var f = func() { a = 2; }; f(); a;", 2},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestAssignUndefined(t *testing.T) {
+	tests := []string{
+		"x = 1;",
+		"var f = func() { y = 1; }; f();",
+		"var f = func() { var g = func() { z = 1; }; g(); }; f();",
+	}
+	for _, input := range tests {
+		_, err := testEval(input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", input)
+		}
+		if !strings.Contains(err.Error(), "undefined") {
+			t.Errorf("[%v] unexpected error %v", input, err)
+		}
+	}
+	// a failed assignment never creates a global
+	_, err := testEval("var f = func() { g = 1; }; f(); g;")
+	if nil == err {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestBlockScope(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var x = 1; if (true) { var x = 2; } x;", 1},
+		{"var x = 1; if (true) { var x = 2; x; }", 2},
+		{"var x = 1; if (true) { x = 2; } x;", 2},
+		{"var x = 1; if (true) { var y = x + 1; if (true) { var x = y * 10; x; } }", 20},
+		{"var x = 1; if (false) { 0 } else { var x = 5; } x;", 1},
+		{"var f = func(x) { var x = x + 1; x; }; f(1);", 2},
+		{"var x = 10; var f = func() { var x = 1; x; }; f() + x;", 11},
+		{`
+		var i = 0;
+		var sum = 0;
+		for {
+			var step = i * 2;
+			sum = sum + step;
+			i = i + 1;
+			if (i >= 3) { break; }
+		}
+		sum;
+		`, 6},
+		{`
+		var fns = [];
+		var i = 0;
+		for {
+			if (i >= 3) { break; }
+			var captured = i;
+			push(fns, func() { captured; });
+			i = i + 1;
+		}
+		fns[0]() + fns[1]() * 10 + fns[2]() * 100;
+		`, 210},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestBlockScopeLeak(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"if (true) { var y = 1; } y;", "`y` not found, it was declared inside a block which has ended"},
+		{"if (true) { if (true) { var y = 1; } } y;", "`y` not found, it was declared inside a block which has ended"},
+		{"var i = 0; for { var last = i; break; } last;", "`last` not found, it was declared inside a block which has ended"},
+		{"if (true) { var y = 1; } y = 2;", "`y` undefined, it was declared inside a block which has ended"},
+		{"var f = func() { if (true) { var y = 1; } y; }; f();", "`y` not found, it was declared inside a block which has ended"},
+		{"never;", "`never` not found"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestForClauses(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var i = 0; for (i < 5) { i = i + 1; } i;", 5},
+		{"var i = 10; for (i < 5) { i = i + 1; } i;", 10},
+		{"var sum = 0; for (var i = 0; i < 5; i = i + 1) { sum = sum + i; } sum;", 10},
+		{"var sum = 0; var i = 0; for (; i < 5; i = i + 1) { sum = sum + i; } sum;", 10},
+		{"var sum = 0; for (var i = 0; ; i = i + 1) { if (i >= 4) { break; } sum = sum + i; } sum;", 6},
+		{"var a = [1, 2, 3]; var i = 0; for (; i < 2; a[i] = 0) { i = i + 1; } a[0] + a[1] + a[2];", 1},
+		{"var sum = 0; for (var i = 0; i < 10; i = i + 1) { if (i % 2 == 0) { continue; } sum = sum + i; } sum;", 25},
+		{"var i = 0; var n = 0; for (i < 5) { i = i + 1; if (i == 3) { continue; } n = n + 1; } n;", 4},
+		{`
+		var count = 0;
+		for (var i = 0; i < 3; i = i + 1) {
+			for (var j = 0; j < 3; j = j + 1) {
+				if (j == 1) { break; }
+				count = count + 1;
+			}
+			count = count + 10;
+		}
+		count;
+		`, 33},
+		{`
+		var count = 0;
+		for (var i = 0; i < 3; i = i + 1) {
+			for (var j = 0; j < 3; j = j + 1) {
+				if (j == 1) { continue; }
+				count = count + 1;
+			}
+		}
+		count;
+		`, 6},
+		{"var f = func() { for (var i = 0; ; i = i + 1) { if (i == 7) { return i; } } }; f();", 7},
+		{"var i = 100; for (var i = 0; i < 3; i = i + 1) { } i;", 100},
+		{"for (false) { }", object.Null{}},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestForClauseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"for (var i = 0; i < 3; i = i + 1) { } i;", "`i` not found, it was declared inside a block which has ended"},
+		{"continue;", "'continue' outside loop"},
+		{"if (true) { continue; }", "'continue' outside loop"},
+		{"break;", "'break' outside loop"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestForIn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var sum = 0; for (x in [1, 2, 3]) { sum = sum + x; } sum;", 6},
+		{"var sum = 0; for (i, x in [10, 20, 30]) { sum = sum + i * x; } sum;", 80},
+		{`var s = ""; for (k in {"a": 1, "b": 2, "c": 3}) { s = s + k; } s;`, "abc"},
+		{`var s = ""; for (k, v in {"x": 1, "y": 2}) { s = s + k + str(v); } s;`, "x1y2"},
+		{`var h = {"b": 1, "a": 2}; h["c"] = 3; h["b"] = 4; var s = ""; for (k, v in h) { s = s + k + str(v); } s;`, "b4a2c3"},
+		{`var s = ""; for (c in "héllo") { s = c + s; } s;`, "olléh"},
+		{`var n = 0; for (i, c in "abc") { n = n + i; } n;`, 3},
+		{"var sum = 0; for (i in range(5)) { sum = sum + i; } sum;", 10},
+		{"var sum = 0; for (i in range(2, 5)) { sum = sum + i; } sum;", 9},
+		{"var sum = 0; for (i in range(10, 0, -3)) { sum = sum + i; } sum;", 22},
+		{"var n = 0; for (i in range(3, 3)) { n = n + 1; } n;", 0},
+		{"var sum = 0; for (x in [1, 2, 3, 4]) { if (x == 2) { continue; } if (x == 4) { break; } sum = sum + x; } sum;", 4},
+		{"var f = func() { for (x in [5, 6, 7]) { if (x == 6) { return x; } } }; f();", 6},
+		{"var a = [1, 2, 3]; for (i, x in a) { a[i] = x * 2; } a[0] + a[1] + a[2];", 12},
+		{`var h = {"a": 1}; for (k in h) { h[k] = 5; } h["a"];`, 5},
+		{"var fns = []; for (x in [1, 2, 3]) { push(fns, func() { x; }); } fns[0]() + fns[2]();", 4},
+		{"var x = 100; for (x in [1, 2]) { } x;", 100},
+		{"for (x in []) { x; }", object.Null{}},
+		{"len(range(0, 10, 3));", 4},
+		{"len(range(10, 0, -3));", 4},
+		{"range(1, 10, 2)[-1];", 9},
+		{"7 in range(1, 10, 2);", true},
+		{"8 in range(1, 10, 2);", false},
+		{"range(5) == range(0, 5);", true},
+		{"len(range(0, 9223372036854775807, 2));", 4611686018427387904},
+		{"range(0, 9223372036854775807, 2)[-1];", 9223372036854775806},
+		{"9223372036854775806 in range(0, 9223372036854775807, 2);", true},
+		{"-2 in range(0, 9223372036854775807, 2);", false},
+		{"len(range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807));", 3},
+		{"range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807)[2];", 9223372036854775806},
+		{"var n = 0; for (i in range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1)) { n = n + i; } n;", 9223372036854775806},
+		{"-1 in range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1);", true},
+		{"len(range(-9223372036854775807 - 1, 9223372036854775807, 3));", 6148914691236517205},
+		{"range(-9223372036854775807 - 1, 9223372036854775807, 3)[-1];", 9223372036854775804},
+		{`type(range(1));`, "range"},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestForInErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"for (x in 5) { }", "integer is not iterable"},
+		{"for (x in null) { }", "null is not iterable"},
+		{"var a = [1, 2]; for (x in a) { push(a, x); }", "array modified during iteration"},
+		{"var a = [1, 2]; for (x in a) { pop(a); }", "array modified during iteration"},
+		{`var h = {"a": 1}; for (k in h) { h["b"] = 2; }`, "hash modified during iteration"},
+		{`var h = {"a": 1, "b": 2}; for (k in h) { delete h[k]; }`, "hash modified during iteration"},
+		{"for (x in [1]) { } x;", "`x` not found, it was declared inside a block which has ended"},
+		{"range(1, 2, 0);", "step must not be 0"},
+		{"range();", "0 args provided, but 1 to 3 args required"},
+		{`range("a");`, "argument 1 must be integer, got string"},
+		{"range(3)[3];", "index 3 out of range for range of length 3"},
+		{"range(-9223372036854775807 - 1, 9223372036854775807);", "range(-9223372036854775808, 9223372036854775807) has more than 9223372036854775807 elements"},
+		{"range(9223372036854775807, -9223372036854775807, -1);", "has more than"},
+		{"range(-9223372036854775807 - 1, 9223372036854775807, 2);", "has more than"},
+	}
+	for _, tt := range tests {
+		_, err := testEval(tt.input)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestRangeInspect(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"range(3)", "range(0, 3)"},
+		{"range(1, 9, 2)", "range(1, 9, 2)"},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		if evaluated.Inspect() != tt.want {
+			t.Errorf("[%v] Inspect() = %v, want %v", tt.input, evaluated.Inspect(), tt.want)
+		}
+	}
+}
+
+func TestLabeledLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`
+		var count = 0;
+		outer: for (var i = 0; i < 3; i = i + 1) {
+			for (var j = 0; j < 3; j = j + 1) {
+				if (j == 1) { break outer; }
+				count = count + 1;
+			}
+			count = count + 10;
+		}
+		count;
+		`, 1},
+		{`
+		var count = 0;
+		outer: for (var i = 0; i < 3; i = i + 1) {
+			for (var j = 0; j < 3; j = j + 1) {
+				if (j == 1) { continue outer; }
+				count = count + 1;
+			}
+			count = count + 10;
+		}
+		count;
+		`, 3},
+		{`
+		var s = "";
+		rows: for (r in ["ab", "cd", "ef"]) {
+			for (c in r) {
+				if (c == "d") { continue rows; }
+				if (c == "f") { break rows; }
+				s = s + c;
+			}
+		}
+		s;
+		`, "abce"},
+		{`
+		var n = 0;
+		a: for (var i = 0; i < 2; i = i + 1) {
+			b: for (var j = 0; j < 2; j = j + 1) {
+				for (var k = 0; k < 5; k = k + 1) {
+					if (k == 1) { continue b; }
+					if (j == 1) { break a; }
+					n = n + 1;
+				}
+			}
+		}
+		n;
+		`, 1},
+		{`
+		var n = 0;
+		outer: for (i in range(3)) {
+			inner: for (j in range(3)) {
+				if (j == 2) { break inner; }
+				n = n + 1;
+			}
+		}
+		n;
+		`, 6},
+		{"var f = func() { outer: for { for { return 42; } } }; f();", 42},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var n = 0; var f = func() { n = n + 1; true; }; false && f(); n;", 0},
+		{"var n = 0; var f = func() { n = n + 1; true; }; true || f(); n;", 0},
+		{"var n = 0; var f = func() { n = n + 1; true; }; true && f(); n;", 1},
+		{"var n = 0; var f = func() { n = n + 1; true; }; false || f(); n;", 1},
+		{"var n = 0; var f = func() { n = n + 1; 5; }; null && f(); n;", 0},
+		{"var n = 0; var f = func() { n = n + 1; 5; }; 0 && f() || f(); n;", 1},
+		{"var x = null; x != null && x[0] == 1;", false},
+		{"var x = [1]; x != null && x[0] == 1;", true},
+		{"var h = {}; \"k\" in h && h[\"k\"][\"missing\"];", false},
+		{"false && undefinedName;", false},
+		{"true || undefinedName;", true},
+		{"false && [1][5];", false},
+		{"var n = 0; var f = func() { n = n + 1; 3; }; 0 && f();", 0},
+		{"var n = 0; var f = func() { n = n + 1; 3; }; 2 || f();", 2},
+		{"var x = 2; false && x;", false},
+		{"false && -2;", 0},
+		{"true || 2.5;", 1.0},
+		{"var x = 2.5; true || x;", true},
+		{`false && -"a";`, false},
+	}
+	for _, tt := range tests {
+		evaluated, err := testEval(tt.input)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		testEvalObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"var f = func(x) {\n  x / 0;\n};\nf(3);", []string{"e.q:4:2: Call.Eval -> f", "e.q:2:5: InfixExpression.Eval", "division by zero"}},
+		{"var a = [1];\n\na[5];", []string{"e.q:3:2: Index.Eval", "index 5 out of range"}},
+		{"if (true) {\n  missing;\n}", []string{"e.q:2:3: Identifier.Eval -> `missing` not found"}},
+		{"for (x in 5) { }", []string{"e.q:1:1: ForInExpression.Eval -> integer is not iterable"}},
+		{"var x = 1;\n  break;", []string{"e.q:2:3: evalStatements -> 'break' outside loop"}},
+		{"-\"a\";", []string{"e.q:1:1: PrefixExpression.Eval"}},
+	}
+	for _, tt := range tests {
+		l := lexer.NewFile("e.q", tt.input)
+		p, err := parser.New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Fatalf("[%v] parser errors %v", tt.input, p.Errors())
+		}
+		_, err = evaluate(program, object.NewEnv())
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("[%v] error %q does not contain %q", tt.input, err.Error(), want)
+			}
+		}
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	tests := []struct {
+		input string
+		msg   string
+		start string
+		end   string
+		notes []string
+	}{
+		{"var f = func(x) {\n  x / 0;\n};\nf(3);", "Integer.calcInteger -> division by zero", "d.q:2:5", "d.q:2:6", []string{"called from f at d.q:4:2"}},
+		{"var g = func() { missing; };\nvar f = func() { g(); };\nf();", "Identifier.Eval -> `missing` not found", "d.q:1:18", "d.q:1:25", []string{"called from g at d.q:2:19", "called from f at d.q:3:2"}},
+		{`println("a" + 1);`, "Integer.calcString -> unsupported op: string + integer", "d.q:1:13", "d.q:1:14", nil},
+		{"println([1][-3]);", "Array.Index -> index -3 out of range for array of length 1", "d.q:1:12", "d.q:1:13", nil},
+		{"var f = func(x) { x; };\nf(f(1 / 0));", "Integer.calcInteger -> division by zero", "d.q:2:7", "d.q:2:8", nil},
+		{"var f = func(x) { 1 / x; };\nprintln(f(0));", "Integer.calcInteger -> division by zero", "d.q:1:21", "d.q:1:22", []string{"called from f at d.q:2:10"}},
+		{"len(1);", "Builtin.Call -> len: argument 1 must be string, array, hash or range, got integer", "d.q:1:4", "d.q:1:5", nil},
+		{"  break;", "evalStatements -> 'break' outside loop", "d.q:1:3", "d.q:1:8", nil},
+		{"var f = func() { break; };\nfor (var i = 0; i < 3; i = i + 1) { f(); }", "evalStatements -> 'break' outside loop", "d.q:1:18", "d.q:1:23", []string{"called from f at d.q:2:38"}},
+		{"var f = func() { continue; };\nfor (x in [1]) { f(); }", "evalStatements -> 'continue' outside loop", "d.q:1:18", "d.q:1:26", []string{"called from f at d.q:2:19"}},
+		{"var f = func(n) {\n  if (n == 0) { return 1 / 0; }\n  return f(n - 1);\n};\nf(5);", "Integer.calcInteger -> division by zero", "d.q:2:26", "d.q:2:27", []string{"called from f at d.q:3:11", "the call above repeated 4 more times", "called from f at d.q:5:2"}},
+	}
+	for _, tt := range tests {
+		l := lexer.NewFile("d.q", tt.input)
+		p, err := parser.New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		_, err = evaluate(program, object.NewEnv())
+		d := ast.Diagnose(err)
+		if nil == d {
+			t.Fatalf("[%v] expected a diagnostic", tt.input)
+		}
+		if d.Code != diag.CodeRuntime || d.Message != tt.msg {
+			t.Errorf("[%v] got [%v] %q, want %q", tt.input, d.Code, d.Message, tt.msg)
+		}
+		if d.Start.String() != tt.start || d.End.String() != tt.end {
+			t.Errorf("[%v] span %v-%v, want %v-%v", tt.input, d.Start, d.End, tt.start, tt.end)
+		}
+		if !reflect.DeepEqual(d.Notes, tt.notes) {
+			t.Errorf("[%v] notes %v, want %v", tt.input, d.Notes, tt.notes)
+		}
+	}
+	if nil != ast.Diagnose(nil) {
+		t.Errorf("Diagnose(nil) is not nil")
+	}
+}
+
+func TestEvalRefusesParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`println("side effect"); var = 1;`, "Program.Eval -> 1 parse errors | 1:29: expected next token to be IDENT, got ASSIGN instead"},
+		{`println("side effect"); if (x { } var y = ;`, "Program.Eval -> 2 parse errors | 1:31: expected next token to be RPAREN, got LBRACE instead"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		l := lexer.New(tt.input)
+		p, err := parser.New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		env := object.NewEnv()
+		env.SetBuiltins(object.NewBuiltins(&out))
+		_, err = evaluate(program, env)
+		if nil == err {
+			t.Fatalf("[%v] expected error", tt.input)
+		}
+		if err.Error() != tt.want {
+			t.Errorf("[%v] error %q, want %q", tt.input, err.Error(), tt.want)
+		}
+		if out.Len() > 0 {
+			t.Errorf("[%v] program ran, printed %q", tt.input, out.String())
+		}
+		if d := ast.Diagnose(err); d != program.Diags[0] {
+			t.Errorf("[%v] Diagnose() = %v, want the first parse error", tt.input, d)
+		}
+	}
+}
+
+func TestCli(t *testing.T) {
+	dir, err := ioutil.TempDir("", "Q")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	script := filepath.Join(dir, "s.q")
+	if err := ioutil.WriteFile(script, []byte("#!/usr/bin/env Q run\nprintln(len(args), args);\nexit(len(args));\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	failing := filepath.Join(dir, "f.q")
+	if err := ioutil.WriteFile(failing, []byte("println(1);\nprintln(x);\n"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	stale := filepath.Join(dir, "stale.qc")
+	if err := ioutil.WriteFile(stale, []byte("Q\x00bc\x00\x00\x00\x00\x00\x00"), 0644); nil != err {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		args    []string
+		stdin   string
+		status  int
+		out     string
+		errPart string
+	}{
+		{[]string{"run", script, "a", "b"}, "", 2, "2 [\"a\", \"b\"]\n", ""},
+		{[]string{"run", script}, "", 0, "0 []\n", ""},
+		{[]string{"run", filepath.Join(dir, "missing.q")}, "", exitError, "", "missing.q"},
+		{[]string{"run"}, "", exitUsage, "", "usage:"},
+		{[]string{"compile", script, "-o", filepath.Join(dir, "s.qc")}, "", 0, "", ""},
+		{[]string{"run", filepath.Join(dir, "s.qc"), "a"}, "", 1, "1 [\"a\"]\n", ""},
+		{[]string{"compile", failing, "-o", filepath.Join(dir, "f.qc")}, "", 0, "", ""},
+		{[]string{"run", filepath.Join(dir, "f.qc")}, "", exitError, "1\n", "f.q:2:9: error[R0001]"},
+		{[]string{"run", stale}, "", exitError, "", "compiled by a different format version"},
+		{[]string{"compile", filepath.Join(dir, "missing.q"), "-o", filepath.Join(dir, "m.qc")}, "", exitError, "", "missing.q"},
+		{[]string{"compile", script}, "", exitUsage, "", "usage:"},
+		{[]string{"-e", `println(args[0] + "!");`, "hi"}, "", 0, "hi!\n", ""},
+		{[]string{"-e", `println(1); exit(); println(2);`}, "", 0, "1\n", ""},
+		{[]string{"-e", `exit(1, 2);`}, "", exitError, "", "0 or 1 args required"},
+		{[]string{"-e", `var = 1;`}, "", exitError, "", "-e:1:5: error[P0001]"},
+		{[]string{"-e", `println(1); 1 / 0;`}, "", exitError, "1\n", "-e:1:15: error[R0001]: Integer.calcInteger -> division by zero"},
+		{[]string{"bogus"}, "", exitUsage, "", "unknown command bogus"},
+		{[]string{"-h"}, "", 0, usage, ""},
+		{nil, "#!/bin/Q\nvar x = 40;\nprintln(x + 2);", 0, "42\n", ""},
+		{nil, "println(1);\nprintln(x);", exitError, "1\n", "<stdin>:2:9: error[R0001]"},
+	}
+	for _, tt := range tests {
+		var out, errOut bytes.Buffer
+		status := cli(tt.args, strings.NewReader(tt.stdin), false, &out, &errOut)
+		if status != tt.status {
+			t.Errorf("%v: status %v, want %v, stderr %q", tt.args, status, tt.status, errOut.String())
+		}
+		if out.String() != tt.out {
+			t.Errorf("%v: stdout %q, want %q", tt.args, out.String(), tt.out)
+		}
+		if "" == tt.errPart && errOut.Len() > 0 || !strings.Contains(errOut.String(), tt.errPart) {
+			t.Errorf("%v: stderr %q, want it to contain %q", tt.args, errOut.String(), tt.errPart)
+		}
+	}
+}
+
+func TestReplExit(t *testing.T) {
+	var out bytes.Buffer
+	status := repl(strings.NewReader("println(1);\nexit(7);\nprintln(2);\n"), &out)
+	if 7 != status {
+		t.Errorf("status %v, want 7", status)
+	}
+	if strings.Contains(out.String(), "2") {
+		t.Errorf("repl went on after exit: %q", out.String())
+	}
+}
+
+func TestReplContinuation(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"var f = func(x) {\n  return x * 2;\n};\nf(21);\n", ">> .. .. func(x) {\nreturn (x * 2);\n}\n>> 42\n>> "},
+		{"1 +\n2;\n", ">> .. 3\n>> "},
+		{"println(1,\n2);\n", ">> .. 1 2\nnull\n>> "},
+		{"\"a\nb\";\n", ">> .. \"a\\nb\"\n>> "},
+		{"var = 1;\n2;\n", ">> 1:5: error[P0001]: expected next token to be IDENT, got ASSIGN instead\n 1 | var = 1;\n   |     ^\n>> 2\n>> "},
+		{"if (true) {\n", ">> .. 1:12: error[P0008]: expected RBRACE to close the block opened at 1:11, got EOF instead\n 1 | if (true) {\n   |            ^\n"},
+		{"var x = (1 +\n", ">> .. 1:13: error[P0008]: EOF has no decoder\n 1 | var x = (1 +\n   |             ^\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		repl(strings.NewReader(tt.input), &out)
+		if out.String() != tt.want {
+			t.Errorf("[%q] output %q, want %q", tt.input, out.String(), tt.want)
+		}
+	}
+}
+
+func TestReplCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "Q")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	saved := filepath.Join(dir, "saved.q")
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{":tokens x = 1;", []string{"1:1 {\"type\":\"IDENT\",\"literal\":\"x\"}", "1:3 {\"type\":\"ASSIGN\",\"literal\":\"=\"}", "1:7 {\"type\":\"EOF\",\"literal\":\"\"}"}},
+		{":ast -x", []string{"Program 1:1 \"-\"", "    Expr: PrefixExpression 1:1 \"-\"", "      Right: Identifier 1:2 \"x\""}},
+		{":ast var = 1;", []string{"1:5: error[P0001]"}},
+		{":tokens", []string{"usage: :tokens <src>"}},
+		{"var a = 1;\nvar b = [a];\n:env", []string{"a = 1\nb = [1]\n"}},
+		{"var a = 1;\n:reset\n:env\na;", []string{">> >> >> 1:1: error[R0001]: Identifier.Eval -> `a` not found"}},
+		{"var a = 1;\na / 0;\nvar f = func() {\nreturn a;\n};\n:save " + saved + "\n:reset\n:load " + saved + "\nf();", []string{"}\n>> >> >> >> 1\n>> "}},
+		{":load " + filepath.Join(dir, "missing.q"), []string{"missing.q: no such file"}},
+		{":help", []string{":tokens <src>", ":save <file>", ":help "}},
+		{":evn", []string{"unknown command :evn, did you mean :env?"}},
+		{":sav x", []string{"unknown command :sav, did you mean :save?"}},
+		{":frobnicate", []string{"unknown command :frobnicate, :help lists the commands"}},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		repl(strings.NewReader(tt.input), &out)
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("[%q] output %q, want it to contain %q", tt.input, out.String(), want)
+			}
+		}
+	}
+	src, err := ioutil.ReadFile(saved)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want := "var a = 1;\nvar f = func() {\nreturn a;\n};\n"; string(src) != want {
+		t.Errorf(":save wrote %q, want %q", string(src), want)
+	}
+}
+
+func TestReplComplete(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out)
+	if _, err := s.eval("", "var force = 1; var fx = 2;", false, true); nil != err {
+		t.Fatal(err)
+	}
+	if got, want := s.complete("f"), []string{"false", "for", "func", "force", "fx"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("complete(f) = %q, want %q", got, want)
+	}
+	if got := s.complete("zz"); 0 != len(got) {
+		t.Errorf("complete(zz) = %q, want none", got)
+	}
+}
+
+func TestInterrupt(t *testing.T) {
+	tests := []string{
+		"for { }",
+		"var i = 0; for (; true; i = i + 1) { x = i; }",
+		"for (i in range(1000000000000)) { }",
+		"var f = func() { return f(); }; f();",
+		"var f = func(n) { x = n; f(n + 1) }; f(0);",
+	}
+	for _, input := range tests {
+		l := lexer.New(input)
+		p, err := parser.New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		env := object.NewEnv()
+		env.Set("x", object.Nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		env.SetContext(ctx)
+		_, err = evaluate(program, env)
+		cancel()
+		if !errors.Is(err, object.ErrInterrupted) {
+			t.Fatalf("[%v] error %v, want interrupted", input, err)
+		}
+		if d := ast.Diagnose(err); "interrupted" != d.Message || !d.Start.IsValid() || len(d.Notes) > 4 {
+			t.Errorf("[%v] diagnostic %v with %v notes", input, d, len(d.Notes))
+		}
+		// the Env survives the interrupt
+		if v, ok := env.Get("x"); !ok || nil == v {
+			t.Errorf("[%v] x lost", input)
+		}
+	}
+}
+
+func TestReplInterrupt(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out)
+	s.interruptible = func() (context.Context, func()) {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		return ctx, cancel
+	}
+	for _, input := range []string{"var n = 0;", "for { n = n + 1; }", "n > 0;"} {
+		if _, err := s.eval("", input, true, true); nil != err {
+			t.Fatal(err)
+		}
+	}
+	if !strings.HasPrefix(out.String(), "0\n\n1:") || !strings.Contains(out.String(), "error[R0001]: interrupted\n") || !strings.HasSuffix(out.String(), "true\n") {
+		t.Errorf("output %q, want the interrupt reported and the session going on", out.String())
+	}
+	if want := []string{"var n = 0;", "n > 0;"}; !reflect.DeepEqual(s.inputs, want) {
+		t.Errorf("inputs %q, want %q", s.inputs, want)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	recurse := "var f = func(n) { if (n == 0) { return 0; } return f(n - 1) + 1; };"
+	tests := []struct {
+		input  string
+		limits object.Limits
+		want   error // nil when the limits are not exceeded
+	}{
+		{"for { }", object.Limits{MaxSteps: 1000}, object.ErrStepLimit},
+		{"var x = 1; x;", object.Limits{MaxSteps: 2}, nil},
+		{"var x = 1; x;", object.Limits{MaxSteps: 1}, object.ErrStepLimit},
+		{"for (i in range(10)) { }", object.Limits{MaxSteps: 13}, nil},
+		{"for (i in range(10)) { }", object.Limits{MaxSteps: 12}, object.ErrStepLimit},
+		{recurse + "f(9);", object.Limits{MaxCallDepth: 10}, nil},
+		{recurse + "f(10);", object.Limits{MaxCallDepth: 10}, object.ErrCallDepthLimit},
+		{recurse + "f(5); f(5); f(5);", object.Limits{MaxCallDepth: 6}, nil},
+		{"var a = []; for (i in range(10)) { push(a, i); }", object.Limits{MaxCollectionSize: 10}, nil},
+		{"var a = []; for (i in range(11)) { push(a, i); }", object.Limits{MaxCollectionSize: 10}, object.ErrCollectionSizeLimit},
+		{"[1, 2] + [3];", object.Limits{MaxCollectionSize: 2}, object.ErrCollectionSizeLimit},
+		{"[1, 2, 3];", object.Limits{MaxCollectionSize: 2}, object.ErrCollectionSizeLimit},
+		{`"ab" + "cd";`, object.Limits{MaxCollectionSize: 3}, object.ErrCollectionSizeLimit},
+		{`"ab" + "c";`, object.Limits{MaxCollectionSize: 3}, nil},
+		{"{1: 1, 2: 2};", object.Limits{MaxCollectionSize: 1}, object.ErrCollectionSizeLimit},
+		{"var h = {}; h[1] = 1; h[1] = 2; h[2] = 2;", object.Limits{MaxCollectionSize: 1}, object.ErrCollectionSizeLimit},
+		{"var a = [0]; for (i in range(100)) { a[0] = i; }", object.Limits{MaxCollectionSize: 1}, nil},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := parser.New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		env := object.NewEnv()
+		env.SetLimits(tt.limits)
+		_, err = evaluate(program, env)
+		if nil == tt.want {
+			if nil != err {
+				t.Errorf("[%v] error %v", tt.input, err)
+			}
+			continue
+		}
+		var limitErr *object.LimitError
+		if !errors.Is(err, tt.want) || !errors.As(err, &limitErr) {
+			t.Errorf("[%v] error %v, want %v", tt.input, err, tt.want)
+		}
+	}
+
+	// the limit stops an assignment before it grows the hash
+	p, err := parser.New(lexer.New("var h = {1: 1}; h[2] = 2;"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	env := object.NewEnv()
+	env.SetLimits(object.Limits{MaxCollectionSize: 1})
+	if _, err := evaluate(p.ParseProgram(), env); !errors.Is(err, object.ErrCollectionSizeLimit) {
+		t.Fatalf("error %v, want %v", err, object.ErrCollectionSizeLimit)
+	}
+	if h, _ := env.Get("h"); `{1: 1}` != h.Inspect() {
+		t.Errorf("h = %v after the limit, want {1: 1}", h.Inspect())
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	l := lexer.New("for { }")
+	p, err := parser.New(l)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := p.ParseProgram()
+	env := object.NewEnv()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	env.SetContext(ctx)
+	_, err = evaluate(program, env)
+	if !errors.Is(err, object.ErrDeadline) || errors.Is(err, object.ErrInterrupted) {
+		t.Errorf("error %v, want the deadline exceeded", err)
+	}
+	if d := ast.Diagnose(err); "deadline exceeded" != d.Message {
+		t.Errorf("diagnostic %v", d)
+	}
+}
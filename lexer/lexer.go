@@ -2,21 +2,43 @@ package lexer
 
 import (
 	"Q/token"
+	"bytes"
+	"strconv"
+	"unicode/utf8"
 )
 
 type Lexer struct {
+	file         string
 	input        string
 	position     int
 	nextPosition int
 	ch           byte
+	line         int // line of ch
+	lineStart    int // offset of the first byte of line
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+// NewFile is New for the source read from file, the name is carried by every token position
+func NewFile(file string, input string) *Lexer {
+	l := &Lexer{file: file, input: input, line: 1}
 	l.readChar()
+	l.skipShebang()
 	return l
 }
 
+// skipShebang skips a `#!` first line so scripts can be executable, the line break is kept
+func (this *Lexer) skipShebang() {
+	if '#' != this.ch || '!' != this.peekChar() {
+		return
+	}
+	for '\n' != this.ch && 0 != this.ch {
+		this.readChar()
+	}
+}
+
 func newToken(tokenType token.TokenType, ch byte) *token.Token {
 	return &token.Token{Type: tokenType, Literal: string(ch)}
 }
@@ -29,6 +51,10 @@ func isDigit(c byte) bool {
 	return '0' <= c && c <= '9'
 }
 
+func isHexDigit(c byte) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
 func isWhitespace(c byte) bool {
 	return c == ' ' ||
 		c == '\t' ||
@@ -66,9 +92,29 @@ func (this *Lexer) Parse() []*token.Token {
 }
 
 func (this *Lexer) nextToken() *token.Token {
-	var tok *token.Token
 	this.skipWhitespace()
+	pos := this.here()
+	tok := this.readToken()
+	tok.Pos = pos
+	return tok
+}
 
+// here is the position of ch
+func (this *Lexer) here() token.Position {
+	end := this.position
+	if end > len(this.input) { // ch is the end of input
+		end = len(this.input)
+	}
+	return token.Position{
+		File:   this.file,
+		Line:   this.line,
+		Col:    utf8.RuneCountInString(this.input[this.lineStart:end]) + 1,
+		Offset: end,
+	}
+}
+
+func (this *Lexer) readToken() *token.Token {
+	var tok *token.Token
 	if 0 == this.ch {
 		return &token.Token{Type: token.EOF, Literal: ""}
 	}
@@ -86,6 +132,13 @@ func (this *Lexer) nextToken() *token.Token {
 		tok = this.twoCharToken(token.LT, '=', token.LEQ, "<=")
 	case '>':
 		tok = this.twoCharToken(token.GT, '=', token.GEQ, ">=")
+	case '"':
+		pos := this.position
+		if literal, ok := this.readString(); ok {
+			tok = &token.Token{Type: token.STRING, Literal: literal}
+		} else {
+			tok = &token.Token{Type: token.ILLEGAL, Literal: this.input[pos:this.position]}
+		}
 	default:
 		tt, ok := token.GetTokenType(this.ch)
 		if ok {
@@ -94,8 +147,9 @@ func (this *Lexer) nextToken() *token.Token {
 			if isLetter(this.ch) {
 				literal := this.readIdentifier()
 				return &token.Token{Type: token.LookupIdent(literal), Literal: literal}
-			} else if isDigit(this.ch) {
-				return &token.Token{Type: token.INT, Literal: this.readNumber()}
+			} else if isDigit(this.ch) || '.' == this.ch && isDigit(this.peekChar()) {
+				tt, literal := this.readNumber()
+				return &token.Token{Type: tt, Literal: literal}
 			} else {
 				tok = newToken(token.ILLEGAL, this.ch)
 			}
@@ -106,12 +160,111 @@ func (this *Lexer) nextToken() *token.Token {
 	return tok
 }
 
-func (this *Lexer) readNumber() string {
+// readNumber reads INT (5) or FLOAT (1.5, .5, 1e-3) literals
+func (this *Lexer) readNumber() (token.TokenType, string) {
 	pos := this.position
+	tt := token.INT
+	this.readDigits()
+	if '.' == this.ch && isDigit(this.peekChar()) {
+		tt = token.FLOAT
+		this.readChar()
+		this.readDigits()
+	}
+	if this.atExponent() {
+		tt = token.FLOAT
+		this.readChar()
+		if '+' == this.ch || '-' == this.ch {
+			this.readChar()
+		}
+		this.readDigits()
+	}
+	return tt, this.input[pos:this.position]
+}
+
+func (this *Lexer) readDigits() {
 	for isDigit(this.ch) {
 		this.readChar()
 	}
-	return this.input[pos:this.position]
+}
+
+func (this *Lexer) atExponent() bool {
+	if 'e' != this.ch && 'E' != this.ch {
+		return false
+	}
+	next := this.peekChar()
+	if '+' == next || '-' == next {
+		return this.nextPosition+1 < len(this.input) && isDigit(this.input[this.nextPosition+1])
+	}
+	return isDigit(next)
+}
+
+// readString decodes a double-quoted literal, this.ch is left on the closing quote.
+// A bad escape sequence still consumes the literal up to its closing quote.
+func (this *Lexer) readString() (string, bool) {
+	var out bytes.Buffer
+	valid := true
+	for {
+		this.readChar()
+		switch this.ch {
+		case 0:
+			return out.String(), false
+		case '"':
+			return out.String(), valid
+		case '\\':
+			this.readChar()
+			if 0 == this.ch {
+				return out.String(), false
+			}
+			if !this.readEscape(&out) {
+				valid = false
+			}
+		default:
+			out.WriteByte(this.ch)
+		}
+	}
+}
+
+func (this *Lexer) readEscape(out *bytes.Buffer) bool {
+	switch this.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '"':
+		out.WriteByte('"')
+	case '\\':
+		out.WriteByte('\\')
+	case 'u':
+		return this.readUnicodeEscape(out)
+	default:
+		return false
+	}
+	return true
+}
+
+// readUnicodeEscape decodes \u{XXXX}, this.ch is on 'u' when called
+func (this *Lexer) readUnicodeEscape(out *bytes.Buffer) bool {
+	if '{' != this.peekChar() {
+		return false
+	}
+	this.readChar()
+	pos := this.nextPosition
+	for isHexDigit(this.peekChar()) {
+		this.readChar()
+	}
+	digits := this.input[pos:this.nextPosition]
+	if '}' != this.peekChar() || len(digits) < 1 || len(digits) > 6 {
+		return false
+	}
+	this.readChar()
+	code, err := strconv.ParseUint(digits, 16, 32)
+	if nil != err || !utf8.ValidRune(rune(code)) {
+		return false
+	}
+	out.WriteRune(rune(code))
+	return true
 }
 
 func (this *Lexer) readIdentifier() string {
@@ -131,6 +284,10 @@ func (this *Lexer) peekChar() byte {
 }
 
 func (this *Lexer) readChar() {
+	if '\n' == this.ch {
+		this.line++
+		this.lineStart = this.nextPosition
+	}
 	if this.nextPosition >= len(this.input) {
 		this.ch = 0
 	} else {
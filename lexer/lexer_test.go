@@ -28,6 +28,7 @@ func TestLexer_NextToken(t *testing.T) {
 	for {
 		break;
 	};
+	[1, 2][0];
 	`
 
 	tests := []struct {
@@ -124,6 +125,15 @@ func TestLexer_NextToken(t *testing.T) {
 		{"87", token.SEMICOLON, ";"},
 		{"88", token.RBRACE, "}"},
 		{"89", token.SEMICOLON, ";"},
+		{"90", token.LBRACKET, "["},
+		{"91", token.INT, "1"},
+		{"92", token.COMMA, ","},
+		{"93", token.INT, "2"},
+		{"94", token.RBRACKET, "]"},
+		{"95", token.LBRACKET, "["},
+		{"96", token.INT, "0"},
+		{"97", token.RBRACKET, "]"},
+		{"98", token.SEMICOLON, ";"},
 		{"EOF", token.EOF, ""},
 	}
 
@@ -138,3 +148,186 @@ func TestLexer_NextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestLexer_String(t *testing.T) {
+	tests := []struct {
+		input       string
+		wantType    token.TokenType
+		wantLiteral string
+	}{
+		{`"hello"`, token.STRING, "hello"},
+		{`""`, token.STRING, ""},
+		{`"a b"`, token.STRING, "a b"},
+		{`"line\nbreak"`, token.STRING, "line\nbreak"},
+		{`"tab\there"`, token.STRING, "tab\there"},
+		{`"say \"hi\""`, token.STRING, `say "hi"`},
+		{`"back\\slash"`, token.STRING, `back\slash`},
+		{`"\u{48}\u{49}"`, token.STRING, "HI"},
+		{`"\u{4e2d}"`, token.STRING, "中"},
+		{`"\u{1F600}"`, token.STRING, "\U0001F600"},
+		{`"unterminated`, token.ILLEGAL, `"unterminated`},
+		{`"bad \q escape"`, token.ILLEGAL, `"bad \q escape`},
+		{`"\u{}"`, token.ILLEGAL, `"\u{}`},
+		{`"\u{110000}"`, token.ILLEGAL, `"\u{110000}`},
+		{`"\u48"`, token.ILLEGAL, `"\u48`},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		tok := l.nextToken()
+		if tok.Type != tt.wantType {
+			t.Fatalf("[%v], Lexer.NextToken() type = %v, want %v", tt.input, tok.Type, tt.wantType)
+		}
+		if tok.Literal != tt.wantLiteral {
+			t.Fatalf("[%v], Lexer.NextToken() literal = %v, want %v", tt.input, tok.Literal, tt.wantLiteral)
+		}
+		if tok = l.nextToken(); !tok.Eof() {
+			t.Fatalf("[%v], expected EOF after string, got %v", tt.input, tok)
+		}
+	}
+}
+
+func TestLexer_Number(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []*token.Token
+	}{
+		{"5", []*token.Token{{Type: token.INT, Literal: "5"}}},
+		{"1.5", []*token.Token{{Type: token.FLOAT, Literal: "1.5"}}},
+		{".5", []*token.Token{{Type: token.FLOAT, Literal: ".5"}}},
+		{"1e-3", []*token.Token{{Type: token.FLOAT, Literal: "1e-3"}}},
+		{"2.5E+10", []*token.Token{{Type: token.FLOAT, Literal: "2.5E+10"}}},
+		{"3e8", []*token.Token{{Type: token.FLOAT, Literal: "3e8"}}},
+		{"1.", []*token.Token{{Type: token.INT, Literal: "1"}, {Type: token.ILLEGAL, Literal: "."}}},
+		{"1e", []*token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "e"}}},
+		{"1e+", []*token.Token{{Type: token.INT, Literal: "1"}, {Type: token.IDENT, Literal: "e"}, {Type: token.ADD, Literal: "+"}}},
+		{"-.5", []*token.Token{{Type: token.SUB, Literal: "-"}, {Type: token.FLOAT, Literal: ".5"}}},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, want := range append(tt.want, &token.Token{Type: token.EOF}) {
+			tok := l.nextToken()
+			if tok.Type != want.Type || tok.Literal != want.Literal {
+				t.Fatalf("[%v] token %v = %v, want %v", tt.input, i, tok, want)
+			}
+		}
+	}
+}
+
+func TestLexer_Hash(t *testing.T) {
+	input := `{"a": 1}; "a" in h; delete h["a"];`
+	want := []*token.Token{
+		{Type: token.LBRACE, Literal: "{"},
+		{Type: token.STRING, Literal: "a"},
+		{Type: token.COLON, Literal: ":"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.RBRACE, Literal: "}"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.STRING, Literal: "a"},
+		{Type: token.IN, Literal: "in"},
+		{Type: token.IDENT, Literal: "h"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.DELETE, Literal: "delete"},
+		{Type: token.IDENT, Literal: "h"},
+		{Type: token.LBRACKET, Literal: "["},
+		{Type: token.STRING, Literal: "a"},
+		{Type: token.RBRACKET, Literal: "]"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.EOF, Literal: ""},
+	}
+	l := New(input)
+	for i, tt := range want {
+		tok := l.nextToken()
+		if tok.Type != tt.Type || tok.Literal != tt.Literal {
+			t.Fatalf("token %v = %v, want %v", i, tok, tt)
+		}
+	}
+}
+
+func TestLexer_Loop(t *testing.T) {
+	input := `for (i; i < n; i = i + 1) { continue; }`
+	want := []*token.Token{
+		{Type: token.FOR, Literal: "for"},
+		{Type: token.LPAREN, Literal: "("},
+		{Type: token.IDENT, Literal: "i"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.IDENT, Literal: "i"},
+		{Type: token.LT, Literal: "<"},
+		{Type: token.IDENT, Literal: "n"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.IDENT, Literal: "i"},
+		{Type: token.ASSIGN, Literal: "="},
+		{Type: token.IDENT, Literal: "i"},
+		{Type: token.ADD, Literal: "+"},
+		{Type: token.INT, Literal: "1"},
+		{Type: token.RPAREN, Literal: ")"},
+		{Type: token.LBRACE, Literal: "{"},
+		{Type: token.CONTINUE, Literal: "continue"},
+		{Type: token.SEMICOLON, Literal: ";"},
+		{Type: token.RBRACE, Literal: "}"},
+		{Type: token.EOF, Literal: ""},
+	}
+	l := New(input)
+	for i, tt := range want {
+		tok := l.nextToken()
+		if tok.Type != tt.Type || tok.Literal != tt.Literal {
+			t.Fatalf("token %v = %v, want %v", i, tok, tt)
+		}
+	}
+}
+
+func TestLexer_Position(t *testing.T) {
+	input := "var a = 1;\n  \"é\" + a\n\tb"
+	want := []token.Position{
+		{File: "x.q", Line: 1, Col: 1, Offset: 0},
+		{File: "x.q", Line: 1, Col: 5, Offset: 4},
+		{File: "x.q", Line: 1, Col: 7, Offset: 6},
+		{File: "x.q", Line: 1, Col: 9, Offset: 8},
+		{File: "x.q", Line: 1, Col: 10, Offset: 9},
+		{File: "x.q", Line: 2, Col: 3, Offset: 13},
+		{File: "x.q", Line: 2, Col: 7, Offset: 18},
+		{File: "x.q", Line: 2, Col: 9, Offset: 20},
+		{File: "x.q", Line: 3, Col: 2, Offset: 23},
+		{File: "x.q", Line: 3, Col: 3, Offset: 24},
+	}
+	l := NewFile("x.q", input)
+	for i, tt := range want {
+		tok := l.nextToken()
+		if tok.Pos != tt {
+			t.Fatalf("token %v (%v) at %+v, want %+v", i, tok.Literal, tok.Pos, tt)
+		}
+	}
+	if s := want[5].String(); s != "x.q:2:3" {
+		t.Errorf("Position.String() = %v, want x.q:2:3", s)
+	}
+	if s := (token.Position{Line: 4, Col: 2}).String(); s != "4:2" {
+		t.Errorf("Position.String() = %v, want 4:2", s)
+	}
+}
+
+func TestLexer_Shebang(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []*token.Token
+	}{
+		{"#!/usr/bin/env Q\nx;", []*token.Token{
+			{Type: token.IDENT, Literal: "x", Pos: token.Position{Line: 2, Col: 1, Offset: 17}},
+			{Type: token.SEMICOLON, Literal: ";", Pos: token.Position{Line: 2, Col: 2, Offset: 18}},
+		}},
+		{"#!/usr/bin/env Q", []*token.Token{
+			{Type: token.EOF, Literal: "", Pos: token.Position{Line: 1, Col: 17, Offset: 16}},
+		}},
+		{"x #!", []*token.Token{
+			{Type: token.IDENT, Literal: "x", Pos: token.Position{Line: 1, Col: 1, Offset: 0}},
+			{Type: token.ILLEGAL, Literal: "#", Pos: token.Position{Line: 1, Col: 3, Offset: 2}},
+		}},
+	}
+	for _, tt := range tests {
+		l := New(tt.input)
+		for i, want := range tt.want {
+			tok := l.nextToken()
+			if *tok != *want {
+				t.Fatalf("[%q] token %v = %+v, want %+v", tt.input, i, tok, want)
+			}
+		}
+	}
+}
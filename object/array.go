@@ -0,0 +1,197 @@
+package object
+
+import (
+	"Q/token"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Array : implement Object, Indexable, Container, Iterable
+type Array struct {
+	Elements []Object
+}
+
+func (this *Array) Type() ObjectType {
+	return ObjectTypeArray
+}
+
+func (this *Array) Inspect() string {
+	return this.inspect(map[Object]bool{})
+}
+
+// inspect prints an array containing itself as [...] where it recurs
+func (this *Array) inspect(visiting map[Object]bool) string {
+	if visiting[this] {
+		return "[...]"
+	}
+	visiting[this] = true
+	defer delete(visiting, this)
+	var out bytes.Buffer
+	elements := []string{}
+	for _, e := range this.Elements {
+		elements = append(elements, inspect(e, visiting))
+	}
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+	return out.String()
+}
+
+func (this *Array) Opposite() (Object, error) {
+	return nil, fmt.Errorf("Array.Opposite -> unsupported")
+}
+
+func (this *Array) Not() (Object, error) {
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *Array) Calc(op *token.Token, right Object) (Object, error) {
+	switch r := right.(type) {
+	case *Array:
+		return this.calcArray(op, r)
+	case *Null:
+		return infixWithNull(op, this, "Array.Calc")
+	default:
+		return calcMixed(op, this, right, "Array.Calc")
+	}
+}
+
+func (this *Array) Call(args []Object, insideLoop bool) (Object, error) {
+	return nil, fmt.Errorf("Array.Call -> unsupported")
+}
+
+func (this *Array) True() bool {
+	return len(this.Elements) > 0
+}
+
+func (this *Array) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *Array) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *Array) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Array) Index(key Object) (Object, error) {
+	idx, err := this.offset(key, "Array.Index")
+	if nil != err {
+		return nil, err
+	}
+	return this.Elements[idx], nil
+}
+
+func (this *Array) SetIndex(key Object, val Object) error {
+	idx, err := this.offset(key, "Array.SetIndex")
+	if nil != err {
+		return err
+	}
+	this.Elements[idx] = val
+	return nil
+}
+
+// offset resolves key to a position in Elements, negative keys count from the end
+func (this *Array) offset(key Object, method string) (int, error) {
+	i, ok := key.(*Integer)
+	if !ok {
+		return 0, fmt.Errorf("%v -> index must be integer, got %v", method, ToString(key.Type()))
+	}
+	sz := int64(len(this.Elements))
+	idx := i.Value
+	if idx < 0 {
+		idx += sz
+	}
+	if idx < 0 || idx >= sz {
+		return 0, fmt.Errorf("%v -> index %v out of range for array of length %v", method, i.Value, sz)
+	}
+	return int(idx), nil
+}
+
+func (this *Array) Contains(item Object) (bool, error) {
+	eq := &token.Token{Type: token.EQ, Literal: "=="}
+	for _, e := range this.Elements {
+		v, err := item.Calc(eq, e)
+		if nil != err {
+			return false, fmt.Errorf("Array.Contains | %v", err)
+		}
+		if v.True() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Iter yields (index, element), the length must not change while iterating
+func (this *Array) Iter() Iterator {
+	return &arrayIterator{arr: this, size: len(this.Elements)}
+}
+
+func (this *Array) calcArray(op *token.Token, right *Array) (Object, error) {
+	switch op.Type {
+	case token.ADD:
+		elements := make([]Object, 0, len(this.Elements)+len(right.Elements))
+		elements = append(elements, this.Elements...)
+		elements = append(elements, right.Elements...)
+		return &Array{Elements: elements}, nil
+	case token.EQ:
+		equal, err := this.equals(right, map[pair]bool{})
+		if nil != err {
+			return nil, err
+		}
+		return ToBoolean(equal), nil
+	case token.NEQ:
+		equal, err := this.equals(right, map[pair]bool{})
+		if nil != err {
+			return nil, err
+		}
+		return ToBoolean(!equal), nil
+	default:
+		return calcMixed(op, this, right, "Array.calcArray")
+	}
+}
+
+// equals holds the pairs of containers being compared in seen, a pair met again is
+// taken as equal so that arrays containing themselves compare
+func (this *Array) equals(right *Array, seen map[pair]bool) (bool, error) {
+	if this == right || seen[pair{this, right}] {
+		return true, nil
+	}
+	if len(this.Elements) != len(right.Elements) {
+		return false, nil
+	}
+	seen[pair{this, right}] = true
+	for i, e := range this.Elements {
+		equal, err := equals(e, right.Elements[i], seen)
+		if nil != err {
+			return false, fmt.Errorf("Array.equals | %v", err)
+		}
+		if !equal {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (this *Array) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return calcMixed(op, left, this, "Array.calcInteger")
+}
+
+func (this *Array) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return calcMixed(op, left, this, "Array.calcBoolean")
+}
+
+func (this *Array) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, "Array.calcNull")
+}
+
+func (this *Array) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Array.calcString")
+}
+
+func (this *Array) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return calcMixed(op, left, this, "Array.calcFloat")
+}
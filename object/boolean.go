@@ -5,7 +5,7 @@ import (
 	"fmt"
 )
 
-// Boolean : implement Object
+// Boolean : implement Object, Hashable
 type Boolean struct {
 	Value bool
 }
@@ -54,6 +54,14 @@ func (this *Boolean) Break() (bool, int) {
 	return false, 0
 }
 
+func (this *Boolean) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Boolean) HashKey() HashKey {
+	return HashKey{Type: this.Type(), Value: toInt64(this.Value)}
+}
+
 func (this *Boolean) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	right := toInteger(this.Value)
 	return right.calcInteger(op, left)
@@ -68,8 +76,14 @@ func (this *Boolean) calcBoolean(op *token.Token, left *Boolean) (Object, error)
 	case token.MUL:
 		return &Integer{Value: toInt64(left.Value) * toInt64(this.Value)}, nil
 	case token.DIV:
+		if !this.Value {
+			return nil, fmt.Errorf("Boolean.calcBoolean -> division by zero")
+		}
 		return &Integer{Value: toInt64(left.Value) / toInt64(this.Value)}, nil
 	case token.MOD:
+		if !this.Value {
+			return nil, fmt.Errorf("Boolean.calcBoolean -> division by zero")
+		}
 		return &Integer{Value: toInt64(left.Value) % toInt64(this.Value)}, nil
 	case token.LT:
 		return ToBoolean(toInt64(left.Value) < toInt64(this.Value)), nil
@@ -95,3 +109,11 @@ func (this *Boolean) calcBoolean(op *token.Token, left *Boolean) (Object, error)
 func (this *Boolean) calcNull(op *token.Token, left *Null) (Object, error) {
 	return infixNull(op, this, "Boolean.calcNull")
 }
+
+func (this *Boolean) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Boolean.calcString")
+}
+
+func (this *Boolean) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return toFloat(toInt64(this.Value)).calcFloat(op, left)
+}
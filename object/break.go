@@ -5,13 +5,15 @@ import (
 	"fmt"
 )
 
-func NewBreak() Object {
-	return &BreakObject{count: 0}
+// NewBreak returns the signal of `break` or `break label`, label is empty for the nearest loop
+func NewBreak(label string) Object {
+	return &BreakObject{count: 0, label: label}
 }
 
 // BreakObject : implement Object
 type BreakObject struct {
 	count int
+	label string
 }
 
 func (this *BreakObject) Type() ObjectType {
@@ -51,6 +53,14 @@ func (this *BreakObject) Break() (bool, int) {
 	return true, this.count
 }
 
+func (this *BreakObject) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *BreakObject) Label() string {
+	return this.label
+}
+
 func (this *BreakObject) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	return nil, fmt.Errorf("BreakObject.calcInteger -> unsupported op %v(%v)", op.Literal, op.Type)
 }
@@ -62,3 +72,11 @@ func (this *BreakObject) calcBoolean(op *token.Token, left *Boolean) (Object, er
 func (this *BreakObject) calcNull(op *token.Token, left *Null) (Object, error) {
 	return nil, fmt.Errorf("BreakObject.calcNull -> unsupported op %v(%v)", op.Literal, op.Type)
 }
+
+func (this *BreakObject) calcString(op *token.Token, left *String) (Object, error) {
+	return nil, fmt.Errorf("BreakObject.calcString -> unsupported op %v(%v)", op.Literal, op.Type)
+}
+
+func (this *BreakObject) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return nil, fmt.Errorf("BreakObject.calcFloat -> unsupported op %v(%v)", op.Literal, op.Type)
+}
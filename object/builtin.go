@@ -0,0 +1,109 @@
+package object
+
+import (
+	"Q/token"
+	"fmt"
+)
+
+type BuiltinFunction func(args []Object) (Object, error)
+
+// Builtin : implement Object
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+func (this *Builtin) Type() ObjectType {
+	return ObjectTypeBuiltin
+}
+
+func (this *Builtin) Inspect() string {
+	return fmt.Sprintf("builtin %v", this.Name)
+}
+
+func (this *Builtin) Not() (Object, error) {
+	return False, nil
+}
+
+func (this *Builtin) Opposite() (Object, error) {
+	return nil, fmt.Errorf("Builtin.Opposite -> unsupported")
+}
+
+func (this *Builtin) Calc(op *token.Token, right Object) (Object, error) {
+	switch r := right.(type) {
+	case *Builtin:
+		return this.calcBuiltin(op, r)
+	case *Null:
+		return infixWithNull(op, this, "Builtin.Calc")
+	default:
+		return calcMixed(op, this, right, "Builtin.Calc")
+	}
+}
+
+func (this *Builtin) Call(args []Object, insideLoop bool) (Object, error) {
+	rc, err := this.Fn(args)
+	if nil != err {
+		return nil, fmt.Errorf("Builtin.Call -> %v: %w", this.Name, err)
+	}
+	return rc, nil
+}
+
+func (this *Builtin) True() bool {
+	return true
+}
+
+func (this *Builtin) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *Builtin) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *Builtin) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Builtin) calcBuiltin(op *token.Token, right *Builtin) (Object, error) {
+	switch op.Type {
+	case token.EQ:
+		return ToBoolean(this == right), nil
+	case token.NEQ:
+		return ToBoolean(this != right), nil
+	default:
+		return calcMixed(op, this, right, "Builtin.calcBuiltin")
+	}
+}
+
+func (this *Builtin) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return calcMixed(op, left, this, "Builtin.calcInteger")
+}
+
+func (this *Builtin) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return calcMixed(op, left, this, "Builtin.calcBoolean")
+}
+
+func (this *Builtin) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, "Builtin.calcNull")
+}
+
+func (this *Builtin) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Builtin.calcString")
+}
+
+func (this *Builtin) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return calcMixed(op, left, this, "Builtin.calcFloat")
+}
+
+// CheckArgs reports a wrong argument count the same way Function.Call does
+func CheckArgs(args []Object, required int) error {
+	if len(args) != required {
+		return fmt.Errorf("%v args provided, but %v args required", len(args), required)
+	}
+	return nil
+}
+
+// ArgError reports an argument (counting from 1) of an unexpected type
+func ArgError(idx int, want string, got Object) error {
+	return fmt.Errorf("argument %v must be %v, got %v", idx, want, ToString(got.Type()))
+}
@@ -0,0 +1,290 @@
+package object
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Builtins is a registry of native functions, it is consulted after Env.Get fails
+type Builtins map[string]*Builtin
+
+// defaultBuiltins is the registry of the Envs without their own, it is never modified so
+// that the Envs may be used by concurrent evaluations
+var defaultBuiltins = NewBuiltins(os.Stdout)
+
+// NewBuiltins returns the core builtins, print and println write to out,
+// eprint and eprintln to os.Stderr
+func NewBuiltins(out io.Writer) Builtins {
+	return NewBuiltinsTo(out, os.Stderr)
+}
+
+// NewBuiltinsTo returns the core builtins, print and println write to out,
+// eprint and eprintln to errOut
+func NewBuiltinsTo(out io.Writer, errOut io.Writer) Builtins {
+	b := Builtins{}
+	b.Register("len", builtinLen)
+	b.Register("print", func(args []Object) (Object, error) {
+		return builtinPrint(out, args, "")
+	})
+	b.Register("println", func(args []Object) (Object, error) {
+		return builtinPrint(out, args, "\n")
+	})
+	b.Register("eprint", func(args []Object) (Object, error) {
+		return builtinPrint(errOut, args, "")
+	})
+	b.Register("eprintln", func(args []Object) (Object, error) {
+		return builtinPrint(errOut, args, "\n")
+	})
+	b.Register("type", builtinType)
+	b.Register("int", builtinInt)
+	b.Register("float", builtinFloat)
+	b.Register("bool", builtinBool)
+	b.Register("str", builtinStr)
+	b.Register("push", builtinPush)
+	b.Register("pop", builtinPop)
+	b.Register("keys", builtinKeys)
+	b.Register("values", builtinValues)
+	b.Register("range", builtinRange)
+	b.Register("exit", builtinExit)
+	return b
+}
+
+func (this Builtins) Register(name string, fn BuiltinFunction) {
+	this[name] = &Builtin{Name: name, Fn: fn}
+}
+
+// copy returns a registry which may be modified without affecting this one
+func (this Builtins) copy() Builtins {
+	b := make(Builtins, len(this))
+	for name, builtin := range this {
+		b[name] = builtin
+	}
+	return b
+}
+
+func (this Builtins) Lookup(name string) (*Builtin, bool) {
+	b, ok := this[name]
+	return b, ok
+}
+
+// ToText is the text print and str use: strings are not quoted
+func ToText(obj Object) string {
+	if s, ok := obj.(*String); ok {
+		return s.Value
+	}
+	return obj.Inspect()
+}
+
+func builtinLen(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case *String:
+		return &Integer{Value: int64(utf8.RuneCountInString(v.Value))}, nil
+	case *Array:
+		return &Integer{Value: int64(len(v.Elements))}, nil
+	case *Hash:
+		return &Integer{Value: int64(v.Len())}, nil
+	case *Range:
+		return &Integer{Value: v.Len()}, nil
+	default:
+		return nil, ArgError(1, "string, array, hash or range", args[0])
+	}
+}
+
+func builtinPrint(out io.Writer, args []Object, end string) (Object, error) {
+	var buf bytes.Buffer
+	for i, arg := range args {
+		if i > 0 {
+			buf.WriteString(" ")
+		}
+		buf.WriteString(ToText(arg))
+	}
+	buf.WriteString(end)
+	if _, err := out.Write(buf.Bytes()); nil != err {
+		return nil, err
+	}
+	return Nil, nil
+}
+
+func builtinType(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	return &String{Value: ToString(args[0].Type())}, nil
+}
+
+func builtinInt(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case *Integer:
+		return v, nil
+	case *Float:
+		if math.IsNaN(v.Value) {
+			return nil, fmt.Errorf("cannot convert %v to integer", v.Inspect())
+		}
+		if v.Value < math.MinInt64 || v.Value >= -math.MinInt64 {
+			return nil, fmt.Errorf("cannot convert %v to integer, out of range", v.Inspect())
+		}
+		return &Integer{Value: int64(v.Value)}, nil
+	case *Boolean:
+		return toInteger(v.Value), nil
+	case *String:
+		i, err := strconv.ParseInt(strings.TrimSpace(v.Value), 10, 64)
+		if nil != err {
+			return nil, fmt.Errorf("cannot convert %v to integer", v.Inspect())
+		}
+		return &Integer{Value: i}, nil
+	default:
+		return nil, ArgError(1, "integer, float, boolean or string", args[0])
+	}
+}
+
+func builtinFloat(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	switch v := args[0].(type) {
+	case *Float:
+		return v, nil
+	case *Integer:
+		return toFloat(v.Value), nil
+	case *Boolean:
+		return toFloat(toInt64(v.Value)), nil
+	case *String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.Value), 64)
+		if nil != err {
+			return nil, fmt.Errorf("cannot convert %v to float", v.Inspect())
+		}
+		return &Float{Value: f}, nil
+	default:
+		return nil, ArgError(1, "integer, float, boolean or string", args[0])
+	}
+}
+
+func builtinBool(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	return ToBoolean(args[0].True()), nil
+}
+
+func builtinStr(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	return &String{Value: ToText(args[0])}, nil
+}
+
+// builtinPush appends to the array in place and returns it
+func builtinPush(args []Object) (Object, error) {
+	if err := CheckArgs(args, 2); nil != err {
+		return nil, err
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, ArgError(1, "array", args[0])
+	}
+	arr.Elements = append(arr.Elements, args[1])
+	return arr, nil
+}
+
+// builtinPop removes and returns the last element
+func builtinPop(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	arr, ok := args[0].(*Array)
+	if !ok {
+		return nil, ArgError(1, "array", args[0])
+	}
+	sz := len(arr.Elements)
+	if 0 == sz {
+		return nil, fmt.Errorf("pop from empty array")
+	}
+	last := arr.Elements[sz-1]
+	arr.Elements = arr.Elements[:sz-1]
+	return last, nil
+}
+
+func builtinKeys(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return nil, ArgError(1, "hash", args[0])
+	}
+	keys := []Object{}
+	for _, pair := range hash.Pairs() {
+		keys = append(keys, pair.Key)
+	}
+	return &Array{Elements: keys}, nil
+}
+
+func builtinValues(args []Object) (Object, error) {
+	if err := CheckArgs(args, 1); nil != err {
+		return nil, err
+	}
+	hash, ok := args[0].(*Hash)
+	if !ok {
+		return nil, ArgError(1, "hash", args[0])
+	}
+	values := []Object{}
+	for _, pair := range hash.Pairs() {
+		values = append(values, pair.Value)
+	}
+	return &Array{Elements: values}, nil
+}
+
+// builtinRange accepts (stop), (start, stop) or (start, stop, step)
+func builtinRange(args []Object) (Object, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("%v args provided, but 1 to 3 args required", len(args))
+	}
+	bounds := []int64{0, 0, 1}
+	for i, arg := range args {
+		v, ok := arg.(*Integer)
+		if !ok {
+			return nil, ArgError(i+1, "integer", arg)
+		}
+		bounds[i] = v.Value
+	}
+	if 1 == len(args) {
+		bounds[0], bounds[1] = 0, bounds[0]
+	}
+	return NewRange(bounds[0], bounds[1], bounds[2])
+}
+
+// ExitError is returned by the exit builtin, it unwinds the evaluation up to the host
+type ExitError struct {
+	Code int
+}
+
+func (this *ExitError) Error() string {
+	return fmt.Sprintf("exit status %v", this.Code)
+}
+
+// builtinExit accepts () or (code)
+func builtinExit(args []Object) (Object, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("%v args provided, but 0 or 1 args required", len(args))
+	}
+	if 0 == len(args) {
+		return nil, &ExitError{Code: 0}
+	}
+	code, ok := args[0].(*Integer)
+	if !ok {
+		return nil, ArgError(1, "integer", args[0])
+	}
+	return nil, &ExitError{Code: int(code.Value)}
+}
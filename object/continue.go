@@ -0,0 +1,82 @@
+package object
+
+import (
+	"Q/token"
+	"fmt"
+)
+
+// NewContinue returns the signal of `continue` or `continue label`, label is empty for the nearest loop
+func NewContinue(label string) Object {
+	return &ContinueObject{count: 0, label: label}
+}
+
+// ContinueObject : implement Object
+type ContinueObject struct {
+	count int
+	label string
+}
+
+func (this *ContinueObject) Type() ObjectType {
+	return ObjectTypeContinueObject
+}
+
+func (this *ContinueObject) Inspect() string {
+	return ToString(ObjectTypeContinueObject)
+}
+
+func (this *ContinueObject) Opposite() (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.Opposite -> unsupported")
+}
+
+func (this *ContinueObject) Not() (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.Not -> unsupported")
+}
+
+func (this *ContinueObject) Calc(op *token.Token, right Object) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.Calc -> unsupported")
+}
+
+func (this *ContinueObject) Call(args []Object, insideLoop bool) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.Call -> unsupported")
+}
+
+func (this *ContinueObject) True() bool {
+	return false
+}
+
+func (this *ContinueObject) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *ContinueObject) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *ContinueObject) Continue() (bool, int) {
+	this.count++
+	return true, this.count
+}
+
+func (this *ContinueObject) Label() string {
+	return this.label
+}
+
+func (this *ContinueObject) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.calcInteger -> unsupported op %v(%v)", op.Literal, op.Type)
+}
+
+func (this *ContinueObject) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.calcBoolean -> unsupported op %v(%v)", op.Literal, op.Type)
+}
+
+func (this *ContinueObject) calcNull(op *token.Token, left *Null) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.calcNull -> unsupported op %v(%v)", op.Literal, op.Type)
+}
+
+func (this *ContinueObject) calcString(op *token.Token, left *String) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.calcString -> unsupported op %v(%v)", op.Literal, op.Type)
+}
+
+func (this *ContinueObject) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return nil, fmt.Errorf("ContinueObject.calcFloat -> unsupported op %v(%v)", op.Literal, op.Type)
+}
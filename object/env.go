@@ -1,12 +1,25 @@
 package object
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
 
 type Env struct {
-	outer *Env
-	m     map[string]Object
+	outer    *Env
+	m        map[string]Object
+	builtins Builtins        // only used on the outermost Env
+	ctx      context.Context // only used on the outermost Env
+	usage    *usage          // only used on the outermost Env
+	source   string          // only used on the outermost Env
+	ended    map[string]bool // the names declared by the inner blocks which have ended
 }
 
+// ErrInterrupted is returned by an evaluation stopped by the cancellation of its context
+var ErrInterrupted = errors.New("interrupted")
+
 func NewEnv() *Env {
 	return &Env{m: map[string]Object{}}
 }
@@ -20,36 +33,143 @@ func (this *Env) Get(name string) (Object, bool) {
 	return v, ok
 }
 
+// SetBuiltins replaces the default registry for the Env and the Envs enclosed by it
+func (this *Env) SetBuiltins(b Builtins) {
+	this.builtins = b
+}
+
+// RegisterBuiltin adds fn to the registry of the outermost Env, a copy of the default one
+// when SetBuiltins was not called, the other Envs never see it
+func (this *Env) RegisterBuiltin(name string, fn BuiltinFunction) {
+	root := this.root()
+	if nil == root.builtins {
+		root.builtins = defaultBuiltins.copy()
+	}
+	root.builtins.Register(name, fn)
+}
+
+func (this *Env) Builtin(name string) (*Builtin, bool) {
+	if nil != this.outer {
+		return this.outer.Builtin(name)
+	}
+	if nil != this.builtins {
+		return this.builtins.Lookup(name)
+	}
+	return defaultBuiltins.Lookup(name)
+}
+
+// SetContext makes the evaluations in the outermost Env and the Envs enclosed by it stop
+// once ctx is done, see Err
+func (this *Env) SetContext(ctx context.Context) {
+	this.root().ctx = ctx
+}
+
+// Context returns the context set by SetContext, nil when there is none
+func (this *Env) Context() context.Context {
+	return this.root().ctx
+}
+
+// SetSource sets the text of the program evaluated in the outermost Env, the functions
+// it defines keep it to render their errors
+func (this *Env) SetSource(source string) {
+	this.root().source = source
+}
+
+// Source returns the text set by SetSource
+func (this *Env) Source() string {
+	return this.root().source
+}
+
+// Err returns ErrInterrupted when the context of the outermost Env is cancelled and a
+// LimitError for ErrDeadline when its deadline passed
+func (this *Env) Err() error {
+	if nil != this.outer {
+		return this.outer.Err()
+	}
+	if nil == this.ctx {
+		return nil
+	}
+	select {
+	case <-this.ctx.Done():
+		if context.DeadlineExceeded == this.ctx.Err() {
+			return &LimitError{Err: ErrDeadline}
+		}
+		return ErrInterrupted
+	default:
+		return nil
+	}
+}
+
 func (this *Env) Set(name string, val Object) Object {
 	this.m[name] = val
 	return val
 }
 
+// Assign updates the binding of name in the nearest Env defining it,
+// it never creates a new binding
 func (this *Env) Assign(name string, val Object) error {
-	if _, ok := this.m[name]; ok {
-		this.m[name] = val
-	} else {
-		return fmt.Errorf("Env.Assign -> `%v` undefined", name)
-		// TODO
-		// if nil == this.outer {
-		// 	return fmt.Errorf("Env.Assign -> `%v` undefined", name)
-		// } else {
-		// 	return this.outer.Assign(name, val)
-		// }
+	for env := this; nil != env; env = env.outer {
+		if _, ok := env.m[name]; ok {
+			env.m[name] = val
+			return nil
+		}
+	}
+	if this.Ended(name) {
+		return fmt.Errorf("Env.Assign -> `%v` undefined, %v", name, EndedHint)
+	}
+	return fmt.Errorf("Env.Assign -> `%v` undefined", name)
+}
+
+// EndedHint explains a lookup failing only because blocks have their own scope
+const EndedHint = "it was declared inside a block which has ended (if/for/func blocks have their own scope)"
+
+// Leave is called when the block owning this Env ends, the outer Env keeps the names it
+// declared for Ended
+func (this *Env) Leave() {
+	if nil == this.outer || 0 == len(this.m) && 0 == len(this.ended) {
+		return
+	}
+	if nil == this.outer.ended {
+		this.outer.ended = map[string]bool{}
+	}
+	for name := range this.m {
+		this.outer.ended[name] = true
+	}
+	for name := range this.ended {
+		this.outer.ended[name] = true
 	}
-	return nil
 }
 
-func newEnclosedEnv(outer *Env) *Env {
+// Ended reports whether name was declared by blocks which have ended, Get failing for it
+func (this *Env) Ended(name string) bool {
+	for env := this; nil != env; env = env.outer {
+		if env.ended[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func NewEnclosedEnv(outer *Env) *Env {
 	env := NewEnv()
 	env.outer = outer
 	return env
 }
 
 func newFunctionEnv(outer *Env, args []string, values []Object) *Env {
-	env := newEnclosedEnv(outer)
+	env := NewEnclosedEnv(outer)
 	for i, name := range args {
 		env.Set(name, values[i])
 	}
 	return env
 }
+
+// Names returns the names bound in this Env, without the outer ones, sorted
+func (this *Env) Names() []string {
+	names := make([]string, 0, len(this.m))
+	for name := range this.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
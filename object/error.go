@@ -0,0 +1,24 @@
+package object
+
+import "fmt"
+
+// wrapError is the error of Wrap
+type wrapError struct {
+	msg string
+	err error
+}
+
+// Wrap returns an error reading "msg | err" which unwraps to err. Unlike fmt.Errorf with %w
+// err is only formatted when the result is, an error unwinding a deep recursion is wrapped
+// at every level.
+func Wrap(err error, format string, a ...interface{}) error {
+	return &wrapError{msg: fmt.Sprintf(format, a...), err: err}
+}
+
+func (this *wrapError) Error() string {
+	return fmt.Sprintf("%v | %v", this.msg, this.err)
+}
+
+func (this *wrapError) Unwrap() error {
+	return this.err
+}
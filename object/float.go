@@ -0,0 +1,123 @@
+package object
+
+import (
+	"Q/token"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Float : implement Object
+type Float struct {
+	Value float64
+}
+
+func (this *Float) Type() ObjectType {
+	return ObjectTypeFloat
+}
+
+func (this *Float) Inspect() string {
+	s := strconv.FormatFloat(this.Value, 'g', -1, 64)
+	if math.IsInf(this.Value, 0) || math.IsNaN(this.Value) {
+		return s
+	}
+	// keep a fraction or an exponent so the output is lexed as a float again
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+func (this *Float) Opposite() (Object, error) {
+	return &Float{Value: -this.Value}, nil
+}
+
+func (this *Float) Not() (Object, error) {
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *Float) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcFloat(op, this)
+}
+
+func (this *Float) Call(args []Object, insideLoop bool) (Object, error) {
+	return nil, fmt.Errorf("Float.Call -> unsupported")
+}
+
+func (this *Float) True() bool {
+	return 0 != this.Value
+}
+
+func (this *Float) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *Float) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *Float) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Float) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return this.calcFloat(op, toFloat(left.Value))
+}
+
+func (this *Float) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return this.calcFloat(op, toFloat(toInt64(left.Value)))
+}
+
+func (this *Float) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, "Float.calcNull")
+}
+
+func (this *Float) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Float.calcString")
+}
+
+func (this *Float) calcFloat(op *token.Token, left *Float) (Object, error) {
+	switch op.Type {
+	case token.ADD:
+		return &Float{Value: left.Value + this.Value}, nil
+	case token.SUB:
+		return &Float{Value: left.Value - this.Value}, nil
+	case token.MUL:
+		return &Float{Value: left.Value * this.Value}, nil
+	case token.DIV:
+		if 0 == this.Value {
+			return nil, fmt.Errorf("Float.calcFloat -> division by zero")
+		}
+		return &Float{Value: left.Value / this.Value}, nil
+	case token.MOD:
+		if 0 == this.Value {
+			return nil, fmt.Errorf("Float.calcFloat -> division by zero")
+		}
+		return &Float{Value: math.Mod(left.Value, this.Value)}, nil
+	case token.LT:
+		return ToBoolean(left.Value < this.Value), nil
+	case token.LEQ:
+		return ToBoolean(left.Value <= this.Value), nil
+	case token.GT:
+		return ToBoolean(left.Value > this.Value), nil
+	case token.GEQ:
+		return ToBoolean(left.Value >= this.Value), nil
+	case token.EQ:
+		return ToBoolean(left.Value == this.Value), nil
+	case token.NEQ:
+		return ToBoolean(left.Value != this.Value), nil
+	case token.AND:
+		if !left.True() {
+			return left, nil
+		}
+		return this, nil
+	case token.OR:
+		if left.True() {
+			return left, nil
+		}
+		return this, nil
+	default:
+		return nil, fmt.Errorf("Float.calcFloat -> unsupported op %v(%v)", op.Literal, op.Type)
+	}
+}
@@ -12,6 +12,8 @@ type Function struct {
 	Args     []string
 	EvalBody func(env *Env, insideLoop bool) (Object, error)
 	Env      *Env
+	Compiled interface{} // the closure running the function when the vm made it, nil for the tree-walker
+	Source   string      // the text of the program defining the function, see Env.SetSource
 }
 
 func (this *Function) Type() ObjectType {
@@ -39,10 +41,15 @@ func (this *Function) Call(args []Object, insideLoop bool) (Object, error) {
 	if len(args) != len(this.Args) {
 		return nil, fmt.Errorf("Function.Call -> %v args provided, but %v args required", len(args), len(this.Args))
 	}
+	if err := this.Env.EnterCall(); nil != err {
+		return nil, err
+	}
+	defer this.Env.LeaveCall()
 	innerEnv := newFunctionEnv(this.Env, this.Args, args)
-	evaluated, err := this.EvalBody(innerEnv, insideLoop)
+	// a break or continue never leaves the function, even called inside a loop
+	evaluated, err := this.EvalBody(innerEnv, false)
 	if nil != err {
-		return nil, fmt.Errorf("Function.Call | %v", err)
+		return nil, Wrap(err, "Function.Call")
 	}
 	if isReturn, rc := evaluated.Return(); isReturn {
 		return rc, nil
@@ -62,6 +69,10 @@ func (this *Function) Break() (bool, int) {
 	return false, 0
 }
 
+func (this *Function) Continue() (bool, int) {
+	return false, 0
+}
+
 func (this *Function) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	// TODO
 	return nil, fmt.Errorf("Function.calcInteger -> unsupported")
@@ -76,3 +87,13 @@ func (this *Function) calcNull(op *token.Token, left *Null) (Object, error) {
 	// TODO
 	return nil, fmt.Errorf("Function.calcNull -> unsupported")
 }
+
+func (this *Function) calcString(op *token.Token, left *String) (Object, error) {
+	// TODO
+	return nil, fmt.Errorf("Function.calcString -> unsupported")
+}
+
+func (this *Function) calcFloat(op *token.Token, left *Float) (Object, error) {
+	// TODO
+	return nil, fmt.Errorf("Function.calcFloat -> unsupported")
+}
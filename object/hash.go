@@ -0,0 +1,257 @@
+package object
+
+import (
+	"Q/token"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// HashKey identifies a Hashable object inside a Hash, values of different types never share a key
+type HashKey struct {
+	Type  ObjectType
+	Value int64
+	Text  string
+}
+
+// HashPair keeps the original key object for Inspect and iteration
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+func NewHash() *Hash {
+	return &Hash{pairs: map[HashKey]*HashPair{}}
+}
+
+// Hash : implement Object, Indexable, Container, Iterable
+type Hash struct {
+	pairs   map[HashKey]*HashPair
+	order   []HashKey // insertion order
+	version int       // changed whenever a key is added or deleted
+}
+
+func (this *Hash) Type() ObjectType {
+	return ObjectTypeHash
+}
+
+func (this *Hash) Inspect() string {
+	return this.inspect(map[Object]bool{})
+}
+
+// inspect prints a hash containing itself as {...} where it recurs
+func (this *Hash) inspect(visiting map[Object]bool) string {
+	if visiting[this] {
+		return "{...}"
+	}
+	visiting[this] = true
+	defer delete(visiting, this)
+	var out bytes.Buffer
+	pairs := []string{}
+	for _, pair := range this.Pairs() {
+		pairs = append(pairs, fmt.Sprintf("%v: %v", pair.Key.Inspect(), inspect(pair.Value, visiting)))
+	}
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+	return out.String()
+}
+
+func (this *Hash) Opposite() (Object, error) {
+	return nil, fmt.Errorf("Hash.Opposite -> unsupported")
+}
+
+func (this *Hash) Not() (Object, error) {
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *Hash) Calc(op *token.Token, right Object) (Object, error) {
+	switch r := right.(type) {
+	case *Hash:
+		return this.calcHash(op, r)
+	case *Null:
+		return infixWithNull(op, this, "Hash.Calc")
+	default:
+		return calcMixed(op, this, right, "Hash.Calc")
+	}
+}
+
+func (this *Hash) Call(args []Object, insideLoop bool) (Object, error) {
+	return nil, fmt.Errorf("Hash.Call -> unsupported")
+}
+
+func (this *Hash) True() bool {
+	return this.Len() > 0
+}
+
+func (this *Hash) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *Hash) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *Hash) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Hash) Len() int {
+	return len(this.order)
+}
+
+// Pairs returns the pairs in insertion order
+func (this *Hash) Pairs() []*HashPair {
+	pairs := make([]*HashPair, 0, len(this.order))
+	for _, k := range this.order {
+		pairs = append(pairs, this.pairs[k])
+	}
+	return pairs
+}
+
+// Get returns the value of key, the bool is false when key is absent
+func (this *Hash) Get(key Object) (Object, bool, error) {
+	k, err := hashKeyOf(key, "Hash.Get")
+	if nil != err {
+		return nil, false, err
+	}
+	pair, ok := this.pairs[k]
+	if !ok {
+		return nil, false, nil
+	}
+	return pair.Value, true, nil
+}
+
+// Set overwrites the value of an existing key in place, new keys are appended to the order
+func (this *Hash) Set(key Object, val Object) error {
+	k, err := hashKeyOf(key, "Hash.Set")
+	if nil != err {
+		return err
+	}
+	if pair, ok := this.pairs[k]; ok {
+		pair.Value = val
+		return nil
+	}
+	this.pairs[k] = &HashPair{Key: key, Value: val}
+	this.order = append(this.order, k)
+	this.version++
+	return nil
+}
+
+// Delete removes key, the bool is false when key is absent
+func (this *Hash) Delete(key Object) (bool, error) {
+	k, err := hashKeyOf(key, "Hash.Delete")
+	if nil != err {
+		return false, err
+	}
+	if _, ok := this.pairs[k]; !ok {
+		return false, nil
+	}
+	delete(this.pairs, k)
+	this.version++
+	for i, v := range this.order {
+		if v == k {
+			this.order = append(this.order[:i], this.order[i+1:]...)
+			break
+		}
+	}
+	return true, nil
+}
+
+// Index returns null for absent keys
+func (this *Hash) Index(key Object) (Object, error) {
+	val, ok, err := this.Get(key)
+	if nil != err {
+		return nil, err
+	}
+	if !ok {
+		return Nil, nil
+	}
+	return val, nil
+}
+
+func (this *Hash) SetIndex(key Object, val Object) error {
+	return this.Set(key, val)
+}
+
+func (this *Hash) Contains(item Object) (bool, error) {
+	_, ok, err := this.Get(item)
+	return ok, err
+}
+
+// Iter yields (key, value) in insertion order, keys must not be added or deleted while iterating
+func (this *Hash) Iter() Iterator {
+	return &hashIterator{hash: this, version: this.version}
+}
+
+func (this *Hash) calcHash(op *token.Token, right *Hash) (Object, error) {
+	switch op.Type {
+	case token.EQ:
+		equal, err := this.equals(right, map[pair]bool{})
+		if nil != err {
+			return nil, err
+		}
+		return ToBoolean(equal), nil
+	case token.NEQ:
+		equal, err := this.equals(right, map[pair]bool{})
+		if nil != err {
+			return nil, err
+		}
+		return ToBoolean(!equal), nil
+	default:
+		return calcMixed(op, this, right, "Hash.calcHash")
+	}
+}
+
+// equals ignores the insertion order, see Array.equals for seen
+func (this *Hash) equals(right *Hash, seen map[pair]bool) (bool, error) {
+	if this == right || seen[pair{this, right}] {
+		return true, nil
+	}
+	if this.Len() != right.Len() {
+		return false, nil
+	}
+	seen[pair{this, right}] = true
+	for k, p := range this.pairs {
+		other, ok := right.pairs[k]
+		if !ok {
+			return false, nil
+		}
+		equal, err := equals(p.Value, other.Value, seen)
+		if nil != err {
+			return false, fmt.Errorf("Hash.equals | %v", err)
+		}
+		if !equal {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (this *Hash) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return calcMixed(op, left, this, "Hash.calcInteger")
+}
+
+func (this *Hash) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return calcMixed(op, left, this, "Hash.calcBoolean")
+}
+
+func (this *Hash) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, "Hash.calcNull")
+}
+
+func (this *Hash) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Hash.calcString")
+}
+
+func (this *Hash) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return calcMixed(op, left, this, "Hash.calcFloat")
+}
+
+func hashKeyOf(key Object, method string) (HashKey, error) {
+	h, ok := key.(Hashable)
+	if !ok {
+		return HashKey{}, fmt.Errorf("%v -> %v is not hashable", method, ToString(key.Type()))
+	}
+	return h.HashKey(), nil
+}
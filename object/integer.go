@@ -5,7 +5,7 @@ import (
 	"fmt"
 )
 
-// Integer : implement Object
+// Integer : implement Object, Hashable
 type Integer struct {
 	Value int64
 }
@@ -53,6 +53,14 @@ func (this *Integer) Break() (bool, int) {
 	return false, 0
 }
 
+func (this *Integer) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Integer) HashKey() HashKey {
+	return HashKey{Type: this.Type(), Value: this.Value}
+}
+
 func (this *Integer) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	switch op.Type {
 	case token.ADD:
@@ -62,8 +70,14 @@ func (this *Integer) calcInteger(op *token.Token, left *Integer) (Object, error)
 	case token.MUL:
 		return &Integer{Value: left.Value * this.Value}, nil
 	case token.DIV:
+		if 0 == this.Value {
+			return nil, fmt.Errorf("Integer.calcInteger -> division by zero")
+		}
 		return &Integer{Value: left.Value / this.Value}, nil
 	case token.MOD:
+		if 0 == this.Value {
+			return nil, fmt.Errorf("Integer.calcInteger -> division by zero")
+		}
 		return &Integer{Value: left.Value % this.Value}, nil
 	case token.LT:
 		return ToBoolean(left.Value < this.Value), nil
@@ -94,6 +108,14 @@ func (this *Integer) calcNull(op *token.Token, left *Null) (Object, error) {
 	return infixNull(op, this, "Integer.calcNull")
 }
 
+func (this *Integer) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Integer.calcString")
+}
+
+func (this *Integer) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return toFloat(this.Value).calcFloat(op, left)
+}
+
 func (this *Integer) and(left *Integer) (Object, error) {
 	if 0 == left.Value {
 		return left, nil
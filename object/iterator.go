@@ -0,0 +1,71 @@
+package object
+
+import "fmt"
+
+// arrayIterator : implement Iterator
+type arrayIterator struct {
+	arr  *Array
+	size int // the length when the iteration started
+	pos  int
+}
+
+func (this *arrayIterator) Next() (Object, Object, bool, error) {
+	if len(this.arr.Elements) != this.size {
+		return nil, nil, false, fmt.Errorf("arrayIterator.Next -> array modified during iteration")
+	}
+	if this.pos >= this.size {
+		return nil, nil, false, nil
+	}
+	idx := this.pos
+	this.pos++
+	return &Integer{Value: int64(idx)}, this.arr.Elements[idx], true, nil
+}
+
+// hashIterator : implement Iterator
+type hashIterator struct {
+	hash    *Hash
+	version int // the version when the iteration started
+	pos     int
+}
+
+func (this *hashIterator) Next() (Object, Object, bool, error) {
+	if this.hash.version != this.version {
+		return nil, nil, false, fmt.Errorf("hashIterator.Next -> hash modified during iteration")
+	}
+	if this.pos >= len(this.hash.order) {
+		return nil, nil, false, nil
+	}
+	pair := this.hash.pairs[this.hash.order[this.pos]]
+	this.pos++
+	return pair.Key, pair.Value, true, nil
+}
+
+// stringIterator : implement Iterator
+type stringIterator struct {
+	chars []rune
+	pos   int
+}
+
+func (this *stringIterator) Next() (Object, Object, bool, error) {
+	if this.pos >= len(this.chars) {
+		return nil, nil, false, nil
+	}
+	idx := this.pos
+	this.pos++
+	return &Integer{Value: int64(idx)}, &String{Value: string(this.chars[idx])}, true, nil
+}
+
+// rangeIterator : implement Iterator
+type rangeIterator struct {
+	r   *Range
+	pos int64
+}
+
+func (this *rangeIterator) Next() (Object, Object, bool, error) {
+	if this.pos >= this.r.Len() {
+		return nil, nil, false, nil
+	}
+	idx := this.pos
+	this.pos++
+	return &Integer{Value: idx}, &Integer{Value: this.r.At(idx)}, true, nil
+}
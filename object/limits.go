@@ -0,0 +1,143 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+// Limits bound the evaluations in an Env, a zero field is no limit
+type Limits struct {
+	MaxSteps          int64 // statements, loop iterations and calls evaluated
+	MaxCallDepth      int   // calls of Q functions in progress
+	MaxCollectionSize int   // elements of an array or hash, characters of a string
+}
+
+// the limits a LimitError reports
+var (
+	ErrStepLimit           = errors.New("step limit exceeded")
+	ErrCallDepthLimit      = errors.New("call depth limit exceeded")
+	ErrCollectionSizeLimit = errors.New("collection size limit exceeded")
+	ErrDeadline            = errors.New("deadline exceeded")
+)
+
+// LimitError is returned by an evaluation stopped by a limit, Err is one of ErrStepLimit,
+// ErrCallDepthLimit, ErrCollectionSizeLimit or ErrDeadline
+type LimitError struct {
+	Err error
+	Max int64 // the limit exceeded, 0 for ErrDeadline
+}
+
+func (this *LimitError) Error() string {
+	if ErrDeadline == this.Err {
+		return this.Err.Error()
+	}
+	return fmt.Sprintf("%v, the limit is %v", this.Err, this.Max)
+}
+
+func (this *LimitError) Unwrap() error {
+	return this.Err
+}
+
+// usage counts what Limits bound, it is kept by the outermost Env
+type usage struct {
+	limits Limits
+	steps  int64
+	depth  int
+}
+
+// SetLimits bounds the evaluations in the Env and the Envs enclosed by it,
+// the steps counted so far are dropped
+func (this *Env) SetLimits(limits Limits) {
+	this.root().usage = &usage{limits: limits}
+}
+
+// Limits returns the limits set by SetLimits
+func (this *Env) Limits() Limits {
+	if u := this.root().usage; nil != u {
+		return u.limits
+	}
+	return Limits{}
+}
+
+func (this *Env) root() *Env {
+	env := this
+	for nil != env.outer {
+		env = env.outer
+	}
+	return env
+}
+
+// Step counts a step and checks the step limit and the context, the evaluation calls it
+// before each statement, loop iteration and call
+func (this *Env) Step() error {
+	root := this.root()
+	if u := root.usage; nil != u {
+		u.steps++
+		if u.limits.MaxSteps > 0 && u.steps > u.limits.MaxSteps {
+			return &LimitError{Err: ErrStepLimit, Max: u.limits.MaxSteps}
+		}
+	}
+	return root.Err()
+}
+
+// EnterCall checks the call depth limit for a call of a function closed over the Env,
+// LeaveCall is deferred when it succeeds
+func (this *Env) EnterCall() error {
+	u := this.root().usage
+	if nil == u {
+		return nil
+	}
+	if u.limits.MaxCallDepth > 0 && u.depth >= u.limits.MaxCallDepth {
+		return &LimitError{Err: ErrCallDepthLimit, Max: int64(u.limits.MaxCallDepth)}
+	}
+	u.depth++
+	return nil
+}
+
+func (this *Env) LeaveCall() {
+	if u := this.root().usage; nil != u {
+		u.depth--
+	}
+}
+
+// CheckSize checks the collection size limit for obj, the evaluation calls it on the
+// arrays, hashes and strings it builds or grows
+func (this *Env) CheckSize(obj Object) error {
+	u := this.root().usage
+	if nil == u || u.limits.MaxCollectionSize <= 0 {
+		return nil
+	}
+	size := 0
+	switch v := obj.(type) {
+	case *Array:
+		size = len(v.Elements)
+	case *Hash:
+		size = v.Len()
+	case *String:
+		size = utf8.RuneCountInString(v.Value)
+	}
+	return u.checkSize(size)
+}
+
+// CheckSetIndex checks the collection size limit before key is set in container, so that
+// a hash the limit stops is not grown; a new key grows a hash by one, an array never grows
+func (this *Env) CheckSetIndex(container Object, key Object) error {
+	u := this.root().usage
+	hash, ok := container.(*Hash)
+	if nil == u || u.limits.MaxCollectionSize <= 0 || !ok {
+		return nil
+	}
+	// SetIndex reports an unhashable key
+	if _, found, err := hash.Get(key); nil != err || found {
+		return nil
+	}
+	return u.checkSize(hash.Len() + 1)
+}
+
+func (this *usage) checkSize(size int) error {
+	if size > this.limits.MaxCollectionSize {
+		return &LimitError{Err: ErrCollectionSizeLimit, Max: int64(this.limits.MaxCollectionSize)}
+	}
+	return nil
+}
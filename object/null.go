@@ -5,7 +5,7 @@ import (
 	"fmt"
 )
 
-// Null : implement Object
+// Null : implement Object, Hashable
 type Null struct{}
 
 func (this *Null) Type() ObjectType {
@@ -44,6 +44,14 @@ func (this *Null) Break() (bool, int) {
 	return false, 0
 }
 
+func (this *Null) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Null) HashKey() HashKey {
+	return HashKey{Type: this.Type()}
+}
+
 func (this *Null) andInteger(left *Integer) Object {
 	if 0 == left.Value {
 		return left
@@ -72,6 +80,34 @@ func (this *Null) orBoolean(left *Boolean) Object {
 	return Nil
 }
 
+func (this *Null) andString(left *String) Object {
+	if !left.True() {
+		return left
+	}
+	return Nil
+}
+
+func (this *Null) orString(left *String) Object {
+	if left.True() {
+		return left
+	}
+	return Nil
+}
+
+func (this *Null) andFloat(left *Float) Object {
+	if !left.True() {
+		return left
+	}
+	return Nil
+}
+
+func (this *Null) orFloat(left *Float) Object {
+	if left.True() {
+		return left
+	}
+	return Nil
+}
+
 func (this *Null) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	switch op.Type {
 	case token.LT:
@@ -128,3 +164,37 @@ func (this *Null) calcNull(op *token.Token, left *Null) (Object, error) {
 		return nil, fmt.Errorf("Null.calcNull -> unsupported op %v(%v)", op.Literal, op.Type)
 	}
 }
+
+func (this *Null) calcString(op *token.Token, left *String) (Object, error) {
+	switch op.Type {
+	case token.LT:
+		return ToBoolean(false), nil
+	case token.LEQ:
+		return ToBoolean(false), nil
+	case token.GT:
+		return ToBoolean(true), nil
+	case token.GEQ:
+		return ToBoolean(true), nil
+	case token.EQ:
+		return ToBoolean(false), nil
+	case token.NEQ:
+		return ToBoolean(true), nil
+	case token.AND:
+		return this.andString(left), nil
+	case token.OR:
+		return this.orString(left), nil
+	default:
+		return nil, fmt.Errorf("Null.calcString -> unsupported op %v(%v)", op.Literal, op.Type)
+	}
+}
+
+func (this *Null) calcFloat(op *token.Token, left *Float) (Object, error) {
+	switch op.Type {
+	case token.AND:
+		return this.andFloat(left), nil
+	case token.OR:
+		return this.orFloat(left), nil
+	default:
+		return this.calcInteger(op, &Integer{Value: int64(left.Value)})
+	}
+}
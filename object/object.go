@@ -14,8 +14,42 @@ type Object interface {
 	True() bool
 	Return() (bool, Object)
 	Break() (bool, int)
+	Continue() (bool, int)
 
 	calcInteger(op *token.Token, left *Integer) (Object, error)
 	calcBoolean(op *token.Token, left *Boolean) (Object, error)
 	calcNull(op *token.Token, left *Null) (Object, error)
+	calcString(op *token.Token, left *String) (Object, error)
+	calcFloat(op *token.Token, left *Float) (Object, error)
+}
+
+// Indexable is implemented by the objects supporting `obj[key]`
+type Indexable interface {
+	Index(key Object) (Object, error)
+	SetIndex(key Object, val Object) error
+}
+
+// Hashable is implemented by the objects usable as Hash keys
+type Hashable interface {
+	HashKey() HashKey
+}
+
+// Container is implemented by the objects supporting `item in obj`
+type Container interface {
+	Contains(item Object) (bool, error)
+}
+
+// Iterable is implemented by the objects supporting `for (x in obj)`
+type Iterable interface {
+	Iter() Iterator
+}
+
+// Iterator yields the pairs of an Iterable, ok is false when it is exhausted
+type Iterator interface {
+	Next() (key Object, value Object, ok bool, err error)
+}
+
+// Labeled is implemented by the loop signals, the label names the loop they unwind to
+type Labeled interface {
+	Label() string
 }
@@ -0,0 +1,169 @@
+package object
+
+import (
+	"Q/token"
+	"fmt"
+	"math"
+)
+
+// NewRange returns the integers from start up to (not including) stop, step must not be 0
+// and there must be at most math.MaxInt64 of them
+func NewRange(start int64, stop int64, step int64) (*Range, error) {
+	if 0 == step {
+		return nil, fmt.Errorf("NewRange -> step must not be 0")
+	}
+	this := &Range{Start: start, Stop: stop, Step: step}
+	if this.length() > math.MaxInt64 {
+		return nil, fmt.Errorf("NewRange -> %v has more than %v elements", this.Inspect(), int64(math.MaxInt64))
+	}
+	return this, nil
+}
+
+// Range : implement Object, Indexable, Container, Iterable
+type Range struct {
+	Start int64
+	Stop  int64
+	Step  int64
+}
+
+func (this *Range) Type() ObjectType {
+	return ObjectTypeRange
+}
+
+func (this *Range) Inspect() string {
+	if 1 == this.Step {
+		return fmt.Sprintf("range(%v, %v)", this.Start, this.Stop)
+	}
+	return fmt.Sprintf("range(%v, %v, %v)", this.Start, this.Stop, this.Step)
+}
+
+func (this *Range) Opposite() (Object, error) {
+	return nil, fmt.Errorf("Range.Opposite -> unsupported")
+}
+
+func (this *Range) Not() (Object, error) {
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *Range) Calc(op *token.Token, right Object) (Object, error) {
+	switch r := right.(type) {
+	case *Range:
+		return this.calcRange(op, r)
+	case *Null:
+		return infixWithNull(op, this, "Range.Calc")
+	default:
+		return calcMixed(op, this, right, "Range.Calc")
+	}
+}
+
+func (this *Range) Call(args []Object, insideLoop bool) (Object, error) {
+	return nil, fmt.Errorf("Range.Call -> unsupported")
+}
+
+func (this *Range) True() bool {
+	return this.Len() > 0
+}
+
+func (this *Range) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *Range) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *Range) Continue() (bool, int) {
+	return false, 0
+}
+
+func (this *Range) Len() int64 {
+	return int64(this.length())
+}
+
+// length is computed in uint64, the distance between two int64 does not always fit an int64
+func (this *Range) length() uint64 {
+	if this.Step > 0 && this.Start < this.Stop {
+		return (uint64(this.Stop)-uint64(this.Start)-1)/uint64(this.Step) + 1
+	}
+	if this.Step < 0 && this.Start > this.Stop {
+		return (uint64(this.Start)-uint64(this.Stop)-1)/uint64(-this.Step) + 1
+	}
+	return 0
+}
+
+// At returns the element idx, which must be in [0, Len()). idx*Step may not fit an int64 but
+// the element does, so the arithmetic wrapping around gives it.
+func (this *Range) At(idx int64) int64 {
+	return int64(uint64(this.Start) + uint64(idx)*uint64(this.Step))
+}
+
+func (this *Range) Index(key Object) (Object, error) {
+	i, ok := key.(*Integer)
+	if !ok {
+		return nil, fmt.Errorf("Range.Index -> index must be integer, got %v", ToString(key.Type()))
+	}
+	sz := this.Len()
+	idx := i.Value
+	if idx < 0 {
+		idx += sz
+	}
+	if idx < 0 || idx >= sz {
+		return nil, fmt.Errorf("Range.Index -> index %v out of range for range of length %v", i.Value, sz)
+	}
+	return &Integer{Value: this.At(idx)}, nil
+}
+
+func (this *Range) SetIndex(key Object, val Object) error {
+	return fmt.Errorf("Range.SetIndex -> ranges are immutable")
+}
+
+func (this *Range) Contains(item Object) (bool, error) {
+	i, ok := item.(*Integer)
+	if !ok {
+		return false, nil
+	}
+	if this.Step > 0 && (i.Value < this.Start || i.Value >= this.Stop) ||
+		this.Step < 0 && (i.Value > this.Start || i.Value <= this.Stop) {
+		return false, nil
+	}
+	if this.Step > 0 {
+		return 0 == (uint64(i.Value)-uint64(this.Start))%uint64(this.Step), nil
+	}
+	return 0 == (uint64(this.Start)-uint64(i.Value))%uint64(-this.Step), nil
+}
+
+// Iter yields (index, integer)
+func (this *Range) Iter() Iterator {
+	return &rangeIterator{r: this}
+}
+
+func (this *Range) calcRange(op *token.Token, right *Range) (Object, error) {
+	switch op.Type {
+	case token.EQ:
+		return ToBoolean(*this == *right), nil
+	case token.NEQ:
+		return ToBoolean(*this != *right), nil
+	default:
+		return calcMixed(op, this, right, "Range.calcRange")
+	}
+}
+
+func (this *Range) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return calcMixed(op, left, this, "Range.calcInteger")
+}
+
+func (this *Range) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return calcMixed(op, left, this, "Range.calcBoolean")
+}
+
+func (this *Range) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, "Range.calcNull")
+}
+
+func (this *Range) calcString(op *token.Token, left *String) (Object, error) {
+	return calcMixed(op, left, this, "Range.calcString")
+}
+
+func (this *Range) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return calcMixed(op, left, this, "Range.calcFloat")
+}
@@ -46,6 +46,10 @@ func (this *ReturnValue) Break() (bool, int) {
 	return false, 0
 }
 
+func (this *ReturnValue) Continue() (bool, int) {
+	return false, 0
+}
+
 func (this *ReturnValue) calcInteger(op *token.Token, left *Integer) (Object, error) {
 	return nil, fmt.Errorf("ReturnValue.calcInteger -> unsupported op %v(%v)", op.Literal, op.Type)
 }
@@ -57,3 +61,11 @@ func (this *ReturnValue) calcBoolean(op *token.Token, left *Boolean) (Object, er
 func (this *ReturnValue) calcNull(op *token.Token, left *Null) (Object, error) {
 	return nil, fmt.Errorf("ReturnValue.calcNull -> unsupported op %v(%v)", op.Literal, op.Type)
 }
+
+func (this *ReturnValue) calcString(op *token.Token, left *String) (Object, error) {
+	return nil, fmt.Errorf("ReturnValue.calcString -> unsupported op %v(%v)", op.Literal, op.Type)
+}
+
+func (this *ReturnValue) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return nil, fmt.Errorf("ReturnValue.calcFloat -> unsupported op %v(%v)", op.Literal, op.Type)
+}
@@ -0,0 +1,178 @@
+package object
+
+import (
+	"Q/token"
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// String : implement Object, Indexable, Hashable, Container, Iterable
+type String struct {
+	Value string
+}
+
+func (this *String) Type() ObjectType {
+	return ObjectTypeString
+}
+
+func (this *String) Inspect() string {
+	return Quote(this.Value)
+}
+
+func (this *String) Opposite() (Object, error) {
+	return nil, fmt.Errorf("String.Opposite -> unsupported")
+}
+
+func (this *String) Not() (Object, error) {
+	return ToBoolean(!this.True()), nil
+}
+
+func (this *String) Calc(op *token.Token, right Object) (Object, error) {
+	return right.calcString(op, this)
+}
+
+func (this *String) Call(args []Object, insideLoop bool) (Object, error) {
+	return nil, fmt.Errorf("String.Call -> unsupported")
+}
+
+func (this *String) True() bool {
+	return len(this.Value) > 0
+}
+
+func (this *String) Return() (bool, Object) {
+	return false, nil
+}
+
+func (this *String) Break() (bool, int) {
+	return false, 0
+}
+
+func (this *String) Continue() (bool, int) {
+	return false, 0
+}
+
+// Index returns the character (not the byte) at key, negative keys count from the end
+func (this *String) Index(key Object) (Object, error) {
+	i, ok := key.(*Integer)
+	if !ok {
+		return nil, fmt.Errorf("String.Index -> index must be integer, got %v", ToString(key.Type()))
+	}
+	chars := []rune(this.Value)
+	sz := int64(len(chars))
+	idx := i.Value
+	if idx < 0 {
+		idx += sz
+	}
+	if idx < 0 || idx >= sz {
+		return nil, fmt.Errorf("String.Index -> index %v out of range for string of length %v", i.Value, sz)
+	}
+	return &String{Value: string(chars[idx])}, nil
+}
+
+// Iter yields (index, character)
+func (this *String) Iter() Iterator {
+	return &stringIterator{chars: []rune(this.Value)}
+}
+
+func (this *String) SetIndex(key Object, val Object) error {
+	return fmt.Errorf("String.SetIndex -> strings are immutable")
+}
+
+func (this *String) HashKey() HashKey {
+	return HashKey{Type: this.Type(), Text: this.Value}
+}
+
+// Contains reports whether item is a substring
+func (this *String) Contains(item Object) (bool, error) {
+	s, ok := item.(*String)
+	if !ok {
+		return false, fmt.Errorf("String.Contains -> expected string, got %v", ToString(item.Type()))
+	}
+	return strings.Contains(this.Value, s.Value), nil
+}
+
+func (this *String) calcInteger(op *token.Token, left *Integer) (Object, error) {
+	return calcMixed(op, left, this, "String.calcInteger")
+}
+
+func (this *String) calcBoolean(op *token.Token, left *Boolean) (Object, error) {
+	return calcMixed(op, left, this, "String.calcBoolean")
+}
+
+func (this *String) calcNull(op *token.Token, left *Null) (Object, error) {
+	return infixNull(op, this, "String.calcNull")
+}
+
+func (this *String) calcFloat(op *token.Token, left *Float) (Object, error) {
+	return calcMixed(op, left, this, "String.calcFloat")
+}
+
+func (this *String) calcString(op *token.Token, left *String) (Object, error) {
+	switch op.Type {
+	case token.ADD:
+		return &String{Value: left.Value + this.Value}, nil
+	case token.LT:
+		return ToBoolean(left.Value < this.Value), nil
+	case token.LEQ:
+		return ToBoolean(left.Value <= this.Value), nil
+	case token.GT:
+		return ToBoolean(left.Value > this.Value), nil
+	case token.GEQ:
+		return ToBoolean(left.Value >= this.Value), nil
+	case token.EQ:
+		return ToBoolean(left.Value == this.Value), nil
+	case token.NEQ:
+		return ToBoolean(left.Value != this.Value), nil
+	case token.AND:
+		if !left.True() {
+			return left, nil
+		}
+		return this, nil
+	case token.OR:
+		if left.True() {
+			return left, nil
+		}
+		return this, nil
+	default:
+		return nil, fmt.Errorf("String.calcString -> unsupported op %v(%v)", op.Literal, op.Type)
+	}
+}
+
+// Quote returns s as a double-quoted Q string literal, the lexer reads it back to s
+func Quote(s string) string {
+	var out bytes.Buffer
+	out.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if utf8.RuneError == r && 1 == size {
+			// invalid utf-8 byte, there is no escape for it so keep it raw
+			out.WriteByte(s[i])
+			i++
+			continue
+		}
+		switch r {
+		case '"':
+			out.WriteString("\\\"")
+		case '\\':
+			out.WriteString("\\\\")
+		case '\n':
+			out.WriteString("\\n")
+		case '\t':
+			out.WriteString("\\t")
+		case '\r':
+			out.WriteString("\\r")
+		default:
+			if unicode.IsPrint(r) {
+				out.WriteRune(r)
+			} else {
+				out.WriteString(fmt.Sprintf("\\u{%x}", r))
+			}
+		}
+		i += size
+	}
+	out.WriteByte('"')
+	return out.String()
+}
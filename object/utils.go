@@ -12,6 +12,13 @@ const (
 	ObjectTypeReturnValue
 	ObjectTypeFunction
 	ObjectTypeBreakObject
+	ObjectTypeString
+	ObjectTypeFloat
+	ObjectTypeArray
+	ObjectTypeHash
+	ObjectTypeBuiltin
+	ObjectTypeContinueObject
+	ObjectTypeRange
 )
 
 var (
@@ -22,12 +29,19 @@ var (
 
 var (
 	objectTypeStrings = map[ObjectType]string{
-		ObjectTypeInteger:     "integer",
-		ObjectTypeBoolean:     "boolean",
-		ObjectTypeNull:        "null",
-		ObjectTypeReturnValue: "return_value",
-		ObjectTypeFunction:    "function",
-		ObjectTypeBreakObject: "break_object",
+		ObjectTypeInteger:        "integer",
+		ObjectTypeBoolean:        "boolean",
+		ObjectTypeNull:           "null",
+		ObjectTypeReturnValue:    "return_value",
+		ObjectTypeFunction:       "function",
+		ObjectTypeBreakObject:    "break_object",
+		ObjectTypeString:         "string",
+		ObjectTypeFloat:          "float",
+		ObjectTypeArray:          "array",
+		ObjectTypeHash:           "hash",
+		ObjectTypeBuiltin:        "builtin",
+		ObjectTypeContinueObject: "continue_object",
+		ObjectTypeRange:          "range",
 	}
 )
 
@@ -61,6 +75,10 @@ func toInteger(v bool) *Integer {
 	return &Integer{Value: toInt64(v)}
 }
 
+func toFloat(v int64) *Float {
+	return &Float{Value: float64(v)}
+}
+
 func infixNull(op *token.Token, right Object, method string) (Object, error) {
 	switch op.Type {
 	case token.LT:
@@ -83,3 +101,105 @@ func infixNull(op *token.Token, right Object, method string) (Object, error) {
 		return nil, fmt.Errorf("infixNull -> (%v) unsupported op %v(%v)", method, op.Literal, op.Type)
 	}
 }
+
+// infixWithNull is the counterpart of infixNull for `left op null`
+func infixWithNull(op *token.Token, left Object, method string) (Object, error) {
+	switch op.Type {
+	case token.LT:
+		return ToBoolean(false), nil
+	case token.LEQ:
+		return ToBoolean(false), nil
+	case token.GT:
+		return ToBoolean(true), nil
+	case token.GEQ:
+		return ToBoolean(true), nil
+	case token.EQ:
+		return ToBoolean(false), nil
+	case token.NEQ:
+		return ToBoolean(true), nil
+	case token.AND:
+		if !left.True() {
+			return left, nil
+		}
+		return Nil, nil
+	case token.OR:
+		if left.True() {
+			return left, nil
+		}
+		return Nil, nil
+	default:
+		return nil, fmt.Errorf("infixWithNull -> (%v) unsupported op %v(%v)", method, op.Literal, op.Type)
+	}
+}
+
+// calcMixed handles the infix ops between values of unrelated types:
+// they are never equal, && and || pick an operand, anything else is an error
+func calcMixed(op *token.Token, left Object, right Object, method string) (Object, error) {
+	switch op.Type {
+	case token.EQ:
+		return False, nil
+	case token.NEQ:
+		return True, nil
+	case token.AND:
+		if !left.True() {
+			return left, nil
+		}
+		return right, nil
+	case token.OR:
+		if left.True() {
+			return left, nil
+		}
+		return right, nil
+	default:
+		return nil, fmt.Errorf("%v -> unsupported op: %v %v %v", method, ToString(left.Type()), op.Literal, ToString(right.Type()))
+	}
+}
+
+// In evaluates `item in container`
+func In(item Object, container Object) (Object, error) {
+	c, ok := container.(Container)
+	if !ok {
+		return nil, fmt.Errorf("In -> %v is not a container", ToString(container.Type()))
+	}
+	found, err := c.Contains(item)
+	if nil != err {
+		return nil, fmt.Errorf("In | %v", err)
+	}
+	return ToBoolean(found), nil
+}
+
+// pair is two containers being compared
+type pair struct {
+	left, right Object
+}
+
+// inspect is o.Inspect(), the containers in visiting print as [...] or {...}
+func inspect(o Object, visiting map[Object]bool) string {
+	switch o := o.(type) {
+	case *Array:
+		return o.inspect(visiting)
+	case *Hash:
+		return o.inspect(visiting)
+	default:
+		return o.Inspect()
+	}
+}
+
+// equals is left == right, the pairs of containers in seen are equal
+func equals(left Object, right Object, seen map[pair]bool) (bool, error) {
+	switch l := left.(type) {
+	case *Array:
+		if r, ok := right.(*Array); ok {
+			return l.equals(r, seen)
+		}
+	case *Hash:
+		if r, ok := right.(*Hash); ok {
+			return l.equals(r, seen)
+		}
+	}
+	v, err := left.Calc(&token.Token{Type: token.EQ, Literal: "=="}, right)
+	if nil != err {
+		return false, err
+	}
+	return v.True(), nil
+}
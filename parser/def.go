@@ -6,3 +6,4 @@ import (
 
 type parseBlockStmtFn func() *ast.BlockStmt
 type parseExpressionFn func(precedence int) ast.Expression
+type parseStmtFn func() ast.Statement
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"Q/ast"
+	"Q/diag"
 	"Q/lexer"
 	"Q/token"
 	"fmt"
@@ -18,22 +19,24 @@ type Parser struct {
 	infixDecoders infixDecoderMap
 }
 
-func newInfixDecoders(parseInfixExpr decodeInfix, parseCall decodeInfix) infixDecoderMap {
+func newInfixDecoders(parseInfixExpr decodeInfix, parseCall decodeInfix, parseIndex decodeInfix) infixDecoderMap {
 	return infixDecoderMap{
-		token.LT:     parseInfixExpr,
-		token.GT:     parseInfixExpr,
-		token.ADD:    parseInfixExpr,
-		token.SUB:    parseInfixExpr,
-		token.MUL:    parseInfixExpr,
-		token.DIV:    parseInfixExpr,
-		token.MOD:    parseInfixExpr,
-		token.EQ:     parseInfixExpr,
-		token.NEQ:    parseInfixExpr,
-		token.LEQ:    parseInfixExpr,
-		token.GEQ:    parseInfixExpr,
-		token.AND:    parseInfixExpr,
-		token.OR:     parseInfixExpr,
-		token.LPAREN: parseCall,
+		token.LT:       parseInfixExpr,
+		token.GT:       parseInfixExpr,
+		token.ADD:      parseInfixExpr,
+		token.SUB:      parseInfixExpr,
+		token.MUL:      parseInfixExpr,
+		token.DIV:      parseInfixExpr,
+		token.MOD:      parseInfixExpr,
+		token.EQ:       parseInfixExpr,
+		token.NEQ:      parseInfixExpr,
+		token.LEQ:      parseInfixExpr,
+		token.GEQ:      parseInfixExpr,
+		token.AND:      parseInfixExpr,
+		token.OR:       parseInfixExpr,
+		token.IN:       parseInfixExpr,
+		token.LPAREN:   parseCall,
+		token.LBRACKET: parseIndex,
 	}
 }
 
@@ -44,56 +47,101 @@ func New(l *lexer.Lexer) (*Parser, error) {
 	}
 	p := &Parser{scanner: s}
 	p.stmtParser = newStmtParser(s, p.parseExpression)
-	p.tokenDecoders = newTokenDecoders(s, p.parseExpression, p.parseBlockStmt)
-	p.infixDecoders = newInfixDecoders(p.parseInfixExpression, p.parseCallExpression)
+	p.tokenDecoders = newTokenDecoders(s, p.parseExpression, p.parseBlockStmt, p.parseStmt)
+	p.infixDecoders = newInfixDecoders(p.parseInfixExpression, p.parseCallExpression, p.parseIndexExpression)
 	return p, nil
 }
 
+// Errors is the string view of Diagnostics
 func (this *Parser) Errors() []string {
-	return this.scanner.errors
+	return this.scanner.Errors()
+}
+
+func (this *Parser) Diagnostics() []*diag.Diagnostic {
+	return this.scanner.diags
+}
+
+// Incomplete reports whether the input is cut short rather than wrong: every diagnostic is
+// CodeIncomplete, so more input may complete it. The REPL asks for more lines then.
+func (this *Parser) Incomplete() bool {
+	if 0 == len(this.scanner.diags) {
+		return false
+	}
+	for _, d := range this.scanner.diags {
+		if diag.CodeIncomplete != d.Code {
+			return false
+		}
+	}
+	return true
 }
 
 func (this *Parser) parseStmt() ast.Statement {
 	return this.stmtParser.decode(this.scanner.curTok.Type)
 }
 
+// ParseProgram parses every statement, a broken statement is reported, dropped and
+// skipped so the following ones are still checked. The program records the diagnostics
+// and refuses to Eval when there is any.
 func (this *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{Stmts: ast.StatementSlice{}}
 	for !this.scanner.eof() {
-		stmt := this.parseStmt()
+		stmt := this.parseStmtOrRecover(false)
 		if nil != stmt {
 			program.Stmts = append(program.Stmts, stmt)
 		}
 		this.scanner.nextToken()
 	}
+	program.Diags = this.scanner.diags
 	return program
 }
 
 func (this *Parser) parseBlockStmt() *ast.BlockStmt {
 	block := &ast.BlockStmt{Tok: this.scanner.curTok}
 	block.Stmts = ast.StatementSlice{}
+	nested := this.scanner.nested
+	this.scanner.nested = 0
 	this.scanner.nextToken()
 	for !this.scanner.curTok.TypeIs(token.RBRACE) {
-		stmt := this.parseStmt()
+		if this.scanner.eof() {
+			this.scanner.appendError(diag.CodeUnexpectedToken, fmt.Sprintf("expected RBRACE to close the block opened at %v, got EOF instead", block.Tok.Pos))
+			this.scanner.nested = nested
+			return block
+		}
+		stmt := this.parseStmtOrRecover(true)
 		if nil != stmt {
 			block.Stmts = append(block.Stmts, stmt)
 		}
 		this.scanner.nextToken()
 	}
+	this.scanner.nested = nested
 	return block
 }
 
+// parseStmtOrRecover returns nil for a statement with errors after skipping what is left of it
+func (this *Parser) parseStmtOrRecover(inBlock bool) ast.Statement {
+	stmt := this.parseStmt()
+	if this.scanner.panicking {
+		this.scanner.synchronize(inBlock)
+		return nil
+	}
+	return stmt
+}
+
 func (this *Parser) parseExpression(precedence int) ast.Expression {
+	if this.scanner.curTok.Illegal() {
+		this.scanner.appendError(diag.CodeIllegalToken, fmt.Sprintf("illegal token %v", this.scanner.curTok.Literal))
+		return nil
+	}
 	tokenDecoder := this.tokenDecoders[this.scanner.curTok.Type]
 	if nil == tokenDecoder {
-		this.scanner.appendError(fmt.Sprintf("%v has no decoder", token.ToString(this.scanner.curTok.Type)))
+		this.scanner.appendError(diag.CodeNoDecoder, fmt.Sprintf("%v has no decoder", token.ToString(this.scanner.curTok.Type)))
 		return nil
 	}
 	leftExpr := tokenDecoder.decode()
 
 	for !this.scanner.peekTok.TypeIs(token.SEMICOLON) && precedence < this.scanner.peekPrecedence() {
 		infix := this.infixDecoders[this.scanner.peekTok.Type]
-		if nil == infix {
+		if nil == infix || this.endsBeforePeek(leftExpr) {
 			return leftExpr
 		}
 		this.scanner.nextToken()
@@ -121,21 +169,40 @@ func (this *Parser) parseCallExpression(left ast.Expression) ast.Expression {
 }
 
 func (this *Parser) parseCallArgs() ast.ExpressionSlice {
-	args := ast.ExpressionSlice{}
-	if this.scanner.peekTok.TypeIs(token.RPAREN) {
-		this.scanner.nextToken()
-		return args
-	}
+	return parseExpressionList(this.scanner, this.parseExpression, token.RPAREN)
+}
+
+func (this *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	expr := &ast.Index{Tok: this.scanner.curTok, Left: left}
 	this.scanner.nextToken()
-	args = append(args, this.parseExpression(PRECED_LOWEST))
+	this.scanner.nested++
+	expr.Index = this.parseExpression(PRECED_LOWEST)
+	this.scanner.nested--
+	if !this.scanner.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return expr
+}
 
-	for this.scanner.peekTok.TypeIs(token.COMMA) {
-		this.scanner.nextToken()
-		this.scanner.nextToken()
-		args = append(args, this.parseExpression(PRECED_LOWEST))
+// parseExpressionList parses `a, b, c` up to the end token, curTok is the opening token
+func parseExpressionList(s *scanner, parseExpression parseExpressionFn, end token.TokenType) ast.ExpressionSlice {
+	list := ast.ExpressionSlice{}
+	if s.peekTok.TypeIs(end) {
+		s.nextToken()
+		return list
+	}
+	s.nextToken()
+	s.nested++
+	list = append(list, parseExpression(PRECED_LOWEST))
+
+	for s.peekTok.TypeIs(token.COMMA) {
+		s.nextToken()
+		s.nextToken()
+		list = append(list, parseExpression(PRECED_LOWEST))
 	}
-	if !this.scanner.expectPeek(token.RPAREN) {
+	s.nested--
+	if !s.expectPeek(end) {
 		return nil
 	}
-	return args
+	return list
 }
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"Q/ast"
+	"Q/diag"
 	"Q/lexer"
 	"fmt"
 	"reflect"
@@ -270,6 +271,78 @@ func TestBoolExpr(t *testing.T) {
 	}
 }
 
+func TestStringExpr(t *testing.T) {
+	input := `"hello \"world\"\n";`
+
+	l := lexer.New(input)
+	p, err := New(l)
+	if nil != err {
+		t.Fatal(err)
+	}
+
+	program := p.ParseProgram()
+	if nil == program {
+		t.Fatalf("program is nil")
+	}
+	checkParserErrors(t, p)
+	if len(program.Stmts) != 1 {
+		t.Fatalf("number of program Statements: %v", len(program.Stmts))
+	}
+
+	stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+	}
+	literal, ok := stmt.Expr.(*ast.String)
+	if !ok {
+		t.Fatalf("Expr is not *ast.String, got %v", reflect.TypeOf(stmt.Expr).String())
+	}
+	if literal.Value != "hello \"world\"\n" {
+		t.Errorf("literal.Value wrong, got %q", literal.Value)
+	}
+	if literal.String() != `"hello \"world\"\n"` {
+		t.Errorf("literal.String() not quoted, got %v", literal.String())
+	}
+}
+
+func TestFloatExpr(t *testing.T) {
+	cases := []struct {
+		input string
+		want  float64
+	}{
+		{"1.5;", 1.5},
+		{".25;", 0.25},
+		{"1e-3;", 0.001},
+		{"2.5E2;", 250},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		if nil == program {
+			t.Fatalf("program is nil")
+		}
+		checkParserErrors(t, p)
+		if len(program.Stmts) != 1 {
+			t.Fatalf("number of program Statements: %v", len(program.Stmts))
+		}
+		stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+		}
+		literal, ok := stmt.Expr.(*ast.Float)
+		if !ok {
+			t.Fatalf("Expr is not *ast.Float, got %v", reflect.TypeOf(stmt.Expr).String())
+		}
+		if literal.Value != tt.want {
+			t.Errorf("literal.Value != %v, got %v", tt.want, literal.Value)
+		}
+	}
+}
+
 func testLiteralExpression(t *testing.T, expr ast.Expression, want interface{}) bool {
 	switch v := want.(type) {
 	case int:
@@ -434,6 +507,17 @@ func TestOpPrecedParsing(t *testing.T) {
 		{"a + add(b * c) + d", "((a + add((b * c))) + d)"},
 		{"add(a, b, 1, 2 * 3, 4 + 5, add(6, 7 * 8))", "add(a, b, 1, (2 * 3), (4 + 5), add(6, (7 * 8)))"},
 		{"add(a + b + c * d / f + g)", "add((((a + b) + ((c * d) / f)) + g))"},
+		{`"a" + b + "\t"`, `(("a" + b) + "\t")`},
+		{"1.5 * -.5 + 1e3", "((1.5 * (-.5)) + 1e3)"},
+		{"a * [1, 2, 3, 4][b * c] * d", "((a * ([1, 2, 3, 4][(b * c)])) * d)"},
+		{"add(a * b[2], b[1], 2 * [1, 2][1])", "add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))"},
+		{"-a[0]", "(-(a[0]))"},
+		{"a[0][1]", "((a[0])[1])"},
+		{"f(x)[0]", "(f(x)[0])"},
+		{"a[i + 1] = b[i];", "(a[(i + 1)]) = (b[i]);"},
+		{"a + 1 in b && c", "(((a + 1) in b) && c)"},
+		{`{"a": 1 + 2, b: [c]}["a"]`, `({"a": (1 + 2), b: [c]}["a"])`},
+		{"delete h[k + 1];", "delete (h[(k + 1)]);"},
 	}
 	for _, tt := range cases {
 		l := lexer.New(tt.input)
@@ -692,3 +776,578 @@ func TestCallParsing(t *testing.T) {
 	testInfixExpression(t, expr.Args[1], 2, "*", 3)
 	testInfixExpression(t, expr.Args[2], 4, "+", 5)
 }
+
+func TestArrayParsing(t *testing.T) {
+	input := "[1, 2 * 2, 3 + 3]"
+
+	l := lexer.New(input)
+	p, err := New(l)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := p.ParseProgram()
+	if nil == program {
+		t.Fatalf("program is nil")
+	}
+	checkParserErrors(t, p)
+	stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+	}
+	array, ok := stmt.Expr.(*ast.Array)
+	if !ok {
+		t.Fatalf("stmt.Expr is not *ast.Array, got %v", reflect.TypeOf(stmt.Expr).String())
+	}
+	if len(array.Elements) != 3 {
+		t.Fatalf("wrong len of elements, got %v", len(array.Elements))
+	}
+	testIntegerLiteral(t, array.Elements[0], 1)
+	testInfixExpression(t, array.Elements[1], 2, "*", 2)
+	testInfixExpression(t, array.Elements[2], 3, "+", 3)
+}
+
+func TestIndexParsing(t *testing.T) {
+	input := "myArray[1 + 1]"
+
+	l := lexer.New(input)
+	p, err := New(l)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := p.ParseProgram()
+	if nil == program {
+		t.Fatalf("program is nil")
+	}
+	checkParserErrors(t, p)
+	stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+	}
+	index, ok := stmt.Expr.(*ast.Index)
+	if !ok {
+		t.Fatalf("stmt.Expr is not *ast.Index, got %v", reflect.TypeOf(stmt.Expr).String())
+	}
+	if !testIdentifier(t, index.Left, "myArray") {
+		return
+	}
+	testInfixExpression(t, index.Index, 1, "+", 1)
+}
+
+func TestIndexAssignParsing(t *testing.T) {
+	input := "a[0] = 5;"
+
+	l := lexer.New(input)
+	p, err := New(l)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := p.ParseProgram()
+	if nil == program {
+		t.Fatalf("program is nil")
+	}
+	checkParserErrors(t, p)
+	if len(program.Stmts) != 1 {
+		t.Fatalf("number of program Statements: %v", len(program.Stmts))
+	}
+	stmt, ok := program.Stmts[0].(*ast.AssignStmt)
+	if !ok {
+		t.Fatalf("program.Stmts[0] is not *ast.AssignStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+	}
+	if nil == stmt.Index {
+		t.Fatalf("stmt.Index is nil")
+	}
+	if !testIdentifier(t, stmt.Index.Left, "a") {
+		return
+	}
+	testIntegerLiteral(t, stmt.Index.Index, 0)
+	testIntegerLiteral(t, stmt.Value, 5)
+}
+
+func TestHashParsing(t *testing.T) {
+	cases := []struct {
+		input string
+		want  map[string]int64
+	}{
+		{`{}`, map[string]int64{}},
+		{`{"one": 1, "two": 2, "three": 3}`, map[string]int64{"one": 1, "two": 2, "three": 3}},
+		{`{"one": 1, "two": 2,}`, map[string]int64{"one": 1, "two": 2}},
+	}
+	for _, tt := range cases {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		if nil == program {
+			t.Fatalf("program is nil")
+		}
+		checkParserErrors(t, p)
+		stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+		}
+		hash, ok := stmt.Expr.(*ast.Hash)
+		if !ok {
+			t.Fatalf("stmt.Expr is not *ast.Hash, got %v", reflect.TypeOf(stmt.Expr).String())
+		}
+		if len(hash.Pairs) != len(tt.want) {
+			t.Fatalf("wrong len of pairs, got %v", len(hash.Pairs))
+		}
+		for _, pair := range hash.Pairs {
+			key, ok := pair.Key.(*ast.String)
+			if !ok {
+				t.Fatalf("key is not *ast.String, got %v", reflect.TypeOf(pair.Key).String())
+			}
+			testIntegerLiteral(t, pair.Value, tt.want[key.Value])
+		}
+	}
+}
+
+func TestHashParsingErrors(t *testing.T) {
+	cases := []string{
+		`{"a" 1}`,
+		`{"a": 1 "b": 2}`,
+		`delete h;`,
+	}
+	for _, input := range cases {
+		l := lexer.New(input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		if len(p.Errors()) < 1 {
+			t.Errorf("[%v] expected parser errors", input)
+		}
+	}
+}
+
+func TestForParsing(t *testing.T) {
+	tests := []struct {
+		input  string
+		cstyle bool
+		init   bool
+		cond   bool
+		post   bool
+		want   string
+	}{
+		{"for { break; }", false, false, false, false, "for {break;}"},
+		{"for (i < 10) { i = i + 1; }", false, false, true, false, "for ((i < 10)) {i = (i + 1);}"},
+		{"for (var i = 0; i < 10; i = i + 1) { continue; }", true, true, true, true, "for (var i = 0; (i < 10); i = (i + 1)) {continue;}"},
+		{"for (;;) { break; }", true, false, false, false, "for (; ; ) {break;}"},
+		{"for (; i < len(a); a[i] = 0) { }", true, false, true, true, "for (; (i < len(a)); (a[i]) = 0) {}"},
+		{"for (f(); ; i = i + 1) { }", true, true, false, true, "for (f(); ; i = (i + 1)) {}"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Stmts) != 1 {
+			t.Fatalf("[%v] program.Stmts has %v stmts", tt.input, len(program.Stmts))
+		}
+		stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+		}
+		expr, ok := stmt.Expr.(*ast.ForExpression)
+		if !ok {
+			t.Fatalf("stmt.Expr is not *ast.ForExpression, got %v", reflect.TypeOf(stmt.Expr).String())
+		}
+		if expr.CStyle != tt.cstyle || (nil != expr.Init) != tt.init || (nil != expr.Cond) != tt.cond || (nil != expr.Post) != tt.post {
+			t.Errorf("[%v] wrong clauses, cstyle=%v init=%v cond=%v post=%v", tt.input, expr.CStyle, expr.Init, expr.Cond, expr.Post)
+		}
+		if expr.String() != tt.want {
+			t.Errorf("[%v] String() = %q, want %q", tt.input, expr.String(), tt.want)
+		}
+	}
+}
+
+func TestForParsingErrors(t *testing.T) {
+	cases := []string{
+		"for (var i = 0; i < 10) { }",
+		"for (var i = 0; i < 10; i = i + 1 { }",
+		"for (i < 10 { }",
+		"for (i < 10)",
+	}
+	for _, input := range cases {
+		l := lexer.New(input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		if len(p.Errors()) < 1 {
+			t.Errorf("[%v] expected parser errors", input)
+		}
+	}
+}
+
+func TestForInParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		key   string
+		value string
+		want  string
+	}{
+		{"for (x in arr) { x; }", "", "x", "for (x in arr) {x}"},
+		{"for (k, v in h) { }", "k", "v", "for (k, v in h) {}"},
+		{"for (i in range(1, 10)) { break; }", "", "i", "for (i in range(1, 10)) {break;}"},
+		{`for (c in "abc" + s) { }`, "", "c", `for (c in ("abc" + s)) {}`},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		stmt, ok := program.Stmts[0].(*ast.ExpressionStmt)
+		if !ok {
+			t.Fatalf("program.Stmts[0] is not *ast.ExpressionStmt, got %v", reflect.TypeOf(program.Stmts[0]).String())
+		}
+		expr, ok := stmt.Expr.(*ast.ForInExpression)
+		if !ok {
+			t.Fatalf("stmt.Expr is not *ast.ForInExpression, got %v", reflect.TypeOf(stmt.Expr).String())
+		}
+		if "" == tt.key {
+			if nil != expr.Key {
+				t.Errorf("[%v] unexpected key %v", tt.input, expr.Key)
+			}
+		} else {
+			testIdentifier(t, expr.Key, tt.key)
+		}
+		testIdentifier(t, expr.Value, tt.value)
+		if expr.String() != tt.want {
+			t.Errorf("[%v] String() = %q, want %q", tt.input, expr.String(), tt.want)
+		}
+	}
+}
+
+func TestForInParsingErrors(t *testing.T) {
+	cases := []string{
+		"for (x in) { }",
+		"for (k, k in h) { }",
+		"for (x in arr { }",
+		"for (k, v in h)",
+	}
+	for _, input := range cases {
+		l := lexer.New(input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		if len(p.Errors()) < 1 {
+			t.Errorf("[%v] expected parser errors", input)
+		}
+	}
+}
+
+func TestLabelParsing(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"outer: for { break outer; }", "outer: for {break outer;}"},
+		{"outer: for (x in a) { for { continue outer; } }", "outer: for (x in a) {for {continue outer;}}"},
+		{"outer: for (;;) { inner: for (i < 3) { break outer; continue inner; break; } }", "outer: for (; ; ) {inner: for ((i < 3)) {break outer;continue inner;break;}}"},
+		{"outer: for { var f = func() { loop: for { break loop; } }; break outer; }", "outer: for {var f = func()loop: for {break loop;};break outer;}"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+		if len(program.Stmts) != 1 {
+			t.Fatalf("[%v] program.Stmts has %v stmts", tt.input, len(program.Stmts))
+		}
+		if program.Stmts[0].String() != tt.want {
+			t.Errorf("[%v] String() = %q, want %q", tt.input, program.Stmts[0].String(), tt.want)
+		}
+	}
+}
+
+func TestLabelParsingErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"break outer;", "1:7: break outer outside loop"},
+		{"continue outer;", "1:10: continue outer outside loop"},
+		{"for { break outer; }", "1:13: unknown label outer"},
+		{"outer: for { } for { continue outer; }", "1:31: unknown label outer"},
+		{"outer: for { var f = func() { break outer; }; }", "1:37: break outer outside loop"},
+		{"outer: for { var f = func() { for { break outer; } }; }", "1:43: unknown label outer"},
+		{"outer: var x = 1;", "1:6: label outer must be followed by a for loop"},
+		{"outer: for { outer: for { } }", "1:19: label outer already defined"},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		found := false
+		for _, msg := range p.Errors() {
+			if msg == tt.want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("[%v] errors %v do not contain %q", tt.input, p.Errors(), tt.want)
+		}
+	}
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"var x = 1;\nvar = 2;", "t.q:2:5: expected next token to be IDENT, got ASSIGN instead"},
+		{"if (x {\n}", "t.q:1:7: expected next token to be RPAREN, got LBRACE instead"},
+		{"var s = \"a\";\n  x = @;", "t.q:2:7: illegal token @"},
+	}
+	for _, tt := range tests {
+		l := lexer.NewFile("t.q", tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		if len(p.Errors()) < 1 || p.Errors()[0] != tt.want {
+			t.Errorf("[%v] errors %v, want %q first", tt.input, p.Errors(), tt.want)
+		}
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "var a = [1,\n  2];\na[0] = f(a) + 1;"
+	l := lexer.NewFile("n.q", input)
+	p, err := New(l)
+	if nil != err {
+		t.Fatal(err)
+	}
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	varStmt := program.Stmts[0].(*ast.VarStmt)
+	assign := program.Stmts[1].(*ast.AssignStmt)
+	infix := assign.Value.(*ast.InfixExpression)
+	array := varStmt.Value.(*ast.Array)
+	tests := []struct {
+		node ast.Node
+		want string
+	}{
+		{program, "n.q:1:1"},
+		{varStmt, "n.q:1:1"},
+		{varStmt.Name, "n.q:1:5"},
+		{array, "n.q:1:9"},
+		{array.Elements[1], "n.q:2:3"},
+		{assign, "n.q:3:2"},
+		{infix, "n.q:3:13"},
+		{infix.Left, "n.q:3:9"},
+		{infix.Right, "n.q:3:15"},
+	}
+	for _, tt := range tests {
+		if tt.node.Pos().String() != tt.want {
+			t.Errorf("%v at %v, want %v", tt.node.String(), tt.node.Pos(), tt.want)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input string
+		code  string
+		start string
+		end   string
+		notes int
+		fix   string
+	}{
+		{"if (x { }", diag.CodeUnexpectedToken, "1:7", "1:8", 0, ")"},
+		{"var = 1;", diag.CodeUnexpectedToken, "1:5", "1:6", 0, ""},
+		{"x = @;", diag.CodeIllegalToken, "1:5", "1:6", 0, ""},
+		{"outer: for { for { break inner; } }", diag.CodeBadLabel, "1:26", "1:31", 1, ""},
+		{"delete x;", diag.CodeBadDelete, "1:8", "1:9", 0, ""},
+		{"for (k, k in h) { }", diag.CodeBadLoop, "1:9", "1:10", 0, ""},
+		{"f(1,", diag.CodeIncomplete, "1:5", "1:5", 0, ""},
+		{"var s = \"abc", diag.CodeIncomplete, "1:9", "1:13", 0, ""},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		diags := p.Diagnostics()
+		if len(diags) < 1 {
+			t.Fatalf("[%v] expected diagnostics", tt.input)
+		}
+		d := diags[0]
+		if d.Severity != diag.SeverityError || d.Code != tt.code {
+			t.Errorf("[%v] got %v[%v], want error[%v]", tt.input, d.Severity, d.Code, tt.code)
+		}
+		if d.Start.String() != tt.start || d.End.String() != tt.end {
+			t.Errorf("[%v] span %v-%v, want %v-%v", tt.input, d.Start, d.End, tt.start, tt.end)
+		}
+		if len(d.Notes) != tt.notes {
+			t.Errorf("[%v] notes %v, want %v", tt.input, d.Notes, tt.notes)
+		}
+		if "" == tt.fix && nil != d.Fix || "" != tt.fix && (nil == d.Fix || d.Fix.Replacement != tt.fix) {
+			t.Errorf("[%v] fix %+v, want %q", tt.input, d.Fix, tt.fix)
+		}
+		if p.Errors()[0] != d.String() {
+			t.Errorf("[%v] Errors()[0] = %q, want %q", tt.input, p.Errors()[0], d.String())
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+		stmts int
+	}{
+		{"var = 1; var x = 2; var y = ;", []string{"1:5: expected next token to be IDENT, got ASSIGN instead", "1:29: SEMICOLON has no decoder"}, 1},
+		{"if (x { y; } var z = 1;", []string{"1:7: expected next token to be RPAREN, got LBRACE instead"}, 1},
+		{"var f = func() { x = ; y = 2; z = ]; }; f();", []string{"1:22: SEMICOLON has no decoder", "1:35: RBRACKET has no decoder"}, 2},
+		{"var f = func() { x = }; f();", []string{"1:22: RBRACE has no decoder"}, 2},
+		{"break outer; continue inner; x;", []string{"1:7: break outer outside loop", "1:23: continue inner outside loop"}, 1},
+		{"} x; ) y;", []string{"1:1: RBRACE has no decoder", "1:6: RPAREN has no decoder"}, 2},
+		{"var a = [1, 2;\nvar b = 3;", []string{"1:14: expected next token to be RBRACKET, got SEMICOLON instead"}, 1},
+		{"f(1, 2\nvar b = 3;", []string{"2:1: expected next token to be RPAREN, got VAR instead"}, 1},
+		{"if (true) { var x = 1;", []string{"1:23: expected RBRACE to close the block opened at 1:11, got EOF instead"}, 0},
+		{"for { x = 1", []string{"1:12: expected RBRACE to close the block opened at 1:5, got EOF instead"}, 0},
+		{"var x = 1 +", []string{"1:12: EOF has no decoder"}, 0},
+		{"var x = 1 2; var y = 3;", []string{"1:11: expected ; after the statement, got INT instead"}, 1},
+		{`x = 1 2 3; y = 2 "s"; z;`, []string{"1:7: expected ; after the statement, got INT instead", "1:18: expected ; after the statement, got STRING instead"}, 1},
+		{"var x = 1 var y = 2;", []string{"1:11: expected ; after the statement, got VAR instead"}, 1},
+		{"x[0] = 1 )", []string{"1:10: expected ; after the statement, got RPAREN instead"}, 0},
+		{"return;", []string{"1:7: SEMICOLON has no decoder"}, 0},
+		{"if (x) { y } z; for (i in a) { break } var f = func() { 1 } f(); var h = {} h;", []string{"1:77: expected ; after the statement, got IDENT instead"}, 5},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		if !reflect.DeepEqual(p.Errors(), tt.want) {
+			t.Errorf("[%v] errors %q, want %q", tt.input, p.Errors(), tt.want)
+		}
+		if len(program.Stmts) != tt.stmts {
+			t.Errorf("[%v] %v stmts, want %v: %v", tt.input, len(program.Stmts), tt.stmts, program.String())
+		}
+		if len(program.Diags) != len(tt.want) {
+			t.Errorf("[%v] program has %v diagnostics, want %v", tt.input, len(program.Diags), len(tt.want))
+		}
+		for _, stmt := range program.Stmts {
+			checkNoNil(t, tt.input, reflect.ValueOf(stmt))
+		}
+	}
+}
+
+// checkNoNil walks the node and fails on nil fields holding an ast.Expression
+func checkNoNil(t *testing.T, input string, v reflect.Value) {
+	exprType := reflect.TypeOf((*ast.Expression)(nil)).Elem()
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			checkNoNil(t, input, v.Elem())
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			if v.Type() == exprType {
+				t.Errorf("[%v] nil expression in the AST", input)
+			}
+			return
+		}
+		checkNoNil(t, input, v.Elem())
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			checkNoNil(t, input, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			checkNoNil(t, input, v.Field(i))
+		}
+	}
+}
+
+func TestBlockBeforeNewLine(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		stmts int
+	}{
+		{"for (x in a) { x }\n[1, 2];", "for (x in a) {x}[1, 2]", 2},
+		{"if (c) { 1 } else { 2 }\n(3);", "ifc{1}else {2}3", 2},
+		{"var f = func(x) { x }\n(2);", "var f = func(x)x;2", 2},
+		{"var y = 1 + if (c) { 1 }\n[0];", "var y = (1 + ifc{1});[0]", 2},
+		{"for (x in a) { x }[0];", "(for (x in a) {x}[0])", 1},
+		{"func(x) { x }(2);", "func(x)x(2)", 1},
+		{"f(if (c) { 1 }\n[0]);", "f((ifc{1}[0]))", 1},
+		{"var h = {1: if (c) { 1 }\n[0]};", "var h = {1: (ifc{1}[0])};", 1},
+		{"f(func() { for (x in a) { x }\n[1] });", "f(func()for (x in a) {x}[1])", 1},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		program := p.ParseProgram()
+		if len(p.Errors()) > 0 {
+			t.Errorf("[%v] errors %q", tt.input, p.Errors())
+		}
+		if len(program.Stmts) != tt.stmts || program.String() != tt.want {
+			t.Errorf("[%v] %v stmts %q, want %v %q", tt.input, len(program.Stmts), program.String(), tt.stmts, tt.want)
+		}
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"var x = 1;", false},
+		{"var f = func(x) {", true},
+		{"var f = func(x) {\n return x;", true},
+		{"if (x) { y; } else", true},
+		{"f(1, 2", true},
+		{"[1, 2,", true},
+		{"var x = 1 +", true},
+		{"x &&", true},
+		{"var s = \"abc", true},
+		{"var s = \"abc\\\"", true},
+		{"var s = \"abc\\\"\"", false},
+		{"var = 1; f(", false},
+		{"f(1; {", false},
+		{"}", false},
+		{"x = @", false},
+	}
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p, err := New(l)
+		if nil != err {
+			t.Fatal(err)
+		}
+		p.ParseProgram()
+		if p.Incomplete() != tt.incomplete {
+			t.Errorf("[%v] Incomplete() = %v, want %v, errors %q", tt.input, p.Incomplete(), tt.incomplete, p.Errors())
+		}
+	}
+}
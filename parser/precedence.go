@@ -9,11 +9,12 @@ const (
 	PRECED_AND    // &&
 	PRECED_EQ     // ==
 	PRECED_NEQ    // !=
-	PRECED_LT     // < > >= <=
+	PRECED_LT     // < > >= <= in
 	PRECED_ADD    // +
 	PRECED_MUL    // *
 	PRECED_PREFIX // -x !x
 	PRECED_CALL   // myFn(x)
+	PRECED_INDEX  // arr[i]
 )
 
 var (
@@ -22,18 +23,20 @@ var (
 		token.GT: PRECED_LT,
 		// ASSIGN
 		// NOT
-		token.ADD:    PRECED_ADD,
-		token.SUB:    PRECED_ADD,
-		token.MUL:    PRECED_MUL,
-		token.DIV:    PRECED_MUL,
-		token.MOD:    PRECED_MUL,
-		token.EQ:     PRECED_EQ,
-		token.NEQ:    PRECED_NEQ,
-		token.LEQ:    PRECED_LT,
-		token.GEQ:    PRECED_LT,
-		token.AND:    PRECED_AND,
-		token.OR:     PRECED_OR,
-		token.LPAREN: PRECED_CALL,
+		token.ADD:      PRECED_ADD,
+		token.SUB:      PRECED_ADD,
+		token.MUL:      PRECED_MUL,
+		token.DIV:      PRECED_MUL,
+		token.MOD:      PRECED_MUL,
+		token.EQ:       PRECED_EQ,
+		token.NEQ:      PRECED_NEQ,
+		token.LEQ:      PRECED_LT,
+		token.GEQ:      PRECED_LT,
+		token.AND:      PRECED_AND,
+		token.OR:       PRECED_OR,
+		token.IN:       PRECED_LT,
+		token.LPAREN:   PRECED_CALL,
+		token.LBRACKET: PRECED_INDEX,
 	}
 )
 
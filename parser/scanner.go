@@ -1,18 +1,23 @@
 package parser
 
 import (
+	"Q/diag"
 	"Q/lexer"
 	"Q/token"
 	"fmt"
+	"strings"
 )
 
 type scanner struct {
-	toks     []*token.Token
-	pos      int
-	curTok   *token.Token
-	peekTok  *token.Token
-	peekTok2 *token.Token
-	errors   []string
+	toks      []*token.Token
+	pos       int
+	curTok    *token.Token
+	peekTok   *token.Token
+	peekTok2  *token.Token
+	diags     []*diag.Diagnostic
+	panicking bool     // set by the first error of a statement, cleared by synchronize
+	loops     []string // labels of the loops enclosing curTok, empty for unlabeled loops
+	nested    int      // brackets enclosing curTok inside the innermost block, no statement ends in them
 }
 
 func newScanner(l *lexer.Lexer) (*scanner, error) {
@@ -20,7 +25,7 @@ func newScanner(l *lexer.Lexer) (*scanner, error) {
 	if nil == toks || len(toks) < 1 {
 		return nil, fmt.Errorf("newScanner -> no valid token")
 	}
-	s := &scanner{toks: toks, pos: 0, errors: []string{}}
+	s := &scanner{toks: toks, pos: 0, diags: []*diag.Diagnostic{}}
 	s.curTok = toks[0]
 	sz := len(toks)
 	if sz == 1 {
@@ -49,11 +54,104 @@ func (this *scanner) curPrecedence() int {
 }
 
 func (this *scanner) Errors() []string {
-	return this.errors
+	return diag.Strings(this.diags)
 }
 
-func (this *scanner) appendError(err string) {
-	this.errors = append(this.errors, err)
+// appendError reports err at curTok
+func (this *scanner) appendError(code string, err string) *diag.Diagnostic {
+	return this.appendErrorAt(code, this.curTok, err)
+}
+
+// appendErrorAt reports err at tok, the errors following the first one of a statement are
+// follow-on errors of it and are dropped until synchronize. An error at the end of the input
+// is reported as CodeIncomplete.
+func (this *scanner) appendErrorAt(code string, tok *token.Token, err string) *diag.Diagnostic {
+	if tok.Eof() || tok.Illegal() && unterminated(tok.Literal) {
+		code = diag.CodeIncomplete
+	}
+	d := diag.Errorf(code, tok.Pos, tok.Pos.Advance(tok.Literal), "%v", err)
+	if this.panicking {
+		return d
+	}
+	this.panicking = true
+	this.diags = append(this.diags, d)
+	return d
+}
+
+// unterminated reports whether lit is a string missing its closing quote
+func unterminated(lit string) bool {
+	if !strings.HasPrefix(lit, "\"") {
+		return false
+	}
+	for i := 1; i < len(lit); i++ {
+		switch lit[i] {
+		case '\\':
+			i++
+		case '"':
+			return false
+		}
+	}
+	return true
+}
+
+// synchronize skips the rest of a broken statement, curTok is left on its last token:
+// a `;`, the token before a `}` closing the enclosing block (inBlock) or before a statement keyword
+func (this *scanner) synchronize(inBlock bool) {
+	this.panicking = false
+	if inBlock && this.curTok.TypeIs(token.RBRACE) {
+		// the error is reported at the `}` closing the block, leave it to the block
+		this.prevToken()
+		return
+	}
+	switch this.curTok.Type {
+	case token.RBRACE, token.RPAREN, token.RBRACKET:
+		// a stray closing token is skipped alone, with the `;` following it
+		if this.peekTok.TypeIs(token.SEMICOLON) {
+			this.nextToken()
+		}
+		return
+	}
+	depth := 0
+	for !this.eof() {
+		switch this.curTok.Type {
+		case token.SEMICOLON:
+			if 0 == depth {
+				return
+			}
+		case token.LBRACE, token.LPAREN, token.LBRACKET:
+			depth++
+		case token.RBRACE, token.RPAREN, token.RBRACKET:
+			if depth > 0 {
+				depth--
+			}
+		}
+		if 0 == depth && (this.peekTok.Eof() || inBlock && this.peekTok.TypeIs(token.RBRACE) || isStmtKeyword(this.peekTok)) {
+			return
+		}
+		this.nextToken()
+	}
+}
+
+func isStmtKeyword(tok *token.Token) bool {
+	switch tok.Type {
+	case token.VAR, token.RETURN, token.FOR, token.IF, token.BREAK, token.CONTINUE, token.DELETE:
+		return true
+	default:
+		return false
+	}
+}
+
+func (this *scanner) prevToken() {
+	if 0 == this.pos {
+		return
+	}
+	this.pos--
+	this.curTok = this.toks[this.pos]
+	this.peekTok = this.toks[this.pos+1]
+	this.peekTok2 = this.peekTok
+	if !this.peekTok.Eof() {
+		this.peekTok2 = this.toks[this.pos+2]
+	}
 }
 
 func (this *scanner) nextToken() {
@@ -75,7 +173,11 @@ func (this *scanner) expectPeek(t token.TokenType) bool {
 		this.nextToken()
 		return true
 	}
-	this.appendError(fmt.Sprintf("expected next token to be %v, got %v instead", token.ToString(t), token.ToString(this.peekTok.Type)))
+	d := this.appendErrorAt(diag.CodeUnexpectedToken, this.peekTok, fmt.Sprintf("expected next token to be %v, got %v instead", token.ToString(t), token.ToString(this.peekTok.Type)))
+	if lit := token.LiteralOf(t); "" != lit {
+		end := this.curTok.Pos.Advance(this.curTok.Literal)
+		d.SetFix(&diag.Fix{Message: fmt.Sprintf("insert `%v`", lit), Start: end, End: end, Replacement: lit})
+	}
 	return false
 }
 
@@ -86,3 +188,77 @@ func (this *scanner) expectPeek2(t1 token.TokenType, t2 token.TokenType) bool {
 func (this *scanner) expectCurPeek(cur token.TokenType, peek token.TokenType) bool {
 	return this.curTok.TypeIs(cur) && this.peekTok.TypeIs(peek)
 }
+
+// lookAhead returns the token n positions after curTok, EOF when there is none
+func (this *scanner) lookAhead(n int) *token.Token {
+	pos := this.pos + n
+	if pos >= len(this.toks) {
+		return this.toks[len(this.toks)-1]
+	}
+	return this.toks[pos]
+}
+
+// insideParens reports whether t appears at the top level of the parentheses opened by curTok
+func (this *scanner) insideParens(t token.TokenType) bool {
+	depth := 0
+	for _, tok := range this.toks[this.pos+1:] {
+		switch {
+		case tok.Eof():
+			return false
+		case 0 == depth && tok.TypeIs(t):
+			return true
+		case tok.TypeIs(token.LPAREN) || tok.TypeIs(token.LBRACKET) || tok.TypeIs(token.LBRACE):
+			depth++
+		case tok.TypeIs(token.RPAREN) || tok.TypeIs(token.RBRACKET) || tok.TypeIs(token.RBRACE):
+			if 0 == depth {
+				return false
+			}
+			depth--
+		}
+	}
+	return false
+}
+
+// enterLoop is called before a loop body (or a labeled loop) is parsed, leaveLoop after it
+func (this *scanner) enterLoop(label string) {
+	this.loops = append(this.loops, label)
+}
+
+func (this *scanner) leaveLoop() {
+	this.loops = this.loops[:len(this.loops)-1]
+}
+
+// enterFunc hides the enclosing loops from a function body, the result is passed to leaveFunc
+func (this *scanner) enterFunc() []string {
+	loops := this.loops
+	this.loops = nil
+	return loops
+}
+
+func (this *scanner) leaveFunc(loops []string) {
+	this.loops = loops
+}
+
+func (this *scanner) insideLoop() bool {
+	return len(this.loops) > 0
+}
+
+// labels returns the labels of the enclosing loops, the innermost first
+func (this *scanner) labels() []string {
+	r := []string{}
+	for i := len(this.loops) - 1; i >= 0; i-- {
+		if "" != this.loops[i] {
+			r = append(r, this.loops[i])
+		}
+	}
+	return r
+}
+
+func (this *scanner) hasLabel(label string) bool {
+	for _, l := range this.loops {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,56 @@ package parser
 
 import (
 	"Q/ast"
+	"Q/diag"
 	"Q/token"
+	"fmt"
+	"strings"
 )
 
-func stmtEnd(scanner *scanner) bool {
-	return scanner.curTok.TypeIs(token.SEMICOLON) || scanner.curTok.Eof()
+// endStmt consumes the `;` ending a statement whose last expression is last, nil for none.
+// The `;` may be left out before a `}`, at the end of the input and after a block, a missing
+// one is reported on the token following the statement. A broken statement is left to synchronize.
+func endStmt(scanner *scanner, last ast.Expression) {
+	if scanner.panicking {
+		return
+	}
+	switch {
+	case scanner.peekTok.TypeIs(token.SEMICOLON):
+		scanner.nextToken()
+	case scanner.peekTok.TypeIs(token.RBRACE), scanner.peekTok.Eof(), endsWithBlock(last):
+	default:
+		d := scanner.appendErrorAt(diag.CodeUnexpectedToken, scanner.peekTok, fmt.Sprintf("expected ; after the statement, got %v instead", token.ToString(scanner.peekTok.Type)))
+		end := scanner.curTok.Pos.Advance(scanner.curTok.Literal)
+		d.SetFix(&diag.Fix{Message: "insert `;`", Start: end, End: end, Replacement: ";"})
+		// synchronize from the unexpected token, unless it starts the next statement
+		if !isStmtKeyword(scanner.peekTok) {
+			scanner.nextToken()
+		}
+	}
+}
+
+// endsWithBlock reports whether expr ends with the block of an if, a loop or a function
+func endsWithBlock(expr ast.Expression) bool {
+	switch e := expr.(type) {
+	case *ast.IfExpression, *ast.ForExpression, *ast.ForInExpression, *ast.Function:
+		return true
+	case *ast.PrefixExpression:
+		return endsWithBlock(e.Right)
+	case *ast.InfixExpression:
+		return endsWithBlock(e.Right)
+	default:
+		return false
+	}
+}
+
+// endsBeforePeek reports whether the statement ending with the block of expr stops before the
+// peek token, a [ or ( on a later line starts the next statement rather than indexing or calling it
+func (this *Parser) endsBeforePeek(expr ast.Expression) bool {
+	peek := this.scanner.peekTok
+	if this.scanner.nested > 0 || !peek.TypeIs(token.LBRACKET) && !peek.TypeIs(token.LPAREN) {
+		return false
+	}
+	return peek.Pos.Line > this.scanner.curTok.Pos.Line && endsWithBlock(expr)
 }
 
 type stmtDecoder interface {
@@ -14,10 +59,11 @@ type stmtDecoder interface {
 }
 
 type stmtParser struct {
-	scanner       *scanner
-	assignDecoder stmtDecoder
-	exprDecoder   stmtDecoder
-	m             map[token.TokenType]stmtDecoder
+	scanner        *scanner
+	assignDecoder  stmtDecoder
+	exprDecoder    stmtDecoder
+	labeledDecoder stmtDecoder
+	m              map[token.TokenType]stmtDecoder
 }
 
 func (this *stmtParser) isAssignStmt() bool {
@@ -32,18 +78,25 @@ func (this *stmtParser) decode(t token.TokenType) ast.Statement {
 	if this.isAssignStmt() {
 		return this.assignDecoder.decode()
 	}
+	if this.scanner.expectCurPeek(token.IDENT, token.COLON) {
+		return this.labeledDecoder.decode()
+	}
 	return this.exprDecoder.decode()
 }
 
 func newStmtParser(s *scanner, parseExpression parseExpressionFn) *stmtParser {
+	exprDecoder := &exprStmt{s, parseExpression}
 	return &stmtParser{
-		scanner:       s,
-		assignDecoder: &assignStmt{s, parseExpression},
-		exprDecoder:   &exprStmt{s, parseExpression},
+		scanner:        s,
+		assignDecoder:  &assignStmt{s, parseExpression},
+		exprDecoder:    exprDecoder,
+		labeledDecoder: &labeledStmt{s, exprDecoder},
 		m: map[token.TokenType]stmtDecoder{
-			token.VAR:    &varStmt{s, parseExpression},
-			token.RETURN: &returnStmt{s, parseExpression},
-			token.BREAK:  &breakStmt{s},
+			token.VAR:      &varStmt{s, parseExpression},
+			token.RETURN:   &returnStmt{s, parseExpression},
+			token.BREAK:    &loopCtrlStmt{s},
+			token.CONTINUE: &loopCtrlStmt{s},
+			token.DELETE:   &deleteStmt{s, parseExpression},
 		},
 	}
 }
@@ -68,9 +121,7 @@ func (this *varStmt) decode() ast.Statement {
 
 	stmt.Value = this.parseExpression(PRECED_LOWEST)
 
-	for !stmtEnd(this.scanner) {
-		this.scanner.nextToken()
-	}
+	endStmt(this.scanner, stmt.Value)
 	return stmt
 }
 
@@ -86,9 +137,7 @@ func (this *returnStmt) decode() ast.Statement {
 
 	stmt.ReturnValue = this.parseExpression(PRECED_LOWEST)
 
-	for !stmtEnd(this.scanner) {
-		this.scanner.nextToken()
-	}
+	endStmt(this.scanner, stmt.ReturnValue)
 	return stmt
 }
 
@@ -102,19 +151,93 @@ func (this *exprStmt) decode() ast.Statement {
 	stmt := &ast.ExpressionStmt{Tok: this.scanner.curTok}
 	stmt.Expr = this.parseExpression(PRECED_LOWEST)
 
-	if this.scanner.peekTok.TypeIs(token.SEMICOLON) {
-		this.scanner.nextToken()
+	if index, ok := stmt.Expr.(*ast.Index); ok && this.scanner.peekTok.TypeIs(token.ASSIGN) {
+		return this.decodeIndexAssign(index)
 	}
+	endStmt(this.scanner, stmt.Expr)
 	return stmt
 }
 
-// breakStmt : implement stmtDecoder
-type breakStmt struct {
+// decodeIndexAssign decodes `a[i] = v`, the peekTok is ASSIGN
+func (this *exprStmt) decodeIndexAssign(index *ast.Index) ast.Statement {
+	stmt := &ast.AssignStmt{Index: index}
+	this.scanner.nextToken()
+	this.scanner.nextToken()
+
+	stmt.Value = this.parseExpression(PRECED_LOWEST)
+
+	endStmt(this.scanner, stmt.Value)
+	return stmt
+}
+
+// loopCtrlStmt : implement stmtDecoder
+// it decodes `break`, `continue`, `break label` and `continue label`
+type loopCtrlStmt struct {
 	scanner *scanner
 }
 
-func (this *breakStmt) decode() ast.Statement {
-	return &ast.BreakStmt{Tok: this.scanner.curTok}
+func (this *loopCtrlStmt) decode() ast.Statement {
+	tok := this.scanner.curTok
+	var label *ast.Identifier
+	if this.scanner.peekTok.TypeIs(token.IDENT) {
+		this.scanner.nextToken()
+		label = &ast.Identifier{Tok: this.scanner.curTok, Value: this.scanner.curTok.Literal}
+		if !this.scanner.insideLoop() {
+			this.scanner.appendError(diag.CodeBadLabel, fmt.Sprintf("%v %v outside loop", tok.Literal, label.Value))
+			return nil
+		}
+		if !this.scanner.hasLabel(label.Value) {
+			d := this.scanner.appendError(diag.CodeBadLabel, fmt.Sprintf("unknown label %v", label.Value))
+			if labels := this.scanner.labels(); len(labels) > 0 {
+				d.AddNote("labels of the enclosing loops: %v", strings.Join(labels, ", "))
+			}
+			return nil
+		}
+	}
+	endStmt(this.scanner, nil)
+	if tok.TypeIs(token.CONTINUE) {
+		return &ast.ContinueStmt{Tok: tok, Label: label}
+	}
+	return &ast.BreakStmt{Tok: tok, Label: label}
+}
+
+// labeledStmt : implement stmtDecoder
+// it decodes `label: for ...`
+type labeledStmt struct {
+	scanner     *scanner
+	exprDecoder *exprStmt
+}
+
+func (this *labeledStmt) decode() ast.Statement {
+	label := &ast.Identifier{Tok: this.scanner.curTok, Value: this.scanner.curTok.Literal}
+	this.scanner.nextToken()
+	if !this.scanner.peekTok.TypeIs(token.FOR) {
+		this.scanner.appendError(diag.CodeBadLabel, fmt.Sprintf("label %v must be followed by a for loop", label.Value))
+		return nil
+	}
+	if this.scanner.hasLabel(label.Value) {
+		this.scanner.appendError(diag.CodeBadLabel, fmt.Sprintf("label %v already defined", label.Value))
+		return nil
+	}
+	this.scanner.nextToken()
+
+	this.scanner.enterLoop(label.Value)
+	stmt := this.exprDecoder.decode()
+	this.scanner.leaveLoop()
+
+	exprStmt, ok := stmt.(*ast.ExpressionStmt)
+	if !ok {
+		return nil
+	}
+	switch loop := exprStmt.Expr.(type) {
+	case *ast.ForExpression:
+		loop.Label = label
+	case *ast.ForInExpression:
+		loop.Label = label
+	default:
+		return nil
+	}
+	return stmt
 }
 
 // assignStmt : implement stmtDecoder
@@ -134,8 +257,26 @@ func (this *assignStmt) decode() ast.Statement {
 
 	stmt.Value = this.parseExpression(PRECED_LOWEST)
 
-	for !stmtEnd(this.scanner) {
-		this.scanner.nextToken()
+	endStmt(this.scanner, stmt.Value)
+	return stmt
+}
+
+// deleteStmt : implement stmtDecoder
+type deleteStmt struct {
+	scanner         *scanner
+	parseExpression parseExpressionFn
+}
+
+func (this *deleteStmt) decode() ast.Statement {
+	stmt := &ast.DeleteStmt{Tok: this.scanner.curTok}
+	this.scanner.nextToken()
+	index, ok := this.parseExpression(PRECED_LOWEST).(*ast.Index)
+	if !ok {
+		this.scanner.appendError(diag.CodeBadDelete, "expected index expression after delete")
+		return nil
 	}
+	stmt.Index = index
+
+	endStmt(this.scanner, nil)
 	return stmt
 }
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"Q/ast"
+	"Q/diag"
 	"Q/token"
 	"fmt"
 	"strconv"
@@ -17,29 +18,38 @@ func newTokenDecoders(
 	s *scanner,
 	parseExpression parseExpressionFn,
 	parseBlockStmt parseBlockStmtFn,
+	parseStmt parseStmtFn,
 ) tokenDecoderMap {
 	identifierDecoder := &identifier{s}
 	integerDecoder := &integer{s}
+	floatDecoder := &float{s}
+	stringDecoder := &stringLiteral{s}
 	booleanDecoder := &boolean{s}
 	nullDecoder := &null{s}
 	prefixExprDecoder := &prefixExpr{s, parseExpression}
 	groupedExprDecoder := &groupedExpr{s, parseExpression}
 	ifExprDecoder := &ifExpr{s, parseExpression, parseBlockStmt}
 	funcDecoder := &funcLiteral{s, parseExpression, parseBlockStmt}
-	forExprDecoder := &forExpr{s, parseBlockStmt}
+	forExprDecoder := &forExpr{s, parseExpression, parseBlockStmt, parseStmt}
+	arrayDecoder := &arrayLiteral{s, parseExpression}
+	hashDecoder := &hashLiteral{s, parseExpression}
 
 	return tokenDecoderMap{
-		token.IDENT:  identifierDecoder,
-		token.INT:    integerDecoder,
-		token.TRUE:   booleanDecoder,
-		token.FALSE:  booleanDecoder,
-		token.NULL:   nullDecoder,
-		token.NOT:    prefixExprDecoder,
-		token.SUB:    prefixExprDecoder,
-		token.LPAREN: groupedExprDecoder,
-		token.IF:     ifExprDecoder,
-		token.FUNC:   funcDecoder,
-		token.FOR:    forExprDecoder,
+		token.IDENT:    identifierDecoder,
+		token.INT:      integerDecoder,
+		token.FLOAT:    floatDecoder,
+		token.STRING:   stringDecoder,
+		token.TRUE:     booleanDecoder,
+		token.FALSE:    booleanDecoder,
+		token.NULL:     nullDecoder,
+		token.NOT:      prefixExprDecoder,
+		token.SUB:      prefixExprDecoder,
+		token.LPAREN:   groupedExprDecoder,
+		token.IF:       ifExprDecoder,
+		token.FUNC:     funcDecoder,
+		token.FOR:      forExprDecoder,
+		token.LBRACKET: arrayDecoder,
+		token.LBRACE:   hashDecoder,
 	}
 }
 
@@ -70,13 +80,38 @@ func (this *integer) decode() ast.Expression {
 	expr := &ast.Integer{Tok: this.scanner.curTok}
 	val, err := strconv.ParseInt(this.scanner.curTok.Literal, 0, 64)
 	if nil != err {
-		this.scanner.appendError(fmt.Sprintf("could not parse %v as integer", this.scanner.curTok.Literal))
+		this.scanner.appendError(diag.CodeBadLiteral, fmt.Sprintf("could not parse %v as integer", this.scanner.curTok.Literal))
 		return nil
 	}
 	expr.Value = val
 	return expr
 }
 
+// float : implement tokenDecoder
+type float struct {
+	scanner *scanner
+}
+
+func (this *float) decode() ast.Expression {
+	expr := &ast.Float{Tok: this.scanner.curTok}
+	val, err := strconv.ParseFloat(this.scanner.curTok.Literal, 64)
+	if nil != err {
+		this.scanner.appendError(diag.CodeBadLiteral, fmt.Sprintf("could not parse %v as float", this.scanner.curTok.Literal))
+		return nil
+	}
+	expr.Value = val
+	return expr
+}
+
+// stringLiteral : implement tokenDecoder
+type stringLiteral struct {
+	scanner *scanner
+}
+
+func (this *stringLiteral) decode() ast.Expression {
+	return &ast.String{Tok: this.scanner.curTok, Value: this.scanner.curTok.Literal}
+}
+
 // null : implement tokenDecoder
 type null struct {
 	scanner *scanner
@@ -110,7 +145,9 @@ type groupedExpr struct {
 
 func (this *groupedExpr) decode() ast.Expression {
 	this.scanner.nextToken()
+	this.scanner.nested++
 	expr := this.parseExpression(PRECED_LOWEST)
+	this.scanner.nested--
 	if !this.scanner.expectPeek(token.RPAREN) {
 		return nil
 	}
@@ -226,21 +263,188 @@ func (this *funcLiteral) decode() ast.Expression {
 	if !this.scanner.expectPeek(token.LBRACE) {
 		return nil
 	}
+	loops := this.scanner.enterFunc()
 	lit.Body = this.parseBlockStmt()
+	this.scanner.leaveFunc(loops)
 	return lit
 }
 
 // forExpr : implement tokenDecoder
 type forExpr struct {
-	scanner        *scanner
-	parseBlockStmt parseBlockStmtFn
+	scanner         *scanner
+	parseExpression parseExpressionFn
+	parseBlockStmt  parseBlockStmtFn
+	parseStmt       parseStmtFn
 }
 
 func (this *forExpr) decode() ast.Expression {
 	expr := &ast.ForExpression{Tok: this.scanner.curTok}
+	if this.scanner.peekTok.TypeIs(token.LPAREN) {
+		this.scanner.nextToken()
+		if this.isForIn() {
+			return this.decodeForIn(expr.Tok)
+		}
+		if this.scanner.insideParens(token.SEMICOLON) {
+			if !this.decodeClauses(expr) {
+				return nil
+			}
+		} else {
+			this.scanner.nextToken()
+			expr.Cond = this.parseExpression(PRECED_LOWEST)
+			if !this.scanner.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+	}
 	if !this.scanner.expectPeek(token.LBRACE) {
 		return nil
 	}
-	expr.Loop = this.parseBlockStmt()
+	expr.Loop = this.parseLoop()
+	return expr
+}
+
+func (this *forExpr) parseLoop() *ast.BlockStmt {
+	this.scanner.enterLoop("")
+	defer this.scanner.leaveLoop()
+	return this.parseBlockStmt()
+}
+
+// isForIn detects `(v in` and `(k, v in`, the curTok is LPAREN
+func (this *forExpr) isForIn() bool {
+	if this.scanner.expectPeek2(token.IDENT, token.IN) {
+		return true
+	}
+	return this.scanner.expectPeek2(token.IDENT, token.COMMA) &&
+		this.scanner.lookAhead(3).TypeIs(token.IDENT) &&
+		this.scanner.lookAhead(4).TypeIs(token.IN)
+}
+
+func (this *forExpr) decodeForIn(tok *token.Token) ast.Expression {
+	expr := &ast.ForInExpression{Tok: tok}
+	this.scanner.nextToken()
+	expr.Value = &ast.Identifier{Tok: this.scanner.curTok, Value: this.scanner.curTok.Literal}
+	if this.scanner.peekTok.TypeIs(token.COMMA) {
+		this.scanner.nextToken()
+		this.scanner.nextToken()
+		expr.Key = expr.Value
+		expr.Value = &ast.Identifier{Tok: this.scanner.curTok, Value: this.scanner.curTok.Literal}
+		if expr.Key.Value == expr.Value.Value {
+			this.scanner.appendError(diag.CodeBadLoop, fmt.Sprintf("duplicate loop variable %v", expr.Key.Value))
+			return nil
+		}
+	}
+	this.scanner.nextToken()
+	this.scanner.nextToken()
+	expr.Iterable = this.parseExpression(PRECED_LOWEST)
+	if nil == expr.Iterable {
+		return nil
+	}
+	if !this.scanner.expectPeek(token.RPAREN) {
+		return nil
+	}
+	if !this.scanner.expectPeek(token.LBRACE) {
+		return nil
+	}
+	expr.Loop = this.parseLoop()
+	return expr
+}
+
+// decodeClauses decodes `(init; cond; post)`, the curTok is LPAREN
+func (this *forExpr) decodeClauses(expr *ast.ForExpression) bool {
+	expr.CStyle = true
+	this.scanner.nextToken()
+	if !this.scanner.curTok.TypeIs(token.SEMICOLON) {
+		expr.Init = this.parseStmt()
+		if nil == expr.Init {
+			return false
+		}
+		if !this.scanner.curTok.TypeIs(token.SEMICOLON) {
+			this.scanner.appendError(diag.CodeBadLoop, fmt.Sprintf("expected ; after for init, got %v instead", token.ToString(this.scanner.curTok.Type)))
+			return false
+		}
+	}
+	this.scanner.nextToken()
+	if !this.scanner.curTok.TypeIs(token.SEMICOLON) {
+		expr.Cond = this.parseExpression(PRECED_LOWEST)
+		if !this.scanner.expectPeek(token.SEMICOLON) {
+			return false
+		}
+	}
+	this.scanner.nextToken()
+	if !this.scanner.curTok.TypeIs(token.RPAREN) {
+		expr.Post = this.decodePost()
+		if nil == expr.Post || !this.scanner.expectPeek(token.RPAREN) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodePost decodes an assignment or an expression which ends at `)` instead of `;`
+func (this *forExpr) decodePost() ast.Statement {
+	if this.scanner.expectCurPeek(token.IDENT, token.ASSIGN) {
+		stmt := &ast.AssignStmt{Name: &ast.Identifier{Tok: this.scanner.curTok, Value: this.scanner.curTok.Literal}}
+		this.scanner.nextToken()
+		this.scanner.nextToken()
+		stmt.Value = this.parseExpression(PRECED_LOWEST)
+		return stmt
+	}
+	tok := this.scanner.curTok
+	left := this.parseExpression(PRECED_LOWEST)
+	if index, ok := left.(*ast.Index); ok && this.scanner.peekTok.TypeIs(token.ASSIGN) {
+		stmt := &ast.AssignStmt{Index: index}
+		this.scanner.nextToken()
+		this.scanner.nextToken()
+		stmt.Value = this.parseExpression(PRECED_LOWEST)
+		return stmt
+	}
+	if nil == left {
+		return nil
+	}
+	return &ast.ExpressionStmt{Tok: tok, Expr: left}
+}
+
+// arrayLiteral : implement tokenDecoder
+type arrayLiteral struct {
+	scanner         *scanner
+	parseExpression parseExpressionFn
+}
+
+func (this *arrayLiteral) decode() ast.Expression {
+	expr := &ast.Array{Tok: this.scanner.curTok}
+	expr.Elements = parseExpressionList(this.scanner, this.parseExpression, token.RBRACKET)
+	if nil == expr.Elements {
+		return nil
+	}
+	return expr
+}
+
+// hashLiteral : implement tokenDecoder
+// blocks only follow if/else/for/func, so `{` in expression position is always a hash
+type hashLiteral struct {
+	scanner         *scanner
+	parseExpression parseExpressionFn
+}
+
+func (this *hashLiteral) decode() ast.Expression {
+	expr := &ast.Hash{Tok: this.scanner.curTok, Pairs: ast.HashPairSlice{}}
+	for !this.scanner.peekTok.TypeIs(token.RBRACE) {
+		this.scanner.nextToken()
+		this.scanner.nested++
+		pair := &ast.HashPair{Key: this.parseExpression(PRECED_LOWEST)}
+		this.scanner.nested--
+		if !this.scanner.expectPeek(token.COLON) {
+			return nil
+		}
+		this.scanner.nextToken()
+		this.scanner.nested++
+		pair.Value = this.parseExpression(PRECED_LOWEST)
+		this.scanner.nested--
+		expr.Pairs = append(expr.Pairs, pair)
+		if !this.scanner.peekTok.TypeIs(token.RBRACE) && !this.scanner.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	this.scanner.nextToken()
 	return expr
 }
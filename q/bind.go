@@ -0,0 +1,254 @@
+package q
+
+import (
+	"fmt"
+	"reflect"
+	"runtime"
+
+	"Q/object"
+)
+
+var (
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+)
+
+// Func returns a builtin calling the Go func fn, for example
+//
+//	func(n int64, s string) (bool, error)
+//	func(format string, a ...interface{}) string
+//
+// The arguments are converted to the parameter types: integer to the integer types, float
+// or integer to the float types, boolean to bool, string to string, array to slices and
+// arrays, hash to maps, null to nil pointers, slices, maps and interfaces, and any value
+// to interface{} (as ToGo does, an argument ToGo rejects fails the call with its
+// *ConversionError) or object.Object (unconverted). A variadic fn takes any number of
+// trailing arguments.
+//
+// The results are converted with ToObject: none is null, one is its value and more are an
+// array. A last result of type error is not converted, a non nil one fails the call, as
+// does a panic of fn.
+func Func(name string, fn interface{}) (*object.Builtin, error) {
+	v := reflect.ValueOf(fn)
+	if reflect.Func != v.Kind() || v.IsNil() {
+		return nil, &ConversionError{Value: fn, Reason: "not a func"}
+	}
+	return bindFunc(name, v), nil
+}
+
+func bindFunc(name string, fn reflect.Value) *object.Builtin {
+	if "" == name {
+		name = runtime.FuncForPC(fn.Pointer()).Name()
+	}
+	return &object.Builtin{Name: name, Fn: func(args []object.Object) (object.Object, error) {
+		in, err := goArgs(fn.Type(), args)
+		if nil != err {
+			return nil, err
+		}
+		out, err := callFunc(fn, in)
+		if nil != err {
+			return nil, err
+		}
+		return goResults(fn.Type(), out)
+	}}
+}
+
+// callFunc calls fn, a panic is returned as an error
+func callFunc(fn reflect.Value, in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); nil != r {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn.Call(in), nil
+}
+
+func goArgs(t reflect.Type, args []object.Object) ([]reflect.Value, error) {
+	n := t.NumIn()
+	if t.IsVariadic() {
+		if len(args) < n-1 {
+			return nil, fmt.Errorf("%v args provided, but at least %v args required", len(args), n-1)
+		}
+	} else if err := object.CheckArgs(args, n); nil != err {
+		return nil, err
+	}
+	in := make([]reflect.Value, 0, len(args))
+	for i, arg := range args {
+		var pt reflect.Type
+		if t.IsVariadic() && i >= n-1 {
+			pt = t.In(n - 1).Elem()
+		} else {
+			pt = t.In(i)
+		}
+		v, err := fromObject(arg, pt)
+		if nil != err {
+			return nil, fmt.Errorf("argument %v: %w", i+1, err)
+		}
+		in = append(in, v)
+	}
+	return in, nil
+}
+
+func goResults(t reflect.Type, out []reflect.Value) (object.Object, error) {
+	if n := t.NumOut(); n > 0 && t.Out(n-1) == errorType {
+		if err := out[n-1]; !err.IsNil() {
+			return nil, err.Interface().(error)
+		}
+		out = out[:n-1]
+	}
+	results := make([]object.Object, 0, len(out))
+	for _, v := range out {
+		obj, err := toObject(v.Interface(), v, map[visit]bool{})
+		if nil != err {
+			return nil, fmt.Errorf("result: %w", err)
+		}
+		results = append(results, obj)
+	}
+	switch len(results) {
+	case 0:
+		return object.Nil, nil
+	case 1:
+		return results[0], nil
+	default:
+		return &object.Array{Elements: results}, nil
+	}
+}
+
+// fromObject converts obj to a value of type t, see Func
+func fromObject(obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if objectType == t || t.Implements(objectType) && reflect.TypeOf(obj) == t {
+		return reflect.ValueOf(obj), nil
+	}
+	if reflect.Interface == t.Kind() && 0 == t.NumMethod() {
+		v, err := ToGo(obj)
+		if nil != err {
+			return reflect.Value{}, err
+		}
+		if nil != v {
+			return reflect.ValueOf(v), nil
+		}
+		return reflect.Zero(t), nil
+	}
+	if _, ok := obj.(*object.Null); ok {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, mismatch(obj, t)
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Bool:
+		b, ok := obj.(*object.Boolean)
+		if !ok {
+			return v, mismatch(obj, t)
+		}
+		v.SetBool(b.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return v, mismatch(obj, t)
+		}
+		if v.OverflowInt(i.Value) {
+			return v, fmt.Errorf("%v overflows %v", i.Value, t)
+		}
+		v.SetInt(i.Value)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, ok := obj.(*object.Integer)
+		if !ok {
+			return v, mismatch(obj, t)
+		}
+		if i.Value < 0 || v.OverflowUint(uint64(i.Value)) {
+			return v, fmt.Errorf("%v overflows %v", i.Value, t)
+		}
+		v.SetUint(uint64(i.Value))
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *object.Float:
+			v.SetFloat(n.Value)
+		case *object.Integer:
+			v.SetFloat(float64(n.Value))
+		default:
+			return v, mismatch(obj, t)
+		}
+	case reflect.String:
+		s, ok := obj.(*object.String)
+		if !ok {
+			return v, mismatch(obj, t)
+		}
+		v.SetString(s.Value)
+	case reflect.Slice, reflect.Array:
+		arr, ok := obj.(*object.Array)
+		if !ok {
+			return v, mismatch(obj, t)
+		}
+		if reflect.Array == t.Kind() && len(arr.Elements) != t.Len() {
+			return v, fmt.Errorf("must be an array of %v elements, got %v", t.Len(), len(arr.Elements))
+		}
+		if reflect.Slice == t.Kind() {
+			v = reflect.MakeSlice(t, len(arr.Elements), len(arr.Elements))
+		}
+		for i, e := range arr.Elements {
+			ev, err := fromObject(e, t.Elem())
+			if nil != err {
+				return v, fmt.Errorf("element %v: %w", i, err)
+			}
+			v.Index(i).Set(ev)
+		}
+	case reflect.Map:
+		hash, ok := obj.(*object.Hash)
+		if !ok {
+			return v, mismatch(obj, t)
+		}
+		v = reflect.MakeMapWithSize(t, hash.Len())
+		for _, pair := range hash.Pairs() {
+			k, err := fromObject(pair.Key, t.Key())
+			if nil != err {
+				return v, fmt.Errorf("key %v: %w", pair.Key.Inspect(), err)
+			}
+			e, err := fromObject(pair.Value, t.Elem())
+			if nil != err {
+				return v, fmt.Errorf("value of %v: %w", pair.Key.Inspect(), err)
+			}
+			v.SetMapIndex(k, e)
+		}
+	case reflect.Ptr:
+		e, err := fromObject(obj, t.Elem())
+		if nil != err {
+			return v, err
+		}
+		v = reflect.New(t.Elem())
+		v.Elem().Set(e)
+	default:
+		return v, fmt.Errorf("%v parameters are not supported", t)
+	}
+	return v, nil
+}
+
+// mismatch reports obj passed for a parameter of type t
+func mismatch(obj object.Object, t reflect.Type) error {
+	return fmt.Errorf("must be %v, got %v", qTypeOf(t), object.ToString(obj.Type()))
+}
+
+// qTypeOf names the Q type converting to t
+func qTypeOf(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float or integer"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map:
+		return "hash"
+	case reflect.Ptr:
+		return qTypeOf(t.Elem()) + " or null"
+	default:
+		return t.String()
+	}
+}
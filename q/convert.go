@@ -0,0 +1,267 @@
+package q
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"Q/object"
+)
+
+// ToObject returns the Q counterpart of v:
+//
+//	nil, nil pointers, maps and slices  null
+//	bool                                boolean
+//	signed and unsigned integers        integer, an uint beyond int64 is an error
+//	float32, float64                    float
+//	string                              string
+//	slices and arrays                   array
+//	maps with string, integer or bool   hash
+//	keys
+//	pointers                            the value pointed to
+//	funcs                               builtin, see Func
+//	object.Object                       itself
+//
+// Named types convert like their underlying type, any other type is a *ConversionError.
+func ToObject(v interface{}) (object.Object, error) {
+	if obj, ok := v.(object.Object); ok {
+		return obj, nil
+	}
+	if nil == v {
+		return object.Nil, nil
+	}
+	return toObject(v, reflect.ValueOf(v), map[visit]bool{})
+}
+
+// visit is a pointer, map or slice being converted, meeting it again inside itself is a cycle
+type visit struct {
+	t   reflect.Type
+	ptr uintptr
+	len int
+}
+
+func toObject(orig interface{}, v reflect.Value, visiting map[visit]bool) (object.Object, error) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return object.Nil, nil
+		}
+		key := visit{t: v.Type(), ptr: v.Pointer()}
+		if reflect.Ptr != v.Kind() {
+			key.len = v.Len()
+		}
+		if visiting[key] {
+			return nil, &ConversionError{Value: orig, Reason: "it contains itself"}
+		}
+		visiting[key] = true
+		defer delete(visiting, key)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return object.ToBoolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, &ConversionError{Value: orig, Reason: "out of the integer range"}
+		}
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return object.Nil, nil
+		}
+		if obj, ok := v.Interface().(object.Object); ok {
+			return obj, nil
+		}
+		return toObject(orig, v.Elem(), visiting)
+	case reflect.Slice, reflect.Array:
+		if reflect.Slice == v.Kind() && v.IsNil() {
+			return object.Nil, nil
+		}
+		elements := make([]object.Object, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			e, err := toObject(orig, v.Index(i), visiting)
+			if nil != err {
+				return nil, err
+			}
+			elements = append(elements, e)
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return object.Nil, nil
+		}
+		// the keys are sorted, the order of a Go map is random
+		hash := object.NewHash()
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return lessKey(keys[i], keys[j])
+		})
+		for _, k := range keys {
+			key, err := toObject(orig, k, visiting)
+			if nil != err {
+				return nil, err
+			}
+			if _, ok := key.(object.Hashable); !ok {
+				return nil, &ConversionError{Value: orig, Reason: "map keys must be strings, integers or booleans"}
+			}
+			val, err := toObject(orig, v.MapIndex(k), visiting)
+			if nil != err {
+				return nil, err
+			}
+			if err := hash.Set(key, val); nil != err {
+				return nil, &ConversionError{Value: orig, Reason: err.Error()}
+			}
+		}
+		return hash, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return object.Nil, nil
+		}
+		return bindFunc("", v), nil
+	default:
+		return nil, &ConversionError{Value: orig, Reason: v.Type().String() + " is not supported"}
+	}
+}
+
+func lessKey(a reflect.Value, b reflect.Value) bool {
+	switch a.Kind() {
+	case reflect.String:
+		return a.String() < b.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() < b.Uint()
+	case reflect.Bool:
+		return !a.Bool() && b.Bool()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float()
+	default:
+		return false
+	}
+}
+
+// maxGoRange bounds the ranges converted to slices when no collection size limit applies
+const maxGoRange = 1 << 24
+
+// ToGo returns the Go counterpart of obj:
+//
+//	null      nil
+//	boolean   bool
+//	integer   int64
+//	float     float64
+//	string    string
+//	array     []interface{}
+//	range     []interface{} of int64
+//	hash      map[string]interface{} when every key is a string,
+//	          map[interface{}]interface{} otherwise
+//
+// Functions and builtins are returned as the object itself. An array or hash containing
+// itself, or a range longer than the collection size limit of DefaultLimits, is a
+// *ConversionError.
+func ToGo(obj object.Object) (interface{}, error) {
+	return toGo(obj, DefaultLimits.MaxCollectionSize)
+}
+
+// toGo converts obj with the ranges bounded by max, the collection size limit of a run
+func toGo(obj object.Object, max int) (interface{}, error) {
+	if max <= 0 {
+		max = maxGoRange
+	}
+	return (&goConverter{max: int64(max), visiting: map[object.Object]bool{}}).convert(obj)
+}
+
+// goConverter holds the arrays and hashes being converted, meeting one again is a cycle
+type goConverter struct {
+	max      int64
+	visiting map[object.Object]bool
+}
+
+func (this *goConverter) convert(obj object.Object) (interface{}, error) {
+	switch v := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Boolean:
+		return v.Value, nil
+	case *object.Integer:
+		return v.Value, nil
+	case *object.Float:
+		return v.Value, nil
+	case *object.String:
+		return v.Value, nil
+	case *object.Array:
+		if err := this.enter(v); nil != err {
+			return nil, err
+		}
+		defer delete(this.visiting, v)
+		r := make([]interface{}, 0, len(v.Elements))
+		for _, e := range v.Elements {
+			ge, err := this.convert(e)
+			if nil != err {
+				return nil, err
+			}
+			r = append(r, ge)
+		}
+		return r, nil
+	case *object.Range:
+		if v.Len() > this.max {
+			return nil, &ConversionError{Value: obj, Reason: fmt.Sprintf("%v elements are more than %v", v.Len(), this.max)}
+		}
+		r := make([]interface{}, 0, v.Len())
+		for i := int64(0); i < v.Len(); i++ {
+			r = append(r, v.At(i))
+		}
+		return r, nil
+	case *object.Hash:
+		if err := this.enter(v); nil != err {
+			return nil, err
+		}
+		defer delete(this.visiting, v)
+		return this.hash(v)
+	default:
+		return obj, nil
+	}
+}
+
+func (this *goConverter) enter(container object.Object) error {
+	if this.visiting[container] {
+		return &ConversionError{Value: container, Reason: "it contains itself"}
+	}
+	this.visiting[container] = true
+	return nil
+}
+
+func (this *goConverter) hash(hash *object.Hash) (interface{}, error) {
+	pairs := hash.Pairs()
+	for _, pair := range pairs {
+		if _, ok := pair.Key.(*object.String); !ok {
+			r := map[interface{}]interface{}{}
+			for _, pair := range pairs {
+				k, err := this.convert(pair.Key)
+				if nil != err {
+					return nil, err
+				}
+				v, err := this.convert(pair.Value)
+				if nil != err {
+					return nil, err
+				}
+				r[k] = v
+			}
+			return r, nil
+		}
+	}
+	r := map[string]interface{}{}
+	for _, pair := range pairs {
+		v, err := this.convert(pair.Value)
+		if nil != err {
+			return nil, err
+		}
+		r[pair.Key.(*object.String).Value] = v
+	}
+	return r, nil
+}
@@ -0,0 +1,86 @@
+package q
+
+import (
+	"io"
+	"os"
+	"reflect"
+
+	"Q/object"
+)
+
+// Limits bound a run, a zero field is no limit
+type Limits = object.Limits
+
+// DefaultLimits is the Limits of an Env without WithLimits, the call depth is bounded so a
+// runaway recursion fails with a LimitError instead of exhausting the Go stack
+var DefaultLimits = Limits{MaxCallDepth: 10000}
+
+// Env holds the globals of the runs using it
+type Env struct {
+	env    *object.Env
+	stdout io.Writer
+	stderr io.Writer
+	limits Limits
+}
+
+// EnvOption configures NewEnv
+type EnvOption func(*Env)
+
+// WithStdout sets the writer of print and println, os.Stdout by default
+func WithStdout(w io.Writer) EnvOption {
+	return func(e *Env) {
+		e.stdout = w
+	}
+}
+
+// WithStderr sets the writer of eprint and eprintln, os.Stderr by default
+func WithStderr(w io.Writer) EnvOption {
+	return func(e *Env) {
+		e.stderr = w
+	}
+}
+
+// WithLimits bounds every run in the Env, each run counts from zero
+func WithLimits(limits Limits) EnvOption {
+	return func(e *Env) {
+		e.limits = limits
+	}
+}
+
+// NewEnv returns an Env with the core builtins and no globals
+func NewEnv(opts ...EnvOption) *Env {
+	this := &Env{stdout: os.Stdout, stderr: os.Stderr, limits: DefaultLimits}
+	for _, opt := range opts {
+		opt(this)
+	}
+	this.env = object.NewEnv()
+	this.env.SetBuiltins(object.NewBuiltinsTo(this.stdout, this.stderr))
+	return this
+}
+
+// Set defines the global name as the Q counterpart of v, see ToObject. A func is bound
+// with Func under name.
+func (this *Env) Set(name string, v interface{}) error {
+	var obj object.Object
+	var err error
+	if fn := reflect.ValueOf(v); reflect.Func == fn.Kind() && !fn.IsNil() {
+		obj = bindFunc(name, fn)
+	} else {
+		obj, err = ToObject(v)
+	}
+	if nil != err {
+		return err
+	}
+	this.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the global name
+func (this *Env) Get(name string) (object.Object, bool) {
+	return this.env.Get(name)
+}
+
+// Names returns the names of the globals, sorted
+func (this *Env) Names() []string {
+	return this.env.Names()
+}
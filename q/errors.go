@@ -0,0 +1,86 @@
+package q
+
+import (
+	"fmt"
+
+	"Q/code"
+	"Q/diag"
+	"Q/object"
+)
+
+// ExitError is returned by Run when the program calls exit(code)
+type ExitError = object.ExitError
+
+// ErrInterrupted is wrapped by the RuntimeError of a run whose context is cancelled
+var ErrInterrupted = object.ErrInterrupted
+
+// Load fails for data which is not a saved Program, a Program saved by another format
+// version or a corrupted one, errors.Is tells which with the errors below
+var (
+	ErrFormat   = code.ErrFormat
+	ErrVersion  = code.ErrVersion
+	ErrChecksum = code.ErrChecksum
+)
+
+// LimitError is wrapped by the RuntimeError of a run stopped by a limit,
+// errors.Is tells which with the errors below
+type LimitError = object.LimitError
+
+var (
+	ErrStepLimit           = object.ErrStepLimit
+	ErrCallDepthLimit      = object.ErrCallDepthLimit
+	ErrCollectionSizeLimit = object.ErrCollectionSizeLimit
+	ErrDeadline            = object.ErrDeadline // the deadline of the context passed to Run
+)
+
+// SyntaxError is returned by Compile for a source with parse errors
+type SyntaxError struct {
+	Diagnostics []*diag.Diagnostic // every parse error, in source order
+	Source      string
+}
+
+func (this *SyntaxError) Error() string {
+	if len(this.Diagnostics) > 1 {
+		return fmt.Sprintf("%v (and %v more errors)", this.Diagnostics[0], len(this.Diagnostics)-1)
+	}
+	return this.Diagnostics[0].String()
+}
+
+// Render prints every parse error with its line of source
+func (this *SyntaxError) Render() string {
+	return diag.RenderAll(this.Diagnostics, this.Source)
+}
+
+// RuntimeError is returned by Run for an evaluation failing
+type RuntimeError struct {
+	Diagnostic *diag.Diagnostic // located at the innermost node, the calls leading to it are notes
+	Source     string
+	Err        error // the error of the evaluation, errors.Is and errors.As see through to it
+}
+
+func (this *RuntimeError) Error() string {
+	return this.Diagnostic.String()
+}
+
+func (this *RuntimeError) Unwrap() error {
+	return this.Err
+}
+
+// Render prints the error with its line of source
+func (this *RuntimeError) Render() string {
+	return this.Diagnostic.Render(this.Source)
+}
+
+// ConversionError is returned for a Go value without a Q counterpart, or a Q value
+// without a Go one
+type ConversionError struct {
+	Value  interface{} // the Go value, or the object.Object converted to Go
+	Reason string
+}
+
+func (this *ConversionError) Error() string {
+	if obj, ok := this.Value.(object.Object); ok {
+		return fmt.Sprintf("cannot convert %v to a Go value: %v", object.ToString(obj.Type()), this.Reason)
+	}
+	return fmt.Sprintf("cannot convert %T to a Q value: %v", this.Value, this.Reason)
+}
@@ -0,0 +1,111 @@
+package q
+
+import (
+	"context"
+	"fmt"
+
+	"Q/object"
+)
+
+// Invoke calls fn, a Q function or builtin returned by a run, with args converted by
+// ToObject and returns the result converted by ToGo, a result ToGo rejects fails with its
+// *ConversionError. It may be called after the run which returned fn has ended; the call
+// runs with ctx and the limits of the Env of that run, counted from zero, and fails like
+// Run does. The Env must not be running meanwhile, except when Invoke is called by a Go
+// func the running program called: the call is then part of that run and its limits, and
+// stops when either ctx or the context of the run is done.
+func Invoke(ctx context.Context, fn object.Object, args ...interface{}) (interface{}, error) {
+	in := make([]object.Object, 0, len(args))
+	for _, arg := range args {
+		obj, err := ToObject(arg)
+		if nil != err {
+			return nil, err
+		}
+		in = append(in, obj)
+	}
+	if nil == fn {
+		return nil, fmt.Errorf("Invoke -> nil function")
+	}
+	var out object.Object
+	var err error
+	max := DefaultLimits.MaxCollectionSize
+	switch f := fn.(type) {
+	case *object.Function:
+		if out, err = invokeFunction(ctx, f, in); nil != err {
+			return nil, runtimeError(err, f.Source)
+		}
+		max = f.Env.Limits().MaxCollectionSize
+	case *object.Builtin:
+		if out, err = f.Call(in, false); nil != err {
+			return nil, runtimeError(err, "")
+		}
+	default:
+		return nil, fmt.Errorf("Invoke -> %v is not a function", object.ToString(fn.Type()))
+	}
+	// a range beyond the collection size limit of the Env is not made a slice
+	return toGo(out, max)
+}
+
+func invokeFunction(ctx context.Context, fn *object.Function, args []object.Object) (object.Object, error) {
+	env := fn.Env
+	if run := env.Context(); nil == run {
+		if nil == ctx {
+			ctx = context.Background()
+		}
+		env.SetContext(ctx)
+		defer env.SetContext(nil)
+		env.SetLimits(env.Limits())
+	} else if nil != ctx && run != ctx {
+		merged, cancel := mergeContext(run, ctx)
+		defer cancel()
+		env.SetContext(merged)
+		defer env.SetContext(run)
+	}
+	if err := env.Step(); nil != err {
+		return nil, err
+	}
+	out, err := fn.Call(args, false)
+	if nil != err {
+		return nil, err
+	}
+	if err := env.CheckSize(out); nil != err {
+		return nil, err
+	}
+	return out, nil
+}
+
+// mergeContext returns a context done when either run or call is, with the earlier deadline
+func mergeContext(run context.Context, call context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(run)
+	if deadline, ok := call.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		merged, cancelDeadline = context.WithDeadline(merged, deadline)
+		cancelMerged := cancel
+		cancel = func() {
+			cancelDeadline()
+			cancelMerged()
+		}
+	}
+	// a deadline passing is left to WithDeadline so that it is told from a cancellation
+	cancelled := func() {
+		if context.DeadlineExceeded != call.Err() {
+			cancel()
+		}
+	}
+	if nil != call.Err() {
+		cancelled()
+		return merged, cancel
+	}
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case <-call.Done():
+			cancelled()
+		case <-stop:
+		}
+	}()
+	return merged, func() {
+		close(stop)
+		cancel()
+	}
+}
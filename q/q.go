@@ -0,0 +1,202 @@
+// Package q embeds the Q language in Go programs:
+//
+//	prog, err := q.Compile(`println("hi " + name); len(name)`)
+//	if nil != err {
+//		return err // a *q.SyntaxError
+//	}
+//	env := q.NewEnv(q.WithStdout(w))
+//	if err := env.Set("name", "gopher"); nil != err {
+//		return err
+//	}
+//	v, err := prog.Run(ctx, env) // err is a *q.RuntimeError or a *q.ExitError
+//
+// A Program is evaluated by walking its syntax tree, or compiled to bytecode run by a
+// virtual machine with WithBackend(VM); both give the same results and errors. Save writes
+// the bytecode of a Program and Load reads it back, skipping the parsing and compiling.
+//
+// A Program is immutable and may be Run any number of times, an Env keeps the globals
+// between runs and must not be used by two runs at once. Go funcs set in an Env are
+// callable from Q, see Func, and the Q functions a run returns are callable from Go,
+// see Invoke.
+package q
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"Q/ast"
+	"Q/code"
+	"Q/compiler"
+	"Q/diag"
+	"Q/lexer"
+	"Q/object"
+	"Q/parser"
+	"Q/token"
+	"Q/vm"
+)
+
+// Program is a parsed Q program
+type Program struct {
+	name     string
+	source   string
+	backend  Backend
+	program  *ast.Program
+	bytecode *code.Program // nil for the TreeWalker
+}
+
+// Backend is the evaluator of a Program
+type Backend int
+
+const (
+	TreeWalker Backend = iota // evaluates the syntax tree, the default
+	VM                        // compiles to bytecode run by a virtual machine, faster
+)
+
+func (this Backend) String() string {
+	switch this {
+	case TreeWalker:
+		return "tree-walker"
+	case VM:
+		return "vm"
+	default:
+		return fmt.Sprintf("Backend(%d)", int(this))
+	}
+}
+
+// CompileOption configures Compile
+type CompileOption func(*Program)
+
+// WithName sets the file name the positions in errors refer to
+func WithName(name string) CompileOption {
+	return func(p *Program) {
+		p.name = name
+	}
+}
+
+// WithBackend sets the evaluator of the Program, TreeWalker by default
+func WithBackend(backend Backend) CompileOption {
+	return func(p *Program) {
+		p.backend = backend
+	}
+}
+
+// Compile parses src, the error is a *SyntaxError holding every parse error
+func Compile(src string, opts ...CompileOption) (*Program, error) {
+	this := &Program{source: src}
+	for _, opt := range opts {
+		opt(this)
+	}
+	p, err := parser.New(lexer.NewFile(this.name, src))
+	if nil != err {
+		d := diag.Errorf(diag.CodeUnexpectedToken, token.Position{}, token.Position{}, "%v", err)
+		return nil, &SyntaxError{Diagnostics: []*diag.Diagnostic{d}, Source: src}
+	}
+	this.program = p.ParseProgram()
+	if diags := p.Diagnostics(); len(diags) > 0 {
+		return nil, &SyntaxError{Diagnostics: diags, Source: src}
+	}
+	switch this.backend {
+	case TreeWalker:
+	case VM:
+		if err = this.compile(); nil != err {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Compile -> unknown backend %v", this.backend)
+	}
+	return this, nil
+}
+
+// compile compiles the syntax tree to bytecode
+func (this *Program) compile() error {
+	bytecode, err := compiler.Compile(this.program)
+	if nil != err {
+		return err
+	}
+	bytecode.Name, bytecode.Source = this.name, this.source
+	this.bytecode = bytecode
+	return nil
+}
+
+// Save writes the Program compiled to bytecode, Load reads it back without parsing the
+// source again. A TreeWalker Program is compiled for saving.
+func (this *Program) Save(w io.Writer) error {
+	if nil == this.bytecode {
+		p := *this
+		if err := p.compile(); nil != err {
+			return err
+		}
+		return p.bytecode.Encode(w)
+	}
+	return this.bytecode.Encode(w)
+}
+
+// Load reads a Program written by Save, it runs on the VM. A Program saved by another
+// format version fails with ErrVersion and a corrupted one with ErrChecksum. The checksum
+// authenticates nothing, so a Program whose operands or jumps do not fit its tables and
+// instructions fails with ErrFormat.
+func Load(r io.Reader) (*Program, error) {
+	bytecode, err := code.Decode(r)
+	if nil != err {
+		return nil, err
+	}
+	return &Program{name: bytecode.Name, source: bytecode.Source, backend: VM, bytecode: bytecode}, nil
+}
+
+// Name is the file name given to Compile
+func (this *Program) Name() string {
+	return this.name
+}
+
+// Backend is the evaluator given to Compile
+func (this *Program) Backend() Backend {
+	return this.backend
+}
+
+// Source is the text the Program was compiled from
+func (this *Program) Source() string {
+	return this.source
+}
+
+// Run evaluates the Program in env, a nil env is a fresh NewEnv(). The result is the value
+// of the last statement, or of a top level return. Cancelling ctx stops the evaluation with
+// a *RuntimeError wrapping ErrInterrupted, exceeding a limit of the Env or the deadline of
+// ctx with one wrapping a *LimitError. exit(code) stops it with an *ExitError.
+func (this *Program) Run(ctx context.Context, env *Env) (object.Object, error) {
+	if nil == env {
+		env = NewEnv()
+	}
+	if nil == ctx {
+		ctx = context.Background()
+	}
+	env.env.SetContext(ctx)
+	defer env.env.SetContext(nil)
+	env.env.SetLimits(env.limits)
+	defer env.env.SetSource(env.env.Source())
+	env.env.SetSource(this.source)
+	var v object.Object
+	var err error
+	if nil != this.bytecode {
+		v, err = vm.Run(this.bytecode, env.env)
+	} else {
+		v, err = this.program.Eval(env.env, false)
+	}
+	if nil != err {
+		return nil, runtimeError(err, this.source)
+	}
+	if nil == v {
+		return object.Nil, nil
+	}
+	return v, nil
+}
+
+// runtimeError returns the error of an evaluation of source failing
+func runtimeError(err error, source string) error {
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		return exit
+	}
+	return &RuntimeError{Diagnostic: ast.Diagnose(err), Source: source, Err: err}
+}
@@ -0,0 +1,670 @@
+package q
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"Q/code"
+	"Q/diag"
+	"Q/object"
+)
+
+func TestCompileRun(t *testing.T) {
+	tests := []struct {
+		input string
+		want  interface{}
+	}{
+		{"1 + 2", int64(3)},
+		{"var x = 1.5; x * 2", 3.0},
+		{`"a" + "b"`, "ab"},
+		{"[1, [true, null]]", []interface{}{int64(1), []interface{}{true, nil}}},
+		{`{"a": 1, "b": [2]}`, map[string]interface{}{"a": int64(1), "b": []interface{}{int64(2)}}},
+		{`{1: "a", true: 2}`, map[interface{}]interface{}{int64(1): "a", true: int64(2)}},
+		{"range(1, 7, 2)", []interface{}{int64(1), int64(3), int64(5)}},
+		{"var f = func(x) { return x * 2 }; return f(4); 0", int64(8)},
+		{"var x = 1;", int64(1)},
+		{"", nil},
+		{"var fs = []; for (i in range(3)) { push(fs, func() { i * 10 }); } var f = fs[2]; f()", int64(20)},
+	}
+	for _, backend := range []Backend{TreeWalker, VM} {
+		for _, tt := range tests {
+			prog, err := Compile(tt.input, WithBackend(backend))
+			if nil != err {
+				t.Fatalf("[%v] %v: %v", backend, tt.input, err)
+			}
+			v, err := prog.Run(context.Background(), nil)
+			if nil != err {
+				t.Fatalf("[%v] %v: %v", backend, tt.input, err)
+			}
+			if got := goValue(t, v); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("[%v] %v: got %#v, want %#v", backend, tt.input, got, tt.want)
+			}
+		}
+	}
+	if _, err := Compile("1", WithBackend(Backend(7))); nil == err {
+		t.Errorf("Backend(7) compiled")
+	}
+}
+
+func TestEnv(t *testing.T) {
+	type celsius float64
+	var stdout, stderr bytes.Buffer
+	env := NewEnv(WithStdout(&stdout), WithStderr(&stderr))
+	globals := map[string]interface{}{
+		"name":  "gopher",
+		"n":     uint8(7),
+		"temp":  celsius(21.5),
+		"tags":  []string{"a", "b"},
+		"ids":   [2]int{1, 2},
+		"conf":  map[string]interface{}{"debug": true, "level": 3},
+		"byKey": map[int]string{2: "b", 1: "a"},
+		"ptr":   &[]int{4},
+		"none":  (*int)(nil),
+		"obj":   &object.Integer{Value: 9},
+	}
+	for name, v := range globals {
+		if err := env.Set(name, v); nil != err {
+			t.Fatalf("Set(%v) error %v", name, err)
+		}
+	}
+	prog, err := Compile(`println(name, n, temp, tags, ids); eprintln(conf, byKey, ptr, none, obj); var added = 1;`)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := prog.Run(context.Background(), env); nil != err {
+		t.Fatal(err)
+	}
+	if want := "gopher 7 21.5 [\"a\", \"b\"] [1, 2]\n"; stdout.String() != want {
+		t.Errorf("stdout %q, want %q", stdout.String(), want)
+	}
+	if want := "{\"debug\": true, \"level\": 3} {1: \"a\", 2: \"b\"} [4] null 9\n"; stderr.String() != want {
+		t.Errorf("stderr %q, want %q", stderr.String(), want)
+	}
+	// the globals live on in the Env
+	prog, err = Compile("added + n")
+	if nil != err {
+		t.Fatal(err)
+	}
+	v, err := prog.Run(context.Background(), env)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if int64(8) != goValue(t, v) {
+		t.Errorf("added + n = %v, want 8", v.Inspect())
+	}
+	if v, ok := env.Get("added"); !ok || "1" != v.Inspect() {
+		t.Errorf("Get(added) = %v, %v", v, ok)
+	}
+}
+
+// goValue converts obj by ToGo, which must succeed
+func goValue(t *testing.T, obj object.Object) interface{} {
+	v, err := ToGo(obj)
+	if nil != err {
+		t.Fatalf("ToGo(%v) error %v", obj.Inspect(), err)
+	}
+	return v
+}
+
+func TestConversionErrors(t *testing.T) {
+	slice := []interface{}{1, nil}
+	slice[1] = slice
+	hash := map[string]interface{}{}
+	hash["h"] = []interface{}{hash}
+	var ptr interface{}
+	ptr = &ptr
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{uint64(1) << 63, "cannot convert uint64 to a Q value: out of the integer range"},
+		{struct{}{}, "cannot convert struct {} to a Q value: struct {} is not supported"},
+		{[]interface{}{1, make(chan int)}, "cannot convert []interface {} to a Q value: chan int is not supported"},
+		{map[[1]int]int{{1}: 1}, "cannot convert map[[1]int]int to a Q value: map keys must be strings, integers or booleans"},
+		{slice, "cannot convert []interface {} to a Q value: it contains itself"},
+		{hash, "cannot convert map[string]interface {} to a Q value: it contains itself"},
+		{&ptr, "cannot convert *interface {} to a Q value: it contains itself"},
+	}
+	for _, tt := range tests {
+		err := NewEnv().Set("x", tt.value)
+		var convErr *ConversionError
+		if !errors.As(err, &convErr) {
+			t.Fatalf("[%#v] error %v, want a ConversionError", tt.value, err)
+		}
+		if err.Error() != tt.want {
+			t.Errorf("[%#v] error %q, want %q", tt.value, err.Error(), tt.want)
+		}
+	}
+}
+
+func TestToGoErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"var a = [1]; push(a, a); a", "cannot convert array to a Go value: it contains itself"},
+		{`var h = {}; h["a"] = [h]; h`, "cannot convert hash to a Go value: it contains itself"},
+		{"var h = {}; h[1] = h; h", "cannot convert hash to a Go value: it contains itself"},
+		{"range(1000000000000000000)", "cannot convert range to a Go value: 1000000000000000000 elements are more than 16777216"},
+	}
+	for _, tt := range tests {
+		prog, err := Compile(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		v, err := prog.Run(context.Background(), nil)
+		if nil != err {
+			t.Fatalf("%v: %v", tt.input, err)
+		}
+		_, err = ToGo(v)
+		var convErr *ConversionError
+		if !errors.As(err, &convErr) || err.Error() != tt.want {
+			t.Errorf("%v: ToGo error %v, want %q", tt.input, err, tt.want)
+		}
+	}
+	// an array met twice, but not inside itself, converts
+	prog, err := Compile("var a = [1]; [a, {\"a\": a}]")
+	if nil != err {
+		t.Fatal(err)
+	}
+	v, err := prog.Run(context.Background(), nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	want := []interface{}{[]interface{}{int64(1)}, map[string]interface{}{"a": []interface{}{int64(1)}}}
+	if got := goValue(t, v); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %#v, want %#v", got, want)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Compile("var = 1;\nvar y = ;", WithName("s.q"))
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("error %v, want a SyntaxError", err)
+	}
+	if 2 != len(syntaxErr.Diagnostics) || diag.CodeUnexpectedToken != syntaxErr.Diagnostics[0].Code {
+		t.Errorf("diagnostics %v", syntaxErr.Diagnostics)
+	}
+	if want := "s.q:1:5: expected next token to be IDENT, got ASSIGN instead (and 1 more errors)"; err.Error() != want {
+		t.Errorf("error %q, want %q", err.Error(), want)
+	}
+	if !strings.HasPrefix(syntaxErr.Render(), "s.q:1:5: error[P0001]") || !strings.Contains(syntaxErr.Render(), " 2 | var y = ;") {
+		t.Errorf("render %q", syntaxErr.Render())
+	}
+}
+
+func TestRuntimeError(t *testing.T) {
+	for _, backend := range []Backend{TreeWalker, VM} {
+		prog, err := Compile("var f = func() { 1 / 0 };\nf();", WithName("r.q"), WithBackend(backend))
+		if nil != err {
+			t.Fatal(err)
+		}
+		_, err = prog.Run(context.Background(), nil)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Fatalf("[%v] error %v, want a RuntimeError", backend, err)
+		}
+		if want := "r.q:1:20: Integer.calcInteger -> division by zero"; err.Error() != want {
+			t.Errorf("[%v] error %q, want %q", backend, err.Error(), want)
+		}
+		if want := []string{"called from f at r.q:2:2"}; !reflect.DeepEqual(runtimeErr.Diagnostic.Notes, want) {
+			t.Errorf("[%v] notes %q, want %q", backend, runtimeErr.Diagnostic.Notes, want)
+		}
+		if !strings.Contains(runtimeErr.Render(), " 1 | var f = func() { 1 / 0 };") {
+			t.Errorf("[%v] render %q", backend, runtimeErr.Render())
+		}
+	}
+}
+
+func TestExitAndInterrupt(t *testing.T) {
+	prog, err := Compile("exit(3); 1")
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, err = prog.Run(context.Background(), nil)
+	var exit *ExitError
+	if !errors.As(err, &exit) || 3 != exit.Code {
+		t.Errorf("error %v, want exit status 3", err)
+	}
+
+	prog, err = Compile("for { }")
+	if nil != err {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+	_, err = prog.Run(ctx, nil)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || !errors.Is(err, ErrInterrupted) {
+		t.Errorf("error %v, want an interrupted RuntimeError", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		input  string
+		limits Limits
+		want   error
+		msg    string
+	}{
+		{"for { }", Limits{MaxSteps: 100}, ErrStepLimit, "1:1: step limit exceeded, the limit is 100"},
+		{"var f = func() { f() }; f();", DefaultLimits, ErrCallDepthLimit, "1:19: call depth limit exceeded, the limit is 10000"},
+		{"var f = func() { f() }; f();", Limits{MaxCallDepth: 5}, ErrCallDepthLimit, "1:19: call depth limit exceeded, the limit is 5"},
+		{"var s = \"ab\"; for { s = s + s; }", Limits{MaxCollectionSize: 1000}, ErrCollectionSizeLimit, "1:27: collection size limit exceeded, the limit is 1000"},
+	}
+	for _, tt := range tests {
+		prog, err := Compile(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		env := NewEnv(WithLimits(tt.limits))
+		// every run counts from zero
+		for i := 0; i < 2; i++ {
+			_, err = prog.Run(context.Background(), env)
+			var runtimeErr *RuntimeError
+			var limitErr *LimitError
+			if !errors.As(err, &runtimeErr) || !errors.As(err, &limitErr) || !errors.Is(err, tt.want) {
+				t.Fatalf("[%v] error %v, want %v", tt.input, err, tt.want)
+			}
+			if err.Error() != tt.msg {
+				t.Errorf("[%v] error %q, want %q", tt.input, err.Error(), tt.msg)
+			}
+		}
+	}
+
+	prog, err := Compile("for { }")
+	if nil != err {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = prog.Run(ctx, nil)
+	if !errors.Is(err, ErrDeadline) {
+		t.Errorf("error %v, want %v", err, ErrDeadline)
+	}
+}
+
+func TestFunc(t *testing.T) {
+	env := NewEnv()
+	funcs := map[string]interface{}{
+		"check": func(n int64, s string) (bool, error) {
+			if n < 0 {
+				return false, errors.New("negative")
+			}
+			return int64(len(s)) == n, nil
+		},
+		"join": func(sep string, parts ...string) string { return strings.Join(parts, sep) },
+		"sum": func(xs []float64) float64 {
+			s := 0.0
+			for _, x := range xs {
+				s += x
+			}
+			return s
+		},
+		"keys":  func(m map[string]int) int { return len(m) },
+		"small": func(b uint8) uint8 { return b },
+		"opt":   func(p *int) bool { return nil == p },
+		"any":   func(v interface{}) interface{} { return v },
+		"obj":   func(o object.Object) string { return o.Inspect() },
+		"pair":  func() (int, string) { return 1, "a" },
+		"none":  func() {},
+		"boom":  func() { panic("boom") },
+	}
+	for name, fn := range funcs {
+		if err := env.Set(name, fn); nil != err {
+			t.Fatalf("Set(%v) error %v", name, err)
+		}
+	}
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`check(2, "ab")`, "true"},
+		{`check(3, "ab")`, "false"},
+		{`join("-")`, `""`},
+		{`join("-", "a", "b", "c")`, `"a-b-c"`},
+		{"sum([1, 2.5])", "3.5"},
+		{`keys({"a": 1, "b": 2})`, "2"},
+		{"small(255)", "255"},
+		{"opt(null)", "true"},
+		{"opt(1)", "false"},
+		{`any([1, "a"])`, `[1, "a"]`},
+		{"obj([1, 2])", `"[1, 2]"`},
+		{"pair()", `[1, "a"]`},
+		{"none()", "null"},
+		{"check", "builtin check"},
+	}
+	for _, tt := range tests {
+		prog, err := Compile(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		v, err := prog.Run(context.Background(), env)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		if got := v.Inspect(); got != tt.want {
+			t.Errorf("[%v] got %v, want %v", tt.input, got, tt.want)
+		}
+	}
+
+	errTests := []struct {
+		input string
+		want  string
+	}{
+		{`check(-1, "")`, "negative"},
+		{`check(1)`, "1 args provided, but 2 args required"},
+		{`check("a", "b")`, "argument 1: must be integer, got string"},
+		{`join()`, "0 args provided, but at least 1 args required"},
+		{`join("-", "a", 1)`, "argument 3: must be string, got integer"},
+		{`sum([1, "a"])`, "argument 1: element 1: must be float or integer, got string"},
+		{`keys({"a": "b"})`, `argument 1: value of "a": must be integer, got string`},
+		{"small(256)", "argument 1: 256 overflows uint8"},
+		{"small(-1)", "argument 1: -1 overflows uint8"},
+		{"small(null)", "argument 1: must be integer, got null"},
+		{`opt("a")`, "argument 1: must be integer, got string"},
+		{"boom()", "panic: boom"},
+		{"var a = [1]; push(a, a); any(a)", "argument 1: cannot convert array to a Go value: it contains itself"},
+		{"any(range(1000000000000000000))", "argument 1: cannot convert range to a Go value: 1000000000000000000 elements are more than 16777216"},
+	}
+	for _, tt := range errTests {
+		prog, err := Compile(tt.input)
+		if nil != err {
+			t.Fatal(err)
+		}
+		_, err = prog.Run(context.Background(), env)
+		if nil == err || !strings.HasSuffix(err.Error(), tt.want) {
+			t.Errorf("[%v] error %v, want %q", tt.input, err, tt.want)
+		}
+		var convErr *ConversionError
+		if strings.Contains(tt.want, "cannot convert") && !errors.As(err, &convErr) {
+			t.Errorf("[%v] error %v, want a ConversionError", tt.input, err)
+		}
+	}
+
+	if _, err := Func("x", 1); nil == err {
+		t.Errorf("Func(1) error nil")
+	}
+	b, err := Func("", strings.ToUpper)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if want := "builtin strings.ToUpper"; b.Inspect() != want {
+		t.Errorf("Inspect() = %v, want %v", b.Inspect(), want)
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	for _, backend := range []Backend{TreeWalker, VM} {
+		t.Run(backend.String(), func(t *testing.T) {
+			testInvoke(t, backend)
+		})
+	}
+}
+
+func testInvoke(t *testing.T, backend Backend) {
+	var calls []object.Object
+	env := NewEnv(WithLimits(Limits{MaxSteps: 1000, MaxCallDepth: 50}))
+	if err := env.Set("register", func(fn object.Object) { calls = append(calls, fn) }); nil != err {
+		t.Fatal(err)
+	}
+	if err := env.Set("apply", func(fn object.Object, x int64) (interface{}, error) {
+		// a callback calling back into the running program
+		return Invoke(nil, fn, x)
+	}); nil != err {
+		t.Fatal(err)
+	}
+	var cancelledErr, expiredErr error
+	if err := env.Set("applyCancelled", func(fn object.Object) {
+		// the context given to a callback is honoured along with the one of the run
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, cancelledErr = Invoke(ctx, fn)
+		ctx, cancel = context.WithTimeout(context.Background(), -time.Second)
+		defer cancel()
+		_, expiredErr = Invoke(ctx, fn)
+	}); nil != err {
+		t.Fatal(err)
+	}
+	prog, err := Compile(`
+var total = 0;
+register(func(x, ys) { total = total + x; [x * 2, ys] });
+register(func() { for { } });
+register(func(n) { var f = func(n) { if (n > 0) { f(n - 1) } }; f(n) });
+register(func(x) { 1 / x });
+register(len);
+register(func() { var a = [1]; push(a, a); a });
+register(func(n) { range(n) });
+applyCancelled(func() { for (var i = 0; i < 10; i = i + 1) { } });
+apply(func(x) { x + 1 }, 2);
+`, WithName("invoke.q"), WithBackend(backend))
+	if nil != err {
+		t.Fatal(err)
+	}
+	v, err := prog.Run(context.Background(), env)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if int64(3) != goValue(t, v) {
+		t.Errorf("apply = %v, want 3", v.Inspect())
+	}
+	if !errors.Is(cancelledErr, ErrInterrupted) {
+		t.Errorf("callback with a cancelled context error %v, want %v", cancelledErr, ErrInterrupted)
+	}
+	if !errors.Is(expiredErr, ErrDeadline) {
+		t.Errorf("callback with an expired context error %v, want %v", expiredErr, ErrDeadline)
+	}
+	if 7 != len(calls) {
+		t.Fatalf("%v functions registered, want 7", len(calls))
+	}
+	add, loop, recurse, div, length, cyclic, span := calls[0], calls[1], calls[2], calls[3], calls[4], calls[5], calls[6]
+
+	// after the run, the globals of the Env are still shared
+	for i := 1; i <= 2; i++ {
+		got, err := Invoke(context.Background(), add, 5, []string{"a"})
+		if nil != err {
+			t.Fatal(err)
+		}
+		if want := []interface{}{int64(10), []interface{}{"a"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("add = %#v, want %#v", got, want)
+		}
+		if total, _ := env.Get("total"); int64(5*i) != goValue(t, total) {
+			t.Errorf("total = %v, want %v", total.Inspect(), 5*i)
+		}
+	}
+	if got, err := Invoke(nil, length, "abc"); nil != err || int64(3) != got {
+		t.Errorf("len = %v, %v, want 3", got, err)
+	}
+
+	// the limits of the Env apply, counted from zero on every call
+	for i := 0; i < 2; i++ {
+		if _, err := Invoke(context.Background(), loop); !errors.Is(err, ErrStepLimit) {
+			t.Errorf("loop error %v, want %v", err, ErrStepLimit)
+		}
+	}
+	if _, err := Invoke(context.Background(), recurse, 10); nil != err {
+		t.Errorf("recurse(10) error %v", err)
+	}
+	if _, err := Invoke(context.Background(), recurse, 100); !errors.Is(err, ErrCallDepthLimit) {
+		t.Errorf("recurse(100) error %v, want %v", err, ErrCallDepthLimit)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Invoke(ctx, add, 1, nil); !errors.Is(err, ErrInterrupted) {
+		t.Errorf("cancelled error %v, want %v", err, ErrInterrupted)
+	}
+
+	_, err = Invoke(context.Background(), div, 0)
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || !strings.HasSuffix(err.Error(), "division by zero") {
+		t.Fatalf("div error %v, want a RuntimeError", err)
+	}
+	if render := runtimeErr.Render(); !strings.Contains(render, "invoke.q:6:22") || !strings.Contains(render, "register(func(x) { 1 / x });") {
+		t.Errorf("div error rendered without its source:\n%v", render)
+	}
+	if _, err := Invoke(context.Background(), add, 1); nil == err {
+		t.Errorf("add(1) error nil")
+	}
+	var convErr *ConversionError
+	if _, err := Invoke(context.Background(), add, 1, struct{}{}); !errors.As(err, &convErr) {
+		t.Errorf("add(1, struct{}{}) error %v, want a ConversionError", err)
+	}
+	// a result without a Go counterpart fails the call
+	if _, err := Invoke(context.Background(), cyclic); !errors.As(err, &convErr) {
+		t.Errorf("cyclic error %v, want a ConversionError", err)
+	}
+	if got, err := Invoke(context.Background(), span, 2); nil != err || !reflect.DeepEqual(got, []interface{}{int64(0), int64(1)}) {
+		t.Errorf("range(2) = %#v, %v", got, err)
+	}
+	if _, err := Invoke(context.Background(), span, int64(1)<<60); !errors.As(err, &convErr) {
+		t.Errorf("range(1 << 60) error %v, want a ConversionError", err)
+	}
+	if _, err := Invoke(context.Background(), &object.Integer{Value: 1}); nil == err {
+		t.Errorf("Invoke(1) error nil")
+	}
+}
+
+func BenchmarkFib(b *testing.B) {
+	src := "var fib = func(n) { if (n < 2) { return n; } return fib(n - 1) + fib(n - 2); }; fib(20);"
+	for _, backend := range []Backend{TreeWalker, VM} {
+		b.Run(backend.String(), func(b *testing.B) {
+			prog, err := Compile(src, WithBackend(backend))
+			if nil != err {
+				b.Fatal(err)
+			}
+			env := NewEnv(WithLimits(Limits{}))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := prog.Run(context.Background(), env); nil != err {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkLoop(b *testing.B) {
+	src := "var s = 0; for (var i = 0; i < 10000; i = i + 1) { if (i % 3 == 0) { s = s + i; } }; s;"
+	for _, backend := range []Backend{TreeWalker, VM} {
+		b.Run(backend.String(), func(b *testing.B) {
+			prog, err := Compile(src, WithBackend(backend))
+			if nil != err {
+				b.Fatal(err)
+			}
+			env := NewEnv(WithLimits(Limits{}))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := prog.Run(context.Background(), env); nil != err {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	src := "var f = func(x) { if (x < 2) { return x } f(x - 1) + f(x - 2) };\nvar s = \"fib\"; [s, f(10), 1.5]"
+	for _, backend := range []Backend{TreeWalker, VM} {
+		prog, err := Compile(src, WithName("fib.q"), WithBackend(backend))
+		if nil != err {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := prog.Save(&buf); nil != err {
+			t.Fatalf("[%v] %v", backend, err)
+		}
+		loaded, err := Load(bytes.NewReader(buf.Bytes()))
+		if nil != err {
+			t.Fatalf("[%v] %v", backend, err)
+		}
+		if "fib.q" != loaded.Name() || src != loaded.Source() || VM != loaded.Backend() {
+			t.Errorf("[%v] loaded %v %q %v", backend, loaded.Name(), loaded.Source(), loaded.Backend())
+		}
+		v, err := loaded.Run(context.Background(), nil)
+		if nil != err {
+			t.Fatalf("[%v] %v", backend, err)
+		}
+		if got, want := goValue(t, v), []interface{}{"fib", int64(55), 1.5}; !reflect.DeepEqual(got, want) {
+			t.Errorf("[%v] got %#v, want %#v", backend, got, want)
+		}
+	}
+
+	prog, err := Compile("var x = 1;\nx / 0", WithName("div.q"))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := prog.Save(&buf); nil != err {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	loaded, err := Load(bytes.NewReader(data))
+	if nil != err {
+		t.Fatal(err)
+	}
+	_, err = loaded.Run(context.Background(), nil)
+	var re *RuntimeError
+	if !errors.As(err, &re) || !strings.Contains(re.Render(), "div.q:2:3") || !strings.Contains(re.Render(), "x / 0") {
+		t.Errorf("runtime error of a loaded program: %v", err)
+	}
+
+	corrupt := func(i int, b byte) []byte {
+		c := append([]byte{}, data...)
+		c[i] ^= b
+		return c
+	}
+	tests := []struct {
+		data []byte
+		want error
+	}{
+		{corrupt(5, 1), ErrVersion},
+		{corrupt(len(data)/2, 0x10), ErrChecksum},
+		{corrupt(len(data)-1, 0x01), ErrChecksum},
+		{data[:len(data)-3], ErrChecksum},
+		{corrupt(0, 0x20), ErrFormat},
+		{data[:4], ErrFormat},
+		{[]byte("println(1);"), ErrFormat},
+	}
+	for i, tt := range tests {
+		if _, err := Load(bytes.NewReader(tt.data)); !errors.Is(err, tt.want) {
+			t.Errorf("%v: Load error %v, want %v", i, err, tt.want)
+		}
+	}
+}
+
+// a file with a valid checksum whose operands are out of range is rejected by Load
+func TestLoadCrafted(t *testing.T) {
+	prog, err := Compile("var x = 7; x;", WithBackend(VM))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := prog.Save(&buf); nil != err {
+		t.Fatal(err)
+	}
+	crafted, err := code.Decode(&buf)
+	if nil != err {
+		t.Fatal(err)
+	}
+	ins := crafted.Main.Instructions
+	i := 0
+	for code.OpConstant != code.Opcode(ins[i]) {
+		def, _ := code.Lookup(ins[i])
+		_, read := code.ReadOperands(def, ins[i+1:])
+		i += 1 + read
+	}
+	copy(ins[i:], code.Make(code.OpConstant, 0xffff))
+	buf.Reset()
+	if err := crafted.Encode(&buf); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := Load(&buf); !errors.Is(err, ErrFormat) {
+		t.Errorf("Load error %v, want %v", err, ErrFormat)
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"Q/ast"
+	"Q/diag"
+	"Q/editor"
+	"Q/lexer"
+	"Q/object"
+	"Q/parser"
+	"Q/q"
+	"Q/token"
+)
+
+const (
+	prompt         = ">> "
+	continuePrompt = ".. "
+)
+
+// historySize is the number of lines the history file keeps
+const historySize = 1000
+
+// repl returns the status passed to exit(), or 0 at the end of in. Lines are buffered while
+// the input is incomplete: an unclosed `{`, `(` or string, or a trailing operator.
+// A line starting with `:` is a command, see :help.
+func repl(in io.Reader, out io.Writer) int {
+	s := newSession(out)
+	reader := newLineReader(in, out, s)
+	lines := []string{}
+	for {
+		linePrompt := prompt
+		if len(lines) > 0 {
+			linePrompt = continuePrompt
+		}
+		text, err := reader.ReadLine(linePrompt)
+		if editor.ErrInterrupt == err {
+			lines = lines[:0]
+			continue
+		}
+		scanned := nil == err
+		if !scanned && 0 == len(lines) {
+			return exitOK
+		}
+		if scanned && 0 == len(lines) && strings.HasPrefix(text, ":") {
+			err = s.command(text)
+		} else {
+			if scanned {
+				lines = append(lines, text)
+			}
+			// at the end of in, an incomplete input is reported as is
+			var more bool
+			more, err = s.eval("", strings.Join(lines, "\n"), true, !scanned)
+			if more {
+				continue
+			}
+			lines = lines[:0]
+		}
+		var exit *object.ExitError
+		if errors.As(err, &exit) {
+			return exit.Code
+		}
+		if nil != err {
+			fmt.Fprintf(out, "%v\n", err)
+		}
+		if !scanned {
+			return exitOK
+		}
+	}
+}
+
+// lineReader reads the input of the REPL a line at a time
+type lineReader interface {
+	ReadLine(prompt string) (string, error)
+}
+
+// newLineReader returns a line editor for a terminal and a plain scanner for anything else
+func newLineReader(in io.Reader, out io.Writer, s *session) lineReader {
+	if f, ok := in.(*os.File); ok && editor.IsTerminal(int(f.Fd())) {
+		history, err := editor.LoadHistory(historyFile(), historySize)
+		if nil != err {
+			fmt.Fprintf(out, "history not loaded: %v\n", err)
+			history = editor.NewHistory(historySize)
+		}
+		if e, err := editor.New(f, out, history, s.complete); nil == err {
+			return e
+		}
+	}
+	return &scanReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// historyFile is ~/.q_history, empty when there is no home directory
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if nil != err {
+		return ""
+	}
+	return filepath.Join(home, ".q_history")
+}
+
+// scanReader is the lineReader without editing
+type scanReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (this *scanReader) ReadLine(prompt string) (string, error) {
+	io.WriteString(this.out, prompt)
+	if !this.scanner.Scan() {
+		if err := this.scanner.Err(); nil != err {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return this.scanner.Text(), nil
+}
+
+// session is the state a REPL keeps between inputs
+type session struct {
+	env    *object.Env
+	out    io.Writer
+	inputs []string // the inputs evaluated without error, :save writes them
+	// interruptible returns the context of an evaluation and the func releasing it
+	interruptible func() (context.Context, func())
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{out: out, interruptible: interruptOnSignal}
+	s.reset()
+	return s
+}
+
+// interruptOnSignal returns a context cancelled by Ctrl-C (SIGINT) until stop is called,
+// so a runaway evaluation returns to the prompt with the session intact
+func interruptOnSignal() (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		select {
+		case <-signals:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
+
+// complete returns the keywords and the names bound in the session starting with word
+func (this *session) complete(word string) []string {
+	r := []string{}
+	for _, name := range append(token.Keywords(), this.env.Names()...) {
+		if strings.HasPrefix(name, word) {
+			r = append(r, name)
+		}
+	}
+	return r
+}
+
+func (this *session) reset() {
+	this.env = object.NewEnv()
+	this.env.SetBuiltins(object.NewBuiltins(this.out))
+	this.env.SetLimits(q.DefaultLimits)
+	this.inputs = nil
+}
+
+// eval parses and evaluates src in the session, parse and runtime errors are rendered to out.
+// more reports an incomplete src which is not evaluated unless final, the error is only
+// returned by exit().
+func (this *session) eval(name string, src string, echo bool, final bool) (more bool, err error) {
+	p, err := parser.New(lexer.NewFile(name, src))
+	if nil != err {
+		return false, err
+	}
+	program := p.ParseProgram()
+	if !final && p.Incomplete() {
+		return true, nil
+	}
+	if diags := p.Diagnostics(); len(diags) != 0 {
+		io.WriteString(this.out, diag.RenderAll(diags, src))
+		return false, nil
+	}
+	ctx, stop := this.interruptible()
+	defer stop()
+	this.env.SetContext(ctx)
+	val, err := program.Eval(this.env, false)
+	if nil != err {
+		var exit *object.ExitError
+		if errors.As(err, &exit) {
+			return false, exit
+		}
+		if errors.Is(err, object.ErrInterrupted) {
+			// the terminal echoed ^C
+			io.WriteString(this.out, "\n")
+		}
+		io.WriteString(this.out, ast.Diagnose(err).Render(src))
+		return false, nil
+	}
+	this.inputs = append(this.inputs, src)
+	if echo && nil != val {
+		io.WriteString(this.out, val.Inspect())
+		io.WriteString(this.out, "\n")
+	}
+	return false, nil
+}
+
+type command struct {
+	name string
+	args string
+	help string
+	run  func(s *session, arg string) error
+}
+
+var commands []*command
+
+func init() {
+	commands = []*command{
+		{"tokens", "<src>", "print the tokens of src", (*session).tokens},
+		{"ast", "<src>", "print the syntax tree of src", (*session).ast},
+		{"env", "", "list the bindings of the session", (*session).listEnv},
+		{"reset", "", "drop the bindings and the inputs of the session", (*session).resetCmd},
+		{"load", "<file>", "evaluate file in the session", (*session).load},
+		{"save", "<file>", "write the inputs evaluated without error to file", (*session).save},
+		{"help", "", "list the commands", (*session).help},
+	}
+}
+
+// command runs a line starting with `:`
+func (this *session) command(line string) error {
+	name, arg := line[1:], ""
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name, arg = name[:i], strings.TrimSpace(name[i+1:])
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if "" != cmd.args && "" == arg {
+				return fmt.Errorf("usage: :%v %v", cmd.name, cmd.args)
+			}
+			return cmd.run(this, arg)
+		}
+	}
+	if closest := closestCommand(name); nil != closest {
+		return fmt.Errorf("unknown command :%v, did you mean :%v?", name, closest.name)
+	}
+	return fmt.Errorf("unknown command :%v, :help lists the commands", name)
+}
+
+func (this *session) tokens(src string) error {
+	for _, tok := range lexer.New(src).Parse() {
+		fmt.Fprintf(this.out, "%v %v\n", tok.Pos, tok)
+	}
+	return nil
+}
+
+func (this *session) ast(src string) error {
+	p, err := parser.New(lexer.New(src))
+	if nil != err {
+		return err
+	}
+	program := p.ParseProgram()
+	if diags := p.Diagnostics(); len(diags) != 0 {
+		io.WriteString(this.out, diag.RenderAll(diags, src))
+		return nil
+	}
+	io.WriteString(this.out, ast.Dump(program))
+	return nil
+}
+
+func (this *session) listEnv(string) error {
+	for _, name := range this.env.Names() {
+		val, _ := this.env.Get(name)
+		fmt.Fprintf(this.out, "%v = %v\n", name, val.Inspect())
+	}
+	return nil
+}
+
+func (this *session) resetCmd(string) error {
+	this.reset()
+	return nil
+}
+
+func (this *session) load(file string) error {
+	src, err := ioutil.ReadFile(file)
+	if nil != err {
+		return err
+	}
+	_, err = this.eval(file, string(src), false, true)
+	return err
+}
+
+func (this *session) save(file string) error {
+	var out bytes.Buffer
+	for _, input := range this.inputs {
+		out.WriteString(input)
+		if !strings.HasSuffix(input, "\n") {
+			out.WriteString("\n")
+		}
+	}
+	return ioutil.WriteFile(file, []byte(out.String()), 0644)
+}
+
+func (this *session) help(string) error {
+	for _, cmd := range commands {
+		fmt.Fprintf(this.out, "  %-16v %v\n", strings.TrimSpace(":"+cmd.name+" "+cmd.args), cmd.help)
+	}
+	return nil
+}
+
+// closestCommand returns the command name is a typo of, nil when none is close enough
+func closestCommand(name string) *command {
+	var closest *command
+	best := 3 // more edits than this is no typo
+	for _, cmd := range commands {
+		if d := editDistance(name, cmd.name); d < best {
+			closest, best = cmd, d
+		}
+	}
+	return closest
+}
+
+// editDistance is the Levenshtein distance of a and b
+func editDistance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -1,6 +1,10 @@
 package token
 
-import "fmt"
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
 
 type TokenType uint
 
@@ -11,6 +15,8 @@ const (
 	//literal_beg
 	IDENT
 	INT
+	STRING
+	FLOAT
 	//literal_end
 
 	//operator_beg
@@ -30,11 +36,14 @@ const (
 	AND       // &&
 	OR        // ||
 	COMMA     // ,
+	COLON     // :
 	SEMICOLON // ;
 	LPAREN    // (
 	RPAREN    // )
 	LBRACE    // {
 	RBRACE    // }
+	LBRACKET  // [
+	RBRACKET  // ]
 	//operator_end
 
 	//keyword_beg
@@ -48,6 +57,9 @@ const (
 	RETURN
 	FOR
 	BREAK
+	CONTINUE
+	IN
+	DELETE
 	//keyword_end
 )
 
@@ -59,29 +71,37 @@ var (
 		'/': DIV,
 		'%': MOD,
 		',': COMMA,
+		':': COLON,
 		';': SEMICOLON,
 		'(': LPAREN,
 		')': RPAREN,
 		'{': LBRACE,
 		'}': RBRACE,
+		'[': LBRACKET,
+		']': RBRACKET,
 	}
 	keywords = map[string]TokenType{
-		"true":   TRUE,
-		"false":  FALSE,
-		"null":   NULL,
-		"func":   FUNC,
-		"var":    VAR,
-		"if":     IF,
-		"else":   ELSE,
-		"return": RETURN,
-		"for":    FOR,
-		"break":  BREAK,
+		"true":     TRUE,
+		"false":    FALSE,
+		"null":     NULL,
+		"func":     FUNC,
+		"var":      VAR,
+		"if":       IF,
+		"else":     ELSE,
+		"return":   RETURN,
+		"for":      FOR,
+		"break":    BREAK,
+		"continue": CONTINUE,
+		"in":       IN,
+		"delete":   DELETE,
 	}
 
 	tokenTypeStrings = map[TokenType]string{
 		EOF:       "EOF",
 		IDENT:     "IDENT",
 		INT:       "INT",
+		STRING:    "STRING",
+		FLOAT:     "FLOAT",
 		LT:        "LT",
 		GT:        "GT",
 		ASSIGN:    "ASSIGN",
@@ -98,11 +118,14 @@ var (
 		AND:       "AND",
 		OR:        "OR",
 		COMMA:     "COMMA",
+		COLON:     "COLON",
 		SEMICOLON: "SEMICOLON",
 		LPAREN:    "LPAREN",
 		RPAREN:    "RPAREN",
 		LBRACE:    "LBRACE",
 		RBRACE:    "RBRACE",
+		LBRACKET:  "LBRACKET",
+		RBRACKET:  "RBRACKET",
 		TRUE:      "TRUE",
 		FALSE:     "FALSE",
 		NULL:      "NULL",
@@ -113,6 +136,9 @@ var (
 		RETURN:    "RETURN",
 		FOR:       "FOR",
 		BREAK:     "BREAK",
+		CONTINUE:  "CONTINUE",
+		IN:        "IN",
+		DELETE:    "DELETE",
 	}
 )
 
@@ -124,6 +150,31 @@ func ToString(t TokenType) string {
 	return "ILLEGAL"
 }
 
+// LiteralOf returns the fixed literal of punctuation and keyword types, it is empty for the others
+func LiteralOf(t TokenType) string {
+	for ch, tt := range tokenTypes {
+		if tt == t {
+			return string(ch)
+		}
+	}
+	for kw, tt := range keywords {
+		if tt == t {
+			return kw
+		}
+	}
+	return ""
+}
+
+// Keywords returns the reserved words, sorted
+func Keywords() []string {
+	r := make([]string, 0, len(keywords))
+	for kw := range keywords {
+		r = append(r, kw)
+	}
+	sort.Strings(r)
+	return r
+}
+
 func LookupIdent(ident string) TokenType {
 	if tok, ok := keywords[ident]; ok {
 		return tok
@@ -131,9 +182,37 @@ func LookupIdent(ident string) TokenType {
 	return IDENT
 }
 
+// Position locates a token in the source, Line and Col count from 1 and Col counts characters
+type Position struct {
+	File   string
+	Line   int
+	Col    int
+	Offset int // in bytes
+}
+
+// String returns file:line:col, or line:col when the source has no file name
+func (this Position) String() string {
+	if "" == this.File {
+		return fmt.Sprintf("%v:%v", this.Line, this.Col)
+	}
+	return fmt.Sprintf("%v:%v:%v", this.File, this.Line, this.Col)
+}
+
+func (this Position) IsValid() bool {
+	return this.Line > 0
+}
+
+// Advance returns the position after lit when lit starts at this and has no line break
+func (this Position) Advance(lit string) Position {
+	this.Col += utf8.RuneCountInString(lit)
+	this.Offset += len(lit)
+	return this
+}
+
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position
 }
 
 func (this *Token) TypeIs(t TokenType) bool {
@@ -0,0 +1,51 @@
+package vm
+
+import (
+	"Q/object"
+	"Q/token"
+)
+
+// operators are the tokens Calc is given for the operator operands
+var operators = map[token.TokenType]*token.Token{}
+
+func init() {
+	for tt, lit := range map[token.TokenType]string{
+		token.LT: "<", token.GT: ">", token.ADD: "+", token.SUB: "-", token.MUL: "*", token.DIV: "/",
+		token.MOD: "%", token.EQ: "==", token.NEQ: "!=", token.LEQ: "<=", token.GEQ: ">=",
+		token.AND: "&&", token.OR: "||", token.IN: "in",
+	} {
+		operators[tt] = &token.Token{Type: tt, Literal: lit}
+	}
+}
+
+// binary returns `left op right`, the integer arithmetic and comparisons skip Calc
+func binary(op token.TokenType, left, right object.Object) (object.Object, error) {
+	if l, ok := left.(*object.Integer); ok {
+		if r, ok := right.(*object.Integer); ok {
+			switch op {
+			case token.ADD:
+				return &object.Integer{Value: l.Value + r.Value}, nil
+			case token.SUB:
+				return &object.Integer{Value: l.Value - r.Value}, nil
+			case token.MUL:
+				return &object.Integer{Value: l.Value * r.Value}, nil
+			case token.LT:
+				return object.ToBoolean(l.Value < r.Value), nil
+			case token.LEQ:
+				return object.ToBoolean(l.Value <= r.Value), nil
+			case token.GT:
+				return object.ToBoolean(l.Value > r.Value), nil
+			case token.GEQ:
+				return object.ToBoolean(l.Value >= r.Value), nil
+			case token.EQ:
+				return object.ToBoolean(l.Value == r.Value), nil
+			case token.NEQ:
+				return object.ToBoolean(l.Value != r.Value), nil
+			}
+		}
+	}
+	if token.IN == op {
+		return object.In(left, right)
+	}
+	return left.Calc(operators[op], right)
+}
@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"Q/ast"
+	"Q/code"
+	"Q/object"
+	"Q/token"
+	"fmt"
+)
+
+// node locates an error at a site, as the ast node the site was compiled from would
+type node struct {
+	site *code.Site
+}
+
+func (this *node) TokenLiteral() string {
+	return this.site.Literal
+}
+
+func (this *node) Pos() token.Position {
+	return this.site.Pos
+}
+
+func (this *node) String() string {
+	return this.site.Literal
+}
+
+func (this *node) Eval(env *object.Env, insideLoop bool) (object.Object, error) {
+	return nil, fmt.Errorf("node.Eval -> a compiled node is not evaluable")
+}
+
+// nodeOf returns the node of site, an *ast.Call for a call so ast.Diagnose notes it
+func nodeOf(f *frame, site int) ast.Node {
+	s := &f.cl.fn.Sites[site]
+	if "" == s.Callee {
+		return &node{site: s}
+	}
+	return &ast.Call{
+		Tok:  &token.Token{Literal: s.Literal, Pos: s.Pos},
+		Func: &ast.Identifier{Tok: &token.Token{Literal: s.Callee, Pos: s.Pos}, Value: s.Callee},
+	}
+}
+
+// siteError returns the error the tree-walker returns for the node of site failing with msg
+// and err, wrapped by the calls it is part of
+func (this *VM) siteError(f *frame, site int, msg string, err error) error {
+	return wrapCalls(f, site, &ast.Error{Node: nodeOf(f, site), Msg: msg, Err: err})
+}
+
+// callError is the error of the call at site, the called function failing with err
+func (this *VM) callError(f *frame, site int, err error) error {
+	msg := "Call.Eval -> " + f.cl.fn.Sites[site].Callee
+	return wrapCalls(f, site, &ast.Error{Node: nodeOf(f, site), Msg: msg, Err: err, Call: true})
+}
+
+// wrapCalls wraps e by the calls whose callee or arguments the node of site is part of
+func wrapCalls(f *frame, site int, e *ast.Error) error {
+	for _, call := range f.cl.fn.Sites[site].Calls {
+		e = &ast.Error{Node: nodeOf(f, call), Msg: "Call.Eval", Err: e}
+	}
+	return e
+}
+
+func (this *VM) notFound(f *frame, site int, name string, hint bool) error {
+	if hint || this.env.Ended(name) {
+		return this.siteError(f, site, fmt.Sprintf("Identifier.Eval -> `%v` not found, %v", name, object.EndedHint), nil)
+	}
+	return this.siteError(f, site, fmt.Sprintf("Identifier.Eval -> `%v` not found", name), nil)
+}
+
+func notIndexable(f *frame, site int, left object.Object) error {
+	msg := fmt.Sprintf("Index.evalOperands -> %v is not indexable", object.ToString(left.Type()))
+	return &ast.Error{Node: nodeOf(f, site), Msg: msg}
+}
+
+// fail unwinds the frames, err is the error of the innermost one, each caller adds the
+// errors of its call as Function.Call and Call.Eval do
+func (this *VM) fail(err error) error {
+	for len(this.frames) > 1 {
+		top := this.frames[len(this.frames)-1]
+		top.cl.env.LeaveCall()
+		this.frames = this.frames[:len(this.frames)-1]
+		caller := &this.frames[len(this.frames)-1]
+		err = this.callError(caller, caller.call, object.Wrap(err, "Function.Call"))
+	}
+	this.frames = this.frames[:0]
+	this.iters = this.iters[:0]
+	return err
+}
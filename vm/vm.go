@@ -0,0 +1,467 @@
+// Package vm runs the bytecode the compiler makes of a program, with the results and
+// errors ast.Program.Eval has for it. The loops a break or continue leaves are resolved
+// when compiling.
+package vm
+
+import (
+	"Q/code"
+	"Q/function"
+	"Q/object"
+	"Q/token"
+	"fmt"
+)
+
+// cell holds a variable captured by closures, value is nil until it is declared
+type cell struct {
+	value object.Object
+}
+
+// closure is a compiled function with the cells it captured
+type closure struct {
+	prog *code.Program
+	fn   *code.Function
+	free []*cell
+	env  *object.Env // the globals
+}
+
+type frame struct {
+	cl    *closure
+	ip    int
+	bp    int // the stack index of the first local, the function is below it
+	cells []*cell
+	iters int // the iterations in progress when the frame was entered
+	call  int // the site of the call the frame is making
+}
+
+type iteration struct {
+	iter object.Iterator
+	hash bool
+}
+
+// VM runs a closure and the closures it calls
+type VM struct {
+	env    *object.Env
+	stack  []object.Object
+	sp     int
+	frames []frame
+	iters  []iteration
+}
+
+// Run runs prog in env as ast.Program.Eval evaluates the program it was compiled from, the
+// top level variables are set in env
+func Run(prog *code.Program, env *object.Env) (object.Object, error) {
+	return newVM(env).run(&closure{prog: prog, fn: prog.Main, env: env}, nil)
+}
+
+func newVM(env *object.Env) *VM {
+	return &VM{env: env, stack: make([]object.Object, 256)}
+}
+
+func (this *VM) run(cl *closure, args []object.Object) (object.Object, error) {
+	this.grow(1 + cl.fn.NumLocals + cl.fn.MaxStack)
+	this.sp = 1 // the function slot is empty
+	copy(this.stack[this.sp:], args)
+	this.enter(cl, this.sp)
+	return this.execute()
+}
+
+// enter pushes the frame of cl, its arguments are on the stack from bp
+func (this *VM) enter(cl *closure, bp int) {
+	f := frame{cl: cl, bp: bp, iters: len(this.iters)}
+	if cl.fn.NumCells > 0 {
+		// OpMakeCell replaces the cells, one read before it is empty rather than missing
+		f.cells = make([]*cell, cl.fn.NumCells)
+		for i := range f.cells {
+			f.cells[i] = &cell{}
+		}
+	}
+	this.frames = append(this.frames, f)
+	this.sp = bp + cl.fn.NumLocals
+}
+
+// grow makes room for n more values on the stack
+func (this *VM) grow(n int) {
+	if this.sp+n <= len(this.stack) {
+		return
+	}
+	size := 2 * len(this.stack)
+	for size < this.sp+n {
+		size *= 2
+	}
+	stack := make([]object.Object, size)
+	copy(stack, this.stack[:this.sp])
+	this.stack = stack
+}
+
+func (this *VM) push(obj object.Object) {
+	this.stack[this.sp] = obj
+	this.sp++
+}
+
+func (this *VM) pop() object.Object {
+	this.sp--
+	obj := this.stack[this.sp]
+	this.stack[this.sp] = nil
+	return obj
+}
+
+func (this *VM) execute() (object.Object, error) {
+	f := &this.frames[len(this.frames)-1]
+	ins := f.cl.fn.Instructions
+	ip := 0
+	for {
+		op := code.Opcode(ins[ip])
+		ip++
+		switch op {
+		case code.OpConstant:
+			this.push(f.cl.prog.Constants[code.ReadUint16(ins[ip:])])
+			ip += 2
+		case code.OpNull:
+			this.push(object.Nil)
+		case code.OpTrue:
+			this.push(object.True)
+		case code.OpFalse:
+			this.push(object.False)
+		case code.OpPop:
+			this.pop()
+		case code.OpGetLocal:
+			this.push(this.stack[f.bp+int(code.ReadUint16(ins[ip:]))])
+			ip += 2
+		case code.OpSetLocal:
+			this.stack[f.bp+int(code.ReadUint16(ins[ip:]))] = this.stack[this.sp-1]
+			ip += 2
+		case code.OpMakeCell:
+			f.cells[code.ReadUint16(ins[ip:])] = &cell{}
+			ip += 2
+		case code.OpGetCell, code.OpGetFree:
+			var c *cell
+			if code.OpGetCell == op {
+				c = f.cells[code.ReadUint16(ins[ip:])]
+			} else {
+				c = f.cl.free[code.ReadUint16(ins[ip:])]
+			}
+			site := int(code.ReadUint32(ins[ip+2:]))
+			ip += 6
+			if nil == c.value {
+				f.ip = ip
+				return nil, this.fail(this.notFound(f, site, f.cl.fn.Sites[site].Literal, false))
+			}
+			this.push(c.value)
+		case code.OpSetCell:
+			f.cells[code.ReadUint16(ins[ip:])].value = this.stack[this.sp-1]
+			ip += 2
+		case code.OpSetFree:
+			f.cl.free[code.ReadUint16(ins[ip:])].value = this.stack[this.sp-1]
+			ip += 2
+		case code.OpGetGlobal:
+			name := f.cl.prog.Names[code.ReadUint16(ins[ip:])]
+			hint := 1 == ins[ip+2]
+			site := int(code.ReadUint32(ins[ip+3:]))
+			ip += 7
+			if val, ok := this.env.Get(name); ok {
+				this.push(val)
+			} else if builtin, ok := this.env.Builtin(name); ok {
+				this.push(builtin)
+			} else {
+				return nil, this.fail(this.notFound(f, site, name, hint))
+			}
+		case code.OpDefineGlobal:
+			this.env.Set(f.cl.prog.Names[code.ReadUint16(ins[ip:])], this.stack[this.sp-1])
+			ip += 2
+		case code.OpSetGlobal:
+			name := f.cl.prog.Names[code.ReadUint16(ins[ip:])]
+			hint := 1 == ins[ip+2]
+			site := int(code.ReadUint32(ins[ip+3:]))
+			ip += 7
+			if err := this.env.Assign(name, this.stack[this.sp-1]); nil != err {
+				if hint && !this.env.Ended(name) {
+					err = fmt.Errorf("Env.Assign -> `%v` undefined, %v", name, object.EndedHint)
+				}
+				return nil, this.fail(this.siteError(f, site, "AssignStmt.Eval -> env.Assign", err))
+			}
+		case code.OpArray:
+			n := int(code.ReadUint16(ins[ip:]))
+			site := int(code.ReadUint32(ins[ip+2:]))
+			ip += 6
+			elements := make([]object.Object, n)
+			copy(elements, this.stack[this.sp-n:this.sp])
+			this.drop(n)
+			arr := &object.Array{Elements: elements}
+			if err := this.env.CheckSize(arr); nil != err {
+				return nil, this.fail(this.siteError(f, site, "Array.Eval", err))
+			}
+			this.push(arr)
+		case code.OpHash:
+			n := int(code.ReadUint16(ins[ip:]))
+			site := int(code.ReadUint32(ins[ip+2:]))
+			ip += 6
+			hash := object.NewHash()
+			for i := this.sp - 2*n; i < this.sp; i += 2 {
+				if err := hash.Set(this.stack[i], this.stack[i+1]); nil != err {
+					return nil, this.fail(this.siteError(f, site, "Hash.Eval", err))
+				}
+			}
+			this.drop(2 * n)
+			if err := this.env.CheckSize(hash); nil != err {
+				return nil, this.fail(this.siteError(f, site, "Hash.Eval", err))
+			}
+			this.push(hash)
+		case code.OpIndex:
+			site := int(code.ReadUint32(ins[ip:]))
+			ip += 4
+			key := this.pop()
+			left := this.pop()
+			container, ok := left.(object.Indexable)
+			if !ok {
+				return nil, this.fail(this.siteError(f, site, "Index.Eval", notIndexable(f, site, left)))
+			}
+			rc, err := container.Index(key)
+			if nil != err {
+				return nil, this.fail(this.siteError(f, site, "Index.Eval", err))
+			}
+			this.push(rc)
+		case code.OpSetIndex:
+			site := int(code.ReadUint32(ins[ip:]))
+			ip += 4
+			key := this.pop()
+			left := this.pop()
+			container, ok := left.(object.Indexable)
+			if !ok {
+				return nil, this.fail(this.siteError(f, site, "Index.assign", notIndexable(f, site, left)))
+			}
+			if err := this.env.CheckSetIndex(left, key); nil != err {
+				return nil, this.fail(this.siteError(f, site, "Index.assign", err))
+			}
+			if err := container.SetIndex(key, this.stack[this.sp-1]); nil != err {
+				return nil, this.fail(this.siteError(f, site, "Index.assign", err))
+			}
+		case code.OpDelete:
+			site := int(code.ReadUint32(ins[ip:]))
+			index := int(code.ReadUint32(ins[ip+4:]))
+			ip += 8
+			key := this.pop()
+			left := this.pop()
+			if _, ok := left.(object.Indexable); !ok {
+				return nil, this.fail(this.siteError(f, site, "DeleteStmt.Eval", notIndexable(f, index, left)))
+			}
+			hash, ok := left.(*object.Hash)
+			if !ok {
+				msg := fmt.Sprintf("DeleteStmt.Eval -> delete from %v is unsupported", object.ToString(left.Type()))
+				return nil, this.fail(this.siteError(f, site, msg, nil))
+			}
+			deleted, err := hash.Delete(key)
+			if nil != err {
+				return nil, this.fail(this.siteError(f, site, "DeleteStmt.Eval", err))
+			}
+			this.push(object.ToBoolean(deleted))
+		case code.OpBinary:
+			op := token.TokenType(ins[ip])
+			site := int(code.ReadUint32(ins[ip+1:]))
+			ip += 5
+			right := this.pop()
+			left := this.stack[this.sp-1]
+			rc, err := binary(op, left, right)
+			if nil == err {
+				err = this.env.CheckSize(rc)
+			}
+			if nil != err {
+				return nil, this.fail(this.siteError(f, site, "InfixExpression.Eval", err))
+			}
+			this.stack[this.sp-1] = rc
+		case code.OpNot, code.OpNeg:
+			site := int(code.ReadUint32(ins[ip:]))
+			ip += 4
+			var rc object.Object
+			var err error
+			if code.OpNot == op {
+				rc, err = this.stack[this.sp-1].Not()
+			} else {
+				rc, err = this.stack[this.sp-1].Opposite()
+			}
+			if nil != err {
+				return nil, this.fail(this.siteError(f, site, "PrefixExpression.Eval", err))
+			}
+			this.stack[this.sp-1] = rc
+		case code.OpJump:
+			ip = int(code.ReadUint32(ins[ip:]))
+		case code.OpJumpIfFalse:
+			if this.pop().True() {
+				ip += 4
+			} else {
+				ip = int(code.ReadUint32(ins[ip:]))
+			}
+		case code.OpJumpIfDecided:
+			left := this.stack[this.sp-1]
+			if token.AND == token.TokenType(ins[ip]) && !left.True() || token.OR == token.TokenType(ins[ip]) && left.True() {
+				ip = int(code.ReadUint32(ins[ip+1:]))
+			} else {
+				ip += 5
+			}
+		case code.OpIter:
+			site := int(code.ReadUint32(ins[ip:]))
+			ip += 4
+			v := this.pop()
+			iterable, ok := v.(object.Iterable)
+			if !ok {
+				msg := fmt.Sprintf("ForInExpression.Eval -> %v is not iterable", object.ToString(v.Type()))
+				return nil, this.fail(this.siteError(f, site, msg, nil))
+			}
+			_, isHash := v.(*object.Hash)
+			this.iters = append(this.iters, iteration{iter: iterable.Iter(), hash: isHash})
+		case code.OpIterNext:
+			form := ins[ip]
+			end := int(code.ReadUint32(ins[ip+1:]))
+			site := int(code.ReadUint32(ins[ip+5:]))
+			ip += 9
+			it := &this.iters[len(this.iters)-1]
+			key, val, ok, err := it.iter.Next()
+			if nil != err {
+				return nil, this.fail(this.siteError(f, site, "ForInExpression.Eval", err))
+			}
+			if !ok {
+				ip = end
+			} else if 2 == form {
+				this.push(key)
+				this.push(val)
+			} else if it.hash {
+				this.push(key)
+			} else {
+				this.push(val)
+			}
+		case code.OpIterEnd:
+			this.iters = this.iters[:len(this.iters)-1]
+		case code.OpStep:
+			kind := ins[ip]
+			site := int(code.ReadUint32(ins[ip+1:]))
+			ip += 5
+			if err := this.env.Step(); nil != err {
+				return nil, this.fail(this.siteError(f, site, stepMessages[kind], err))
+			}
+		case code.OpCall:
+			n := int(code.ReadUint16(ins[ip:]))
+			site := int(code.ReadUint32(ins[ip+2:]))
+			ip += 6
+			f.ip, f.call = ip, site
+			if err := this.env.Step(); nil != err {
+				return nil, this.fail(this.siteError(f, site, "Call.Eval", err))
+			}
+			callee := this.stack[this.sp-n-1]
+			if fn, ok := callee.(*object.Function); ok {
+				if cl, ok := fn.Compiled.(*closure); ok {
+					if err := this.call(cl, n); nil != err {
+						return nil, this.fail(this.callError(f, site, err))
+					}
+					f = &this.frames[len(this.frames)-1]
+					ins, ip = cl.fn.Instructions, 0
+					continue
+				}
+			}
+			args := make([]object.Object, n)
+			copy(args, this.stack[this.sp-n:this.sp])
+			this.drop(n + 1)
+			rc, err := callee.Call(args, false)
+			if nil != err {
+				return nil, this.fail(this.callError(f, site, err))
+			}
+			// builtins like push grow their arguments
+			if err := this.env.CheckSize(rc); nil != err {
+				return nil, this.fail(this.siteError(f, site, "Call.Eval", err))
+			}
+			this.push(rc)
+		case code.OpReturn:
+			rc := this.stack[this.sp-1]
+			this.iters = this.iters[:f.iters]
+			this.drop(this.sp - f.bp + 1)
+			this.frames = this.frames[:len(this.frames)-1]
+			if 0 == len(this.frames) {
+				return rc, nil
+			}
+			f.cl.env.LeaveCall()
+			f = &this.frames[len(this.frames)-1]
+			ins, ip = f.cl.fn.Instructions, f.ip
+			if err := this.env.CheckSize(rc); nil != err {
+				return nil, this.fail(this.siteError(f, f.call, "Call.Eval", err))
+			}
+			this.push(rc)
+		case code.OpClosure:
+			proto := f.cl.prog.Functions[code.ReadUint16(ins[ip:])]
+			ip += 2
+			free := make([]*cell, len(proto.Free))
+			for i, c := range proto.Free {
+				if c.Local {
+					free[i] = f.cells[c.Index]
+				} else {
+					free[i] = f.cl.free[c.Index]
+				}
+			}
+			this.push((&closure{prog: f.cl.prog, fn: proto, free: free, env: f.cl.env}).object())
+		case code.OpFail:
+			msg := f.cl.prog.Names[code.ReadUint16(ins[ip:])]
+			site := int(code.ReadUint32(ins[ip+2:]))
+			f.ip = ip + 6
+			return nil, this.fail(this.siteError(f, site, msg, nil))
+		default:
+			return nil, fmt.Errorf("VM.execute -> opcode %v undefined", op)
+		}
+	}
+}
+
+// call enters cl called with the n arguments on the top of the stack, as Function.Call does
+func (this *VM) call(cl *closure, n int) error {
+	if n != len(cl.fn.Params) {
+		return fmt.Errorf("Function.Call -> %v args provided, but %v args required", n, len(cl.fn.Params))
+	}
+	if err := cl.env.EnterCall(); nil != err {
+		return err
+	}
+	this.grow(cl.fn.NumLocals + cl.fn.MaxStack)
+	this.enter(cl, this.sp-n)
+	return nil
+}
+
+// drop pops n values
+func (this *VM) drop(n int) {
+	for i := this.sp - n; i < this.sp; i++ {
+		this.stack[i] = nil
+	}
+	this.sp -= n
+}
+
+// object returns the function value of the closure
+func (this *closure) object() *object.Function {
+	fn := this.fn
+	return &object.Function{
+		Fn: function.Function{
+			Inspect: func() string {
+				return fn.Inspect
+			},
+			ArgumentOf: func(idx int) string {
+				return fn.Params[idx]
+			},
+			Body: func() string {
+				return fn.Body
+			},
+		},
+		Args:     fn.Params,
+		EvalBody: this.evalBody,
+		Env:      this.env,
+		Compiled: this,
+		Source:   this.env.Source(),
+	}
+}
+
+// evalBody runs the closure when the function is called through Function.Call, by a
+// builtin or from Go, the arguments are bound in env
+func (this *closure) evalBody(env *object.Env, insideLoop bool) (object.Object, error) {
+	args := make([]object.Object, len(this.fn.Params))
+	for i, name := range this.fn.Params {
+		args[i], _ = env.Get(name)
+	}
+	return newVM(this.env).run(this, args)
+}
+
+var stepMessages = []string{
+	code.StepStatement: "evalStatements",
+	code.StepFor:       "ForExpression.Eval",
+	code.StepForIn:     "ForInExpression.Eval",
+}
@@ -0,0 +1,52 @@
+package vm
+
+import (
+	"testing"
+
+	"Q/compiler"
+	"Q/lexer"
+	"Q/object"
+	"Q/parser"
+)
+
+// TestStackUnwound runs programs leaving loops and functions from inside expressions, the
+// stack must be empty and cleared after each
+func TestStackUnwound(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"var n = 0; outer: for (x in [1, 2]) { n = n + [x, for (y in [3]) { break outer; }][0]; } n", 0},
+		{"var n = 0; outer: for (x in [1, 2]) { [x, [x, for (y in [3]) { continue outer; }]]; n = 9; } n", 0},
+		{"var f = func() { for (x in [1]) { for (y in [2]) { return [x, y][1]; } } }; [f(), f()][0] + f()", 4},
+		{"var f = func(n) { if (n < 2) { return n } f(n - 1) + f(n - 2) }; f(10)", 55},
+		{"var s = 0; for (var i = 0; i < 5; i = i + 1) { if (i % 2 == 0) { continue; } s = s + [i][0]; } s", 4},
+	}
+	for _, tt := range tests {
+		p, err := parser.New(lexer.New(tt.input))
+		if nil != err {
+			t.Fatal(err)
+		}
+		prog, err := compiler.Compile(p.ParseProgram())
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		vm := newVM(object.NewEnv())
+		rc, err := vm.run(&closure{prog: prog, fn: prog.Main, env: vm.env}, nil)
+		if nil != err {
+			t.Fatalf("[%v] %v", tt.input, err)
+		}
+		if i, ok := rc.(*object.Integer); !ok || i.Value != tt.expected {
+			t.Errorf("[%v] got %v, want %v", tt.input, rc.Inspect(), tt.expected)
+		}
+		if 0 != vm.sp || 0 != len(vm.frames) || 0 != len(vm.iters) {
+			t.Errorf("[%v] sp %v, %v frames, %v iterations left", tt.input, vm.sp, len(vm.frames), len(vm.iters))
+		}
+		for i, v := range vm.stack {
+			if nil != v {
+				t.Errorf("[%v] stack[%v] = %v is not cleared", tt.input, i, v.Inspect())
+				break
+			}
+		}
+	}
+}